@@ -0,0 +1,62 @@
+// Command embed-server serves a jina-embeddings-v2 model over HTTP or, with
+// -stdin, embeds the lines of standard input and writes NDJSON to standard
+// output:
+//
+//	cat texts.txt | embed-server -stdin > embeddings.ndjson
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/server"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
+)
+
+func main() {
+	modelPath := flag.String("model", "model/model.onnx", "path to the ONNX model")
+	tokenizerPath := flag.String("tokenizer", "model/tokenizer.json", "path to tokenizer.json")
+	configPath := flag.String("config", "model/config.json", "path to config.json")
+	addr := flag.String("addr", ":8000", "HTTP listen address")
+	stdin := flag.Bool("stdin", false, "embed lines from stdin and write NDJSON to stdout instead of serving HTTP")
+	batchSize := flag.Int("batch", server.DefaultLineBatchSize, "lines per EmbedBatch call in -stdin mode")
+	truncate := flag.Bool("truncate", false, "truncate inputs longer than the model's maximum length instead of rejecting them")
+	flag.Parse()
+
+	// Progress goes to stderr so stdout carries only embeddings in -stdin mode.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	tok := tokenizer.NewSentencePieceTokenizer(tokenizer.WithLogger(logger))
+	if err := tok.LoadFromLocal(*tokenizerPath, *configPath); err != nil {
+		fatalf("failed to load tokenizer: %v", err)
+	}
+
+	var opts []embedding.Option
+	if *truncate {
+		opts = append(opts, embedding.WithTruncation())
+	}
+	m, err := embedding.NewModel(*modelPath, tok, opts...)
+	if err != nil {
+		fatalf("failed to load model: %v", err)
+	}
+	defer m.Close()
+
+	if *stdin {
+		err = server.ServeLines(os.Stdin, os.Stdout, m, *batchSize)
+	} else {
+		logger.Info("serving embeddings", "addr", *addr)
+		err = server.Serve(*addr, m)
+	}
+	if err != nil {
+		m.Close()
+		fatalf("%v", err)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/grpcserver/embeddingpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:50051", "embedding service address")
+	task := flag.String("task", "", "task adapter, e.g. retrieval.query (empty for the server default)")
+	flag.Parse()
+
+	texts := flag.Args()
+	if len(texts) == 0 {
+		texts = []string{"This is an apple", "This is a banana"}
+	}
+
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	client := embeddingpb.NewEmbeddingServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	reply, err := client.Embed(ctx, &embeddingpb.EmbedRequest{Texts: texts, Task: *task})
+	if err != nil {
+		log.Fatalf("Embed failed: %v", err)
+	}
+	fmt.Printf("Embed: %d embeddings in %v\n", len(reply.GetEmbeddings()), time.Since(start))
+	for i, emb := range reply.GetEmbeddings() {
+		values := emb.GetValues()
+		fmt.Printf("  %q: dim %d, first values %v\n", texts[i], len(values), values[:min(5, len(values))])
+	}
+
+	// Stream one request per text and read the replies back in order.
+	stream, err := client.EmbedStream(ctx)
+	if err != nil {
+		log.Fatalf("EmbedStream failed: %v", err)
+	}
+	go func() {
+		for _, text := range texts {
+			if err := stream.Send(&embeddingpb.EmbedRequest{Texts: []string{text}, Task: *task}); err != nil {
+				log.Printf("Send failed: %v", err)
+				return
+			}
+		}
+		_ = stream.CloseSend()
+	}()
+
+	for i := 0; ; i++ {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Recv failed: %v", err)
+		}
+		fmt.Printf("EmbedStream reply %d: dim %d\n", i, len(reply.GetEmbeddings()[0].GetValues()))
+	}
+}
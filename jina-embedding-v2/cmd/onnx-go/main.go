@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
@@ -12,7 +13,7 @@ func main() {
 	modelPath := "model/model.onnx"
 
 	fmt.Printf("Initializing tokenizer...\n")
-	tok := tokenizer.NewSentencePieceTokenizer()
+	tok := tokenizer.NewSentencePieceTokenizer(tokenizer.WithLogger(slog.Default()))
 	// err := tok.LoadFromLocal("model/tokenizer.json", "model/config.json")
 	err := tok.LoadFromHuggingFace("jinaai/jina-embeddings-v2-base-en")
 	if err != nil {
@@ -0,0 +1,119 @@
+// Command search shows the whole retrieval flow in one binary: it boots an
+// embedded Weaviate, embeds a small corpus with jina-embeddings-v2, stores
+// the vectors, and ranks the corpus against a query:
+//
+//	go run ./cmd/search -query "Which fruit is red?"
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/weaviateserver"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/weaviatestore"
+)
+
+const className = "Passage"
+
+var corpus = []string{
+	"Apples are usually red or green and grow on trees.",
+	"Bananas are long yellow fruit that grow in tropical climates.",
+	"The Go programming language was designed at Google.",
+	"ONNX Runtime executes machine learning models across platforms.",
+	"Weaviate is a vector database that stores objects with their embeddings.",
+	"Strawberries are small red fruit often eaten in summer.",
+	"The Eiffel Tower is in Paris.",
+}
+
+func main() {
+	modelPath := flag.String("model", "model/model.onnx", "path to the ONNX model")
+	tokenizerPath := flag.String("tokenizer", "model/tokenizer.json", "path to tokenizer.json")
+	configPath := flag.String("config", "model/config.json", "path to config.json")
+	dataPath := flag.String("data", "./weaviate-search-data", "Weaviate data directory")
+	query := flag.String("query", "Which fruit is red?", "text to search for")
+	limit := flag.Int("limit", 3, "number of results to print")
+	flag.Parse()
+
+	// weaviateserver.Bootstrap parses os.Args itself; keep our flags from it.
+	os.Args = os.Args[:1]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tok := tokenizer.NewSentencePieceTokenizer()
+	if err := tok.LoadFromLocal(*tokenizerPath, *configPath); err != nil {
+		fatalf("failed to load tokenizer: %v", err)
+	}
+	m, err := embedding.NewModel(*modelPath, tok)
+	if err != nil {
+		fatalf("failed to load model: %v", err)
+	}
+	defer m.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	server, port, err := weaviateserver.BootstrapOnFreePort(ctx, "8080", *dataPath, 10, weaviateserver.WithLogger(logger))
+	if err != nil {
+		fatalf("failed to start Weaviate: %v", err)
+	}
+	defer server.Shutdown()
+	store := weaviatestore.NewClient("http://localhost:"+port, nil)
+
+	// The class survives restarts in the data directory; reuse it.
+	err = store.CreateClass(ctx, className, "cosine")
+	if errors.Is(err, weaviatestore.ErrClassExists) {
+		fmt.Printf("Using existing class %s\n", className)
+	} else if err != nil {
+		fatalf("failed to create class %s: %v", className, err)
+	}
+
+	vectors, err := m.EmbedBatch(corpus)
+	if err != nil {
+		fatalf("failed to embed corpus: %v", err)
+	}
+	docs := make([]weaviatestore.Document, len(corpus))
+	for i, text := range corpus {
+		docs[i] = weaviatestore.Document{
+			ID:         passageID(i),
+			Properties: map[string]interface{}{"text": text},
+			Vector:     vectors[i],
+		}
+	}
+	if err := store.InsertBatch(ctx, className, docs, weaviatestore.BatchOptions{}); err != nil {
+		fatalf("failed to insert corpus: %v", err)
+	}
+	fmt.Printf("Inserted %d passages\n", len(docs))
+
+	queryVector, err := m.Embed(*query)
+	if err != nil {
+		fatalf("failed to embed query: %v", err)
+	}
+	page, err := store.SearchNearVector(ctx, className, queryVector, weaviatestore.SearchOptions{
+		Limit:      *limit,
+		Properties: []string{"text"},
+	})
+	if err != nil {
+		fatalf("search failed: %v", err)
+	}
+
+	fmt.Printf("\nQuery: %s\n", *query)
+	for i, r := range page.Results {
+		fmt.Printf("%d. [distance %.4f] %v\n", i+1, r.Distance, r.Properties["text"])
+	}
+}
+
+// passageID gives every corpus entry a fixed UUID, so running the example
+// again overwrites the stored passages instead of duplicating them.
+func passageID(i int) string {
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", i)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	os.Exit(1)
+}
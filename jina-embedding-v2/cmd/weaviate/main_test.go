@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/weaviateserver"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/weaviatestore"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// startTestWeaviate boots an embedded Weaviate for integration tests. They
+// are slow and bind fixed ports, so they only run with WEAVIATE_INTEGRATION=1.
+func startTestWeaviate(t *testing.T, port string) *weaviate.Client {
+	t.Helper()
+
+	if os.Getenv("WEAVIATE_INTEGRATION") == "" {
+		t.Skip("set WEAVIATE_INTEGRATION=1 to run Weaviate integration tests")
+	}
+
+	// Bootstrap parses os.Args; hide the go test flags from it.
+	args := os.Args
+	os.Args = args[:1]
+	t.Cleanup(func() { os.Args = args })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	server, err := weaviateserver.Bootstrap(ctx, port, t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to start Weaviate: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	client, err := weaviate.NewClient(weaviate.Config{Host: "localhost:" + port, Scheme: "http"})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestCosineDistanceMatchesWeaviate(t *testing.T) {
+	client := startTestWeaviate(t, "8081")
+	ctx := context.Background()
+
+	className := "DistanceCheck"
+	err := client.Schema().ClassCreator().WithClass(&models.Class{
+		Class:             className,
+		Vectorizer:        "none",
+		VectorIndexConfig: map[string]interface{}{"distance": "cosine"},
+	}).Do(ctx)
+	if err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+
+	stored := []float32{0.3, -0.2, 0.9, 0.1}
+	query := []float32{0.5, 0.4, 0.2, -0.3}
+
+	_, err = client.Data().Creator().
+		WithClassName(className).
+		WithProperties(map[string]interface{}{"name": "stored"}).
+		WithVector(stored).
+		Do(ctx)
+	if err != nil {
+		t.Fatalf("failed to insert object: %v", err)
+	}
+
+	resp, err := client.GraphQL().Get().
+		WithClassName(className).
+		WithFields(
+			graphql.Field{Name: "name"},
+			graphql.Field{Name: "_additional", Fields: []graphql.Field{{Name: "distance"}}},
+		).
+		WithNearVector(client.GraphQL().NearVectorArgBuilder().WithVector(query)).
+		Do(ctx)
+	if err != nil {
+		t.Fatalf("nearVector query failed: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("nearVector query returned errors: %v", resp.Errors[0].Message)
+	}
+
+	objects := resp.Data["Get"].(map[string]interface{})[className].([]interface{})
+	if len(objects) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(objects))
+	}
+	additional := objects[0].(map[string]interface{})["_additional"].(map[string]interface{})
+	weaviateDistance := additional["distance"].(float64)
+
+	goDistance := embedding.CosineDistance(stored, query)
+	if math.Abs(weaviateDistance-float64(goDistance)) > 1e-4 {
+		t.Errorf("Weaviate distance %v, CosineDistance %v", weaviateDistance, goDistance)
+	}
+}
+
+func TestSearchNearVectorPagesWithoutGaps(t *testing.T) {
+	client := startTestWeaviate(t, "8082")
+	ctx := context.Background()
+
+	className := "PagedSearch"
+	err := client.Schema().ClassCreator().WithClass(&models.Class{
+		Class:             className,
+		Vectorizer:        "none",
+		VectorIndexConfig: map[string]interface{}{"distance": "cosine"},
+	}).Do(ctx)
+	if err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+
+	const total = 150
+	for i := 0; i < total; i++ {
+		angle := float64(i) / total * math.Pi
+		_, err := client.Data().Creator().
+			WithClassName(className).
+			WithProperties(map[string]interface{}{"name": strconv.Itoa(i)}).
+			WithVector([]float32{float32(math.Cos(angle)), float32(math.Sin(angle)), 0.5, 0.1}).
+			Do(ctx)
+		if err != nil {
+			t.Fatalf("failed to insert object %d: %v", i, err)
+		}
+	}
+
+	store := weaviatestore.NewClient("http://localhost:8082", nil)
+	seen := map[string]bool{}
+	opts := weaviatestore.SearchOptions{Limit: 40, Properties: []string{"name"}}
+	lastDistance := float32(-1)
+	for {
+		page, err := store.SearchNearVector(ctx, className, []float32{1, 0, 0.5, 0.1}, opts)
+		if err != nil {
+			t.Fatalf("SearchNearVector: %v", err)
+		}
+		for _, r := range page.Results {
+			if seen[r.ID] {
+				t.Fatalf("result %s returned twice", r.ID)
+			}
+			seen[r.ID] = true
+			if r.Distance < lastDistance-1e-6 {
+				t.Errorf("results out of order: distance %v after %v", r.Distance, lastDistance)
+			}
+			lastDistance = r.Distance
+		}
+		if page.Next == "" {
+			break
+		}
+		opts.After = page.Next
+	}
+
+	if len(seen) != total {
+		t.Errorf("paged through %d objects, want %d", len(seen), total)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeCLIScript answers both "infer MODEL INPUT" and "interactive MODEL"
+// with a fixed embedding, so the benchmarks below measure the subprocess
+// handling alone.
+const fakeCLIScript = `if [ "$1" = infer ]; then printf '[0.1,0.2,0.3]\n'; exit 0; fi
+while IFS= read -r line; do printf '{"version":1,"embeddings":[[0.1,0.2,0.3]]}\n'; done`
+
+// benchmarkBinary returns the coreml-cli binary and model to benchmark:
+// the compiled model from "make jina-v2" with "real", otherwise a shell
+// script standing in for coreml-cli.
+func benchmarkBinary(b *testing.B, real bool) (binaryPath, modelPath string) {
+	b.Helper()
+
+	if !real {
+		binaryPath = writeFakeBinary(b, fakeCLIScript)
+		return binaryPath, filepath.Dir(binaryPath)
+	}
+	binaryPath, modelPath = "./coreml-cli-v2", "./jina-v2"
+	for _, path := range []string{binaryPath, modelPath} {
+		if _, err := os.Stat(path); err != nil {
+			b.Skipf("%s not available (run make jina-v2 on macOS)", path)
+		}
+	}
+	return binaryPath, modelPath
+}
+
+// BenchmarkInfer measures the latency (ns/op) and throughput (infer/s) of
+// one inference in each mode. Non-interactive mode starts a process and
+// loads the model for every call; interactive mode pays that once, before
+// the timer starts. The "fake" variants isolate process and pipe overhead:
+//
+//	go test -run '^$' -bench Infer ./coreml
+func BenchmarkInfer(b *testing.B) {
+	for _, backend := range []string{"fake", "coreml"} {
+		for _, interactive := range []bool{false, true} {
+			name := backend + "/non-interactive"
+			if interactive {
+				name = backend + "/interactive"
+			}
+			b.Run(name, func(b *testing.B) {
+				binaryPath, modelPath := benchmarkBinary(b, backend == "coreml")
+				service := NewService(binaryPath, modelPath, interactive)
+				defer service.Close()
+				if interactive && !service.interactive {
+					b.Fatal("interactive process did not start")
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				start := time.Now()
+				for i := 0; i < b.N; i++ {
+					if _, err := service.Infer("This is an apple"); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "infer/s")
+			})
+		}
+	}
+}
+
+// BenchmarkInteractiveStartup measures starting the interactive process and
+// getting its first answer, the cost BenchmarkInfer's interactive variants
+// leave out. Compare it with the non-interactive ns/op to see after how
+// many inferences process reuse pays off.
+func BenchmarkInteractiveStartup(b *testing.B) {
+	for _, backend := range []string{"fake", "coreml"} {
+		b.Run(backend, func(b *testing.B) {
+			binaryPath, modelPath := benchmarkBinary(b, backend == "coreml")
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				service := NewService(binaryPath, modelPath, true)
+				if _, err := service.Infer("This is an apple"); err != nil {
+					b.Fatal(err)
+				}
+				service.Close()
+			}
+		})
+	}
+}
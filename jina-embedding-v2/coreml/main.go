@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"strings"
 	"sync"
 	"time"
@@ -36,19 +35,44 @@ type Service struct {
 	binaryPath  string
 	modelPath   string
 	interactive bool
-	cmd         *exec.Cmd
+	runner      commandRunner
+	proc        process
 	stdin       io.WriteCloser
 	stdout      io.ReadCloser
-	scanner     *bufio.Scanner
+	reader      *bufio.Reader
+	stderr      *stderrRing
 	mu          sync.Mutex
+
+	// readBufferSize is the initial size of the stdout reader's buffer.
+	// Responses are read a line at a time with no upper limit; the buffer
+	// only grows as needed.
+	readBufferSize int
+
+	// defaultTask is sent as the "task" field of interactive requests
+	// made through Infer, for models with task adapters.
+	defaultTask string
 }
 
-func NewService(binaryPath, modelPath string, interactive bool) *Service {
-	s := &Service{
-		binaryPath:  binaryPath,
-		modelPath:   modelPath,
-		interactive: interactive,
+// DefaultReadBufferSize is the initial stdout buffer size of an interactive
+// process.
+const DefaultReadBufferSize = 1024 * 1024
+
+// ServiceOption configures a Service.
+type ServiceOption func(*Service)
+
+// WithReadBufferSize sets the initial size of the buffer used to read
+// interactive responses. Larger responses are still read in full; a buffer
+// close to the typical response size avoids regrowing it.
+func WithReadBufferSize(size int) ServiceOption {
+	return func(s *Service) {
+		if size > 0 {
+			s.readBufferSize = size
+		}
 	}
+}
+
+func NewService(binaryPath, modelPath string, interactive bool, opts ...ServiceOption) *Service {
+	s := newService(binaryPath, modelPath, interactive, opts)
 
 	if interactive {
 		if err := s.startInteractiveProcess(); err != nil {
@@ -59,20 +83,92 @@ func NewService(binaryPath, modelPath string, interactive bool) *Service {
 	return s
 }
 
+func newService(binaryPath, modelPath string, interactive bool, opts []ServiceOption) *Service {
+	s := &Service{
+		binaryPath:     binaryPath,
+		modelPath:      modelPath,
+		interactive:    interactive,
+		runner:         execRunner{},
+		readBufferSize: DefaultReadBufferSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetDefaultTask sets the task Infer requests in interactive mode, e.g.
+// "retrieval.query" for jina-embeddings-v3 style models. An empty task (the
+// default) omits the field.
+func (s *Service) SetDefaultTask(task string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultTask = task
+}
+
 func (s *Service) Infer(inputValue string) (string, error) {
+	s.mu.Lock()
+	task := s.defaultTask
+	s.mu.Unlock()
+
+	return s.InferWithTask(inputValue, task)
+}
+
+// InferWithTask is Infer with an explicit task. The non-interactive CLI has
+// no task argument, so task is only honoured in interactive mode.
+func (s *Service) InferWithTask(inputValue, task string) (string, error) {
 	if s.interactive {
-		return s.inferInteractive(inputValue)
+		return s.inferInteractive(inputValue, task)
 	}
 	return s.inferNonInteractive(inputValue)
 }
 
-func (s *Service) inferInteractive(inputValue string) (string, error) {
-	fmt.Printf("inferencing : %s", inputValue)
+// ProtocolVersion is the version of the interactive protocol: sent in
+// every request, and checked in every response so that a coreml-cli
+// speaking another version fails loudly instead of being misread. A
+// response without a version, as sent by coreml-cli builds that predate
+// versioning such as the checked-in coreml-cli-v2, is taken as version 1.
+const ProtocolVersion = 1
+
+// interactiveRequest is one line of JSON sent to the interactive process.
+type interactiveRequest struct {
+	Version int      `json:"version"`
+	Inputs  []string `json:"inputs"`
+	Task    string   `json:"task,omitempty"`
+}
+
+// ProtocolVersionError is returned when the interactive process answers
+// with a protocol version other than ProtocolVersion.
+type ProtocolVersionError struct {
+	Want int
+	Got  int
+}
+
+func (e *ProtocolVersionError) Error() string {
+	return fmt.Sprintf("coreml-cli speaks protocol version %d, want version %d", e.Got, e.Want)
+}
+
+// checkProtocolVersion fails unless response is a JSON object whose
+// "version" is ProtocolVersion or missing.
+func checkProtocolVersion(response []byte) error {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(response, &header); err != nil {
+		return fmt.Errorf("invalid response from coreml-cli: %w", err)
+	}
+	if header.Version != 0 && header.Version != ProtocolVersion {
+		return &ProtocolVersionError{Want: ProtocolVersion, Got: header.Version}
+	}
+	return nil
+}
+
+func (s *Service) inferInteractive(inputValue, task string) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for retries := 0; retries < 2; retries++ {
-		if s.cmd == nil || s.stdin == nil || s.scanner == nil {
+		if s.proc == nil || s.stdin == nil || s.reader == nil {
 			if err := s.restartInteractiveProcess(); err != nil {
 				if retries == 1 {
 					return "", fmt.Errorf("failed to restart interactive process: %w", err)
@@ -81,7 +177,7 @@ func (s *Service) inferInteractive(inputValue string) (string, error) {
 			}
 		}
 
-		if s.cmd.ProcessState != nil && s.cmd.ProcessState.Exited() {
+		if s.proc.Exited() {
 			if err := s.restartInteractiveProcess(); err != nil {
 				if retries == 1 {
 					return "", fmt.Errorf("failed to restart interactive process after exit: %w", err)
@@ -90,8 +186,10 @@ func (s *Service) inferInteractive(inputValue string) (string, error) {
 			}
 		}
 
-		input := map[string]interface{}{
-			"inputs": []string{inputValue},
+		input := interactiveRequest{
+			Version: ProtocolVersion,
+			Inputs:  []string{inputValue},
+			Task:    task,
 		}
 		inputJSON, err := json.Marshal(input)
 		if err != nil {
@@ -103,21 +201,27 @@ func (s *Service) inferInteractive(inputValue string) (string, error) {
 				s.restartInteractiveProcess()
 				continue
 			}
-			return "", fmt.Errorf("failed to write to stdin: %w", err)
+			return "", s.withStderr(fmt.Errorf("failed to write to stdin: %w", err))
 		}
 
-		if !s.scanner.Scan() {
-			if err := s.scanner.Err(); err != nil {
-				if retries < 1 {
-					s.restartInteractiveProcess()
-					continue
-				}
-				return "", fmt.Errorf("failed to read from stdout: %w", err)
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil && !(err == io.EOF && len(line) > 0) {
+			// EOF means the process exited, e.g. it crashed on this
+			// input; restart it and retry once.
+			if retries < 1 {
+				s.restartInteractiveProcess()
+				continue
+			}
+			if err == io.EOF {
+				return "", s.withStderr(fmt.Errorf("no response from interactive process"))
 			}
-			return "", fmt.Errorf("no response from interactive process")
+			return "", s.withStderr(fmt.Errorf("failed to read from stdout: %w", err))
 		}
 
-		response := strings.TrimSpace(s.scanner.Text())
+		response := strings.TrimSpace(string(line))
+		if err := checkProtocolVersion([]byte(response)); err != nil {
+			return "", err
+		}
 		return response, nil
 	}
 
@@ -125,17 +229,11 @@ func (s *Service) inferInteractive(inputValue string) (string, error) {
 }
 
 func (s *Service) inferNonInteractive(inputValue string) (string, error) {
-	if _, err := os.Stat(s.binaryPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("coreml-cli binary not found at %s", s.binaryPath)
-	}
-
 	if _, err := os.Stat(s.modelPath); os.IsNotExist(err) {
 		return "", fmt.Errorf("model not found at %s", s.modelPath)
 	}
 
-	cmd := exec.Command(s.binaryPath, "infer", s.modelPath, inputValue)
-
-	output, err := cmd.CombinedOutput()
+	output, err := s.runner.CombinedOutput(s.binaryPath, "infer", s.modelPath, inputValue)
 	if err != nil {
 		return "", fmt.Errorf("failed to execute coreml-cli: %w, output: %s", err, string(output))
 	}
@@ -144,42 +242,27 @@ func (s *Service) inferNonInteractive(inputValue string) (string, error) {
 }
 
 func (s *Service) startInteractiveProcess() error {
-	if _, err := os.Stat(s.binaryPath); os.IsNotExist(err) {
-		return fmt.Errorf("coreml-cli binary not found at %s", s.binaryPath)
-	}
-
 	if _, err := os.Stat(s.modelPath); os.IsNotExist(err) {
 		return fmt.Errorf("model not found at %s", s.modelPath)
 	}
 
-	s.cmd = exec.Command(s.binaryPath, "interactive", s.modelPath)
-
-	stdin, err := s.cmd.StdinPipe()
+	proc, err := s.runner.Start(s.binaryPath, "interactive", s.modelPath)
 	if err != nil {
-		return fmt.Errorf("failed to create stdin pipe: %w", err)
+		return err
 	}
-	s.stdin = stdin
+	s.proc = proc
+	s.stdin = proc.Stdin()
+	s.stdout = proc.Stdout()
+	s.reader = bufio.NewReaderSize(s.stdout, s.readBufferSize)
 
-	stdout, err := s.cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-	s.stdout = stdout
-	s.scanner = bufio.NewScanner(stdout)
-
-	// Set a larger buffer size to handle large embedding responses
-	buf := make([]byte, 10*1024*1024) // 10MB buffer
-	s.scanner.Buffer(buf, 10*1024*1024)
-
-	if err := s.cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start interactive process: %w", err)
-	}
+	s.stderr = newStderrRing(stderrLines)
+	go s.stderr.readFrom(proc.Stderr())
 
 	return nil
 }
 
 func (s *Service) stopInteractiveProcess() error {
-	if s.cmd == nil {
+	if s.proc == nil {
 		return nil
 	}
 
@@ -190,22 +273,20 @@ func (s *Service) stopInteractiveProcess() error {
 		s.stdout.Close()
 	}
 
-	if s.cmd.Process != nil {
-		if err := s.cmd.Process.Kill(); err != nil {
-			return fmt.Errorf("failed to kill process: %w", err)
-		}
+	if err := s.proc.Kill(); err != nil {
+		return fmt.Errorf("failed to kill process: %w", err)
 	}
 
-	s.cmd = nil
+	s.proc = nil
 	s.stdin = nil
 	s.stdout = nil
-	s.scanner = nil
+	s.reader = nil
+	s.stderr = nil
 
 	return nil
 }
 
 func (s *Service) restartInteractiveProcess() error {
-	fmt.Print("restarted")
 	s.stopInteractiveProcess()
 	return s.startInteractiveProcess()
 }
@@ -1,6 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -22,21 +29,6 @@ func TestCoreMLInference(t *testing.T) {
 	t.Logf("Inference time: %v", elapsed)
 }
 
-func BenchmarkCoreMLInference(b *testing.B) {
-	binaryPath := "./coreml-cli"
-	modelPath := "./jina-v2"
-	input := "./cat.jpg"
-	service := NewService(binaryPath, modelPath, false)
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := service.Infer(input)
-		if err != nil {
-			b.Fatalf("Error: %v", err)
-		}
-	}
-}
-
 func TestCoreMLInteractiveMode(t *testing.T) {
 	binaryPath := "./coreml-cli-v2"
 	modelPath := "./jina-v2"
@@ -75,18 +67,136 @@ func TestCoreMLInteractiveMultipleInferences(t *testing.T) {
 	}
 }
 
-func BenchmarkCoreMLInteractiveMode(b *testing.B) {
-	binaryPath := "./coreml-cli"
-	modelPath := "./jina-v2"
-	input := "test"
-	service := NewService(binaryPath, modelPath, true)
+// echoScript echoes every request line back.
+const echoScript = "while IFS= read -r line; do printf '%s\\n' \"$line\"; done"
+
+// writeFakeBinary writes a shell script standing in for coreml-cli and
+// returns its path. The model path can be the script's directory.
+func writeFakeBinary(tb testing.TB, body string) string {
+	tb.Helper()
+
+	if runtime.GOOS == "windows" {
+		tb.Skip("fake binary is a shell script")
+	}
+
+	binaryPath := filepath.Join(tb.TempDir(), "fake-coreml-cli")
+	if err := os.WriteFile(binaryPath, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		tb.Fatal(err)
+	}
+	return binaryPath
+}
+
+// newEchoService starts a Service whose "binary" is a shell script that
+// echoes every request line back, so tests can inspect what was sent.
+func newEchoService(t *testing.T) *Service {
+	t.Helper()
+
+	binaryPath := writeFakeBinary(t, echoScript)
+	service := NewService(binaryPath, filepath.Dir(binaryPath), true)
+	if !service.interactive {
+		t.Fatal("fake binary did not start in interactive mode")
+	}
+	t.Cleanup(func() { service.Close() })
+	return service
+}
+
+func TestInteractiveRequestIncludesTask(t *testing.T) {
+	service := newEchoService(t)
+
+	tests := []struct {
+		name        string
+		defaultTask string
+		task        *string
+		want        string
+	}{
+		{"no task", "", nil, ""},
+		{"default task", "retrieval.query", nil, "retrieval.query"},
+		{"explicit task", "retrieval.query", ptr("text-matching"), "text-matching"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.SetDefaultTask(tt.defaultTask)
+
+			var sent string
+			var err error
+			if tt.task != nil {
+				sent, err = service.InferWithTask("Testing", *tt.task)
+			} else {
+				sent, err = service.Infer("Testing")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var req map[string]interface{}
+			if err := json.Unmarshal([]byte(sent), &req); err != nil {
+				t.Fatalf("sent invalid JSON %q: %v", sent, err)
+			}
+			task, hasTask := req["task"]
+			if tt.want == "" && hasTask {
+				t.Errorf("request %s should not have a task field", sent)
+			}
+			if tt.want != "" && task != tt.want {
+				t.Errorf("request %s has task %v, want %q", sent, task, tt.want)
+			}
+			if inputs, _ := req["inputs"].([]interface{}); len(inputs) != 1 || inputs[0] != "Testing" {
+				t.Errorf("request %s has inputs %v", sent, req["inputs"])
+			}
+		})
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestInteractiveReadsLargeResponse(t *testing.T) {
+	// A single response line larger than the old 10MB scanner limit.
+	const size = 12 * 1024 * 1024
+	script := fmt.Sprintf(`while IFS= read -r line; do printf '{"version":1,"data":"'; head -c %d /dev/zero | tr '\0' a; echo '"}'; done`, size)
+	binaryPath := writeFakeBinary(t, script)
+
+	service := NewService(binaryPath, filepath.Dir(binaryPath), true, WithReadBufferSize(64))
+	if !service.interactive {
+		t.Fatal("fake binary did not start in interactive mode")
+	}
 	defer service.Close()
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := service.Infer(input)
+	for i := 0; i < 2; i++ {
+		result, err := service.Infer("Testing")
 		if err != nil {
-			b.Fatalf("Error: %v", err)
+			t.Fatal(err)
+		}
+		if want := size + len(`{"version":1,"data":""}`); len(result) != want {
+			t.Fatalf("response %d has %d bytes, want %d", i, len(result), want)
 		}
 	}
 }
+
+func TestInteractiveErrorIncludesStderr(t *testing.T) {
+	script := "echo 'loading model' >&2; echo 'error: no such model: /models/jina-v2' >&2; exit 1"
+	binaryPath := writeFakeBinary(t, script)
+
+	service := NewService(binaryPath, filepath.Dir(binaryPath), true)
+	if !service.interactive {
+		t.Fatal("fake binary did not start in interactive mode")
+	}
+	defer service.Close()
+
+	_, err := service.Infer("Testing")
+	if err == nil {
+		t.Fatal("expected an error from a process that exits")
+	}
+	if !strings.Contains(err.Error(), "error: no such model: /models/jina-v2") {
+		t.Errorf("error %q does not include the process stderr", err)
+	}
+}
+
+func TestStderrRingKeepsLastLines(t *testing.T) {
+	ring := newStderrRing(3)
+	ring.readFrom(strings.NewReader("one\ntwo\n\nthree\nfour\r\nfive"))
+
+	want := []string{"three", "four", "five"}
+	if got := ring.tail(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("tail = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ServicePool runs several interactive coreml-cli processes so that
+// inferences can use more than one core. Each worker is a Service, so a
+// crashed process is restarted by that worker alone.
+type ServicePool struct {
+	workers []*Service
+	idle    chan *Service
+}
+
+// NewServicePool starts workers interactive processes. Unlike NewService it
+// does not fall back to non-interactive mode: if any process fails to start
+// the ones already running are stopped and an error is returned.
+func NewServicePool(binaryPath, modelPath string, workers int, opts ...ServiceOption) (*ServicePool, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("workers must be at least 1, got %d", workers)
+	}
+
+	p := &ServicePool{idle: make(chan *Service, workers)}
+	for i := 0; i < workers; i++ {
+		s := newService(binaryPath, modelPath, true, opts)
+		if err := s.startInteractiveProcess(); err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to start worker %d: %w", i, err)
+		}
+		p.workers = append(p.workers, s)
+		p.idle <- s
+	}
+
+	return p, nil
+}
+
+// SetDefaultTask sets the task used by every worker, see
+// Service.SetDefaultTask.
+func (p *ServicePool) SetDefaultTask(task string) {
+	for _, s := range p.workers {
+		s.SetDefaultTask(task)
+	}
+}
+
+// Infer runs one inference on the next idle worker.
+func (p *ServicePool) Infer(inputValue string) (string, error) {
+	s := <-p.idle
+	defer func() { p.idle <- s }()
+
+	return s.Infer(inputValue)
+}
+
+// InferBatch fans inputs across the workers, running at most one request
+// per worker at a time. Results are in input order; the error joins the
+// failures of individual inputs, whose results are left empty.
+func (p *ServicePool) InferBatch(inputs []string) ([]string, error) {
+	results := make([]string, len(inputs))
+	errs := make([]error, len(inputs))
+
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		s := <-p.idle
+		wg.Add(1)
+		go func(i int, input string) {
+			defer wg.Done()
+			defer func() { p.idle <- s }()
+
+			result, err := s.Infer(input)
+			if err != nil {
+				errs[i] = fmt.Errorf("input %d: %w", i, err)
+				return
+			}
+			results[i] = result
+		}(i, input)
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// Close stops every worker process.
+func (p *ServicePool) Close() error {
+	var errs []error
+	for _, s := range p.workers {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func newTestPool(t *testing.T, script string, workers int) *ServicePool {
+	t.Helper()
+
+	binaryPath := writeFakeBinary(t, script)
+	pool, err := NewServicePool(binaryPath, filepath.Dir(binaryPath), workers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func sentInput(t *testing.T, response string) string {
+	t.Helper()
+
+	var req interactiveRequest
+	if err := json.Unmarshal([]byte(response), &req); err != nil || len(req.Inputs) != 1 {
+		t.Fatalf("unexpected response %q: %v", response, err)
+	}
+	return req.Inputs[0]
+}
+
+func TestServicePoolInferBatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"long running workers", echoScript},
+		// Every process answers one request and exits, so each worker
+		// has to restart before its next request.
+		{"crashing workers", "IFS= read -r line; printf '%s\\n' \"$line\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newTestPool(t, tt.script, 3)
+
+			inputs := make([]string, 10)
+			for i := range inputs {
+				inputs[i] = fmt.Sprintf("input %d", i)
+			}
+
+			results, err := pool.InferBatch(inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, result := range results {
+				if got := sentInput(t, result); got != inputs[i] {
+					t.Errorf("result %d is for %q, want %q", i, got, inputs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewServicePoolErrors(t *testing.T) {
+	if _, err := NewServicePool("./missing-coreml-cli", ".", 2); err == nil {
+		t.Error("expected an error for a missing binary")
+	}
+
+	binaryPath := writeFakeBinary(t, echoScript)
+	if _, err := NewServicePool(binaryPath, filepath.Dir(binaryPath), 0); err == nil {
+		t.Error("expected an error for zero workers")
+	}
+}
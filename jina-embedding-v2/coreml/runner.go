@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// commandRunner starts coreml-cli processes. Service uses execRunner; tests
+// substitute a fake so the process handling runs without the binary.
+type commandRunner interface {
+	// CombinedOutput runs name to completion and returns its stdout and
+	// stderr.
+	CombinedOutput(name string, args ...string) ([]byte, error)
+	// Start starts name with pipes to its standard streams.
+	Start(name string, args ...string) (process, error)
+}
+
+// process is a running coreml-cli started by a commandRunner.
+type process interface {
+	Stdin() io.WriteCloser
+	Stdout() io.ReadCloser
+	Stderr() io.ReadCloser
+	// Exited reports whether the process is known to have exited.
+	Exited() bool
+	Kill() error
+}
+
+// execRunner runs real binaries with os/exec.
+type execRunner struct{}
+
+func (execRunner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	if _, err := os.Stat(name); os.IsNotExist(err) {
+		return nil, fmt.Errorf("coreml-cli binary not found at %s", name)
+	}
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+func (execRunner) Start(name string, args ...string) (process, error) {
+	if _, err := os.Stat(name); os.IsNotExist(err) {
+		return nil, fmt.Errorf("coreml-cli binary not found at %s", name)
+	}
+
+	p := &execProcess{cmd: exec.Command(name, args...)}
+	var err error
+	if p.stdin, err = p.cmd.StdinPipe(); err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	if p.stdout, err = p.cmd.StdoutPipe(); err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if p.stderr, err = p.cmd.StderrPipe(); err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	if err := p.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start interactive process: %w", err)
+	}
+	return p, nil
+}
+
+type execProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+}
+
+func (p *execProcess) Stdin() io.WriteCloser { return p.stdin }
+func (p *execProcess) Stdout() io.ReadCloser { return p.stdout }
+func (p *execProcess) Stderr() io.ReadCloser { return p.stderr }
+
+func (p *execProcess) Exited() bool {
+	return p.cmd.ProcessState != nil && p.cmd.ProcessState.Exited()
+}
+
+func (p *execProcess) Kill() error {
+	if p.cmd.Process == nil {
+		return nil
+	}
+	return p.cmd.Process.Kill()
+}
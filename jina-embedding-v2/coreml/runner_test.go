@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRunner starts fakeProcesses that answer each request line with
+// respond(n, line), n counting the processes started so far. A response of
+// "" makes the process crash: it closes stdout without answering.
+type fakeRunner struct {
+	respond  func(n int, line string) string
+	startErr func(n int) error
+
+	starts int
+	args   [][]string
+	output []byte
+}
+
+func (r *fakeRunner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	r.args = append(r.args, args)
+	return r.output, nil
+}
+
+func (r *fakeRunner) Start(name string, args ...string) (process, error) {
+	r.starts++
+	r.args = append(r.args, args)
+	if r.startErr != nil {
+		if err := r.startErr(r.starts); err != nil {
+			return nil, err
+		}
+	}
+
+	n := r.starts
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	p := &fakeProcess{stdin: stdinW, stdout: stdoutR, stderr: stderrR}
+
+	go func() {
+		defer stdoutW.Close()
+		defer stderrW.Close()
+		scanner := bufio.NewScanner(stdinR)
+		for scanner.Scan() {
+			response := r.respond(n, scanner.Text())
+			if response == "" {
+				io.WriteString(stderrW, "fake crash\n")
+				stdinR.Close()
+				return
+			}
+			io.WriteString(stdoutW, response+"\n")
+		}
+	}()
+	return p, nil
+}
+
+type fakeProcess struct {
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+	killed bool
+}
+
+func (p *fakeProcess) Stdin() io.WriteCloser { return p.stdin }
+func (p *fakeProcess) Stdout() io.ReadCloser { return p.stdout }
+func (p *fakeProcess) Stderr() io.ReadCloser { return p.stderr }
+func (p *fakeProcess) Exited() bool          { return false }
+
+func (p *fakeProcess) Kill() error {
+	p.killed = true
+	return p.stdin.Close()
+}
+
+// newFakeService returns an interactive Service running runner's processes.
+func newFakeService(t *testing.T, runner *fakeRunner) *Service {
+	t.Helper()
+
+	s := newService("coreml-cli", t.TempDir(), true, nil)
+	s.runner = runner
+	if err := s.startInteractiveProcess(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestInferInteractiveFake(t *testing.T) {
+	runner := &fakeRunner{respond: func(n int, line string) string { return line }}
+	s := newFakeService(t, runner)
+
+	for i := 0; i < 3; i++ {
+		got, err := s.Infer("apple")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"version":1,"inputs":["apple"]}`; got != want {
+			t.Errorf("Infer = %q, want %q", got, want)
+		}
+	}
+	if runner.starts != 1 {
+		t.Errorf("started %d processes, want 1", runner.starts)
+	}
+	if want := []string{"interactive", s.modelPath}; !reflect.DeepEqual(runner.args[0], want) {
+		t.Errorf("started with %v, want %v", runner.args[0], want)
+	}
+}
+
+func TestInferInteractiveRestartsAfterCrash(t *testing.T) {
+	// The first process crashes on its first request; its replacement
+	// answers.
+	runner := &fakeRunner{respond: func(n int, line string) string {
+		if n == 1 {
+			return ""
+		}
+		return `{"version":1}`
+	}}
+	s := newFakeService(t, runner)
+
+	got, err := s.Infer("apple")
+	if err != nil {
+		t.Fatalf("Infer: %v", err)
+	}
+	if got != `{"version":1}` {
+		t.Errorf("Infer = %q", got)
+	}
+	if runner.starts != 2 {
+		t.Errorf("started %d processes, want 2", runner.starts)
+	}
+}
+
+func TestInferInteractiveGivesUpAfterRetry(t *testing.T) {
+	runner := &fakeRunner{respond: func(n int, line string) string { return "" }}
+	s := newFakeService(t, runner)
+
+	_, err := s.Infer("apple")
+	if err == nil || !strings.Contains(err.Error(), "no response from interactive process") {
+		t.Fatalf("Infer error = %v, want no response", err)
+	}
+	if runner.starts != 2 {
+		t.Errorf("started %d processes, want 2: the original and one restart", runner.starts)
+	}
+}
+
+func TestInferInteractiveRestartFails(t *testing.T) {
+	runner := &fakeRunner{
+		respond: func(n int, line string) string { return "" },
+		startErr: func(n int) error {
+			if n > 1 {
+				return errors.New("no binary")
+			}
+			return nil
+		},
+	}
+	s := newFakeService(t, runner)
+
+	_, err := s.Infer("apple")
+	if err == nil || !strings.Contains(err.Error(), "failed to restart interactive process: no binary") {
+		t.Fatalf("Infer error = %v, want the restart failure", err)
+	}
+}
+
+func TestInferNonInteractiveFake(t *testing.T) {
+	runner := &fakeRunner{output: []byte("[0.1,0.2]\n")}
+	s := newService("coreml-cli", t.TempDir(), false, nil)
+	s.runner = runner
+
+	got, err := s.Infer("apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "[0.1,0.2]\n" {
+		t.Errorf("Infer = %q", got)
+	}
+	if want := []string{"infer", s.modelPath, "apple"}; len(runner.args) != 1 || !reflect.DeepEqual(runner.args[0], want) {
+		t.Errorf("ran with %v, want %v", runner.args, want)
+	}
+}
+
+func TestInferInteractiveChecksProtocolVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantErr  string
+	}{
+		{"matching", `{"version":1,"embeddings":[[0.1]]}`, ""},
+		{"newer", `{"version":2,"embeddings":[[0.1]]}`, "protocol version 2, want version 1"},
+		// coreml-cli-v2 predates versioning and speaks version 1.
+		{"unversioned", `{"embeddings":[[0.1]]}`, ""},
+		{"not an object", `[[0.1]]`, "invalid response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{respond: func(n int, line string) string { return tt.response }}
+			s := newFakeService(t, runner)
+
+			got, err := s.Infer("apple")
+			if tt.wantErr == "" {
+				if err != nil || got != tt.response {
+					t.Errorf("Infer = %q, %v; want %q", got, err, tt.response)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Infer error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	var versionErr *ProtocolVersionError
+	if err := checkProtocolVersion([]byte(`{"version":3}`)); !errors.As(err, &versionErr) || versionErr.Got != 3 {
+		t.Errorf("checkProtocolVersion error = %v, want a ProtocolVersionError for version 3", err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stderrLines is how many trailing stderr lines are kept per process.
+const stderrLines = 20
+
+// stderrGrace is how long a failed request waits for the process to finish
+// writing stderr before the captured lines are reported.
+const stderrGrace = 200 * time.Millisecond
+
+// stderrRing keeps the last lines a process wrote to stderr.
+type stderrRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+	done  chan struct{}
+}
+
+func newStderrRing(size int) *stderrRing {
+	return &stderrRing{
+		lines: make([]string, size),
+		done:  make(chan struct{}),
+	}
+}
+
+// readFrom copies lines from r into the ring until r is closed.
+func (r *stderrRing) readFrom(rd io.Reader) {
+	defer close(r.done)
+
+	br := bufio.NewReader(rd)
+	for {
+		line, err := br.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			r.add(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (r *stderrRing) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// tail returns the captured lines, oldest first, after giving the process
+// up to grace to close stderr.
+func (r *stderrRing) tail(grace time.Duration) []string {
+	select {
+	case <-r.done:
+	case <-time.After(grace):
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	return append(append([]string(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// withStderr adds the process's last stderr lines to err, if there are any.
+func (s *Service) withStderr(err error) error {
+	if s.stderr == nil {
+		return err
+	}
+	lines := s.stderr.tail(stderrGrace)
+	if len(lines) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w; stderr:\n%s", err, strings.Join(lines, "\n"))
+}
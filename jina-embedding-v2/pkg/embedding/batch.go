@@ -0,0 +1,274 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PartialBatchError is returned by EmbedBatch when the soft timeout set with
+// WithBatchSoftTimeout expires before every input has been embedded. The
+// embeddings returned alongside it are valid for all other indices.
+type PartialBatchError struct {
+	// Unprocessed lists the indices of texts that were not embedded, in
+	// ascending order. Their entries in the result are nil.
+	Unprocessed []int
+}
+
+func (e *PartialBatchError) Error() string {
+	return fmt.Sprintf("batch soft timeout: %d inputs not processed", len(e.Unprocessed))
+}
+
+// EmbedBatch embeds several texts, padding them into shared inference runs.
+// Inputs are grouped by token length so rows of similar size share a run,
+// and, when WithMaxTokensPerBatch is set, each run is kept under that many
+// padded tokens. Results are returned in the order of texts.
+//
+// If WithBatchSoftTimeout is set and expires between runs, the completed
+// embeddings are returned together with a *PartialBatchError naming the
+// inputs that were skipped.
+func (m *Model) EmbedBatch(texts []string) ([][]float32, error) {
+	embeddings, _, err := m.embedBatch(context.Background(), texts, m.taskID, false)
+	return embeddings, err
+}
+
+// InputTooLongError is returned by EmbedBatchContext for an input longer
+// than MaxLength when the model was not created with WithTruncation.
+type InputTooLongError struct {
+	Index     int
+	Tokens    int
+	MaxLength int
+}
+
+func (e *InputTooLongError) Error() string {
+	return fmt.Sprintf("input %d is %d tokens long, exceeding model_max_length of %d", e.Index, e.Tokens, e.MaxLength)
+}
+
+// EmbedBatchContext is EmbedBatch giving up with ctx.Err() once ctx is
+// done, which is checked before tokenizing and before each inference run.
+// It also returns the number of tokens embedded, after truncation, so
+// callers need not tokenize the texts a second time to count them. Unless
+// the model was created with WithTruncation, an input longer than MaxLength
+// fails the whole call with an *InputTooLongError before any inference.
+func (m *Model) EmbedBatchContext(ctx context.Context, texts []string) ([][]float32, int, error) {
+	return m.embedBatch(ctx, texts, m.taskID, true)
+}
+
+// EmbedBatchWithTask is EmbedBatch using the LoRA adapter for taskType
+// instead of the one selected with WithTask. The tokenizer must implement
+// TaskTokenizer.
+func (m *Model) EmbedBatchWithTask(texts []string, taskType string) ([][]float32, error) {
+	taskID, err := m.resolveTask(taskType)
+	if err != nil {
+		return nil, err
+	}
+	embeddings, _, err := m.embedBatch(context.Background(), texts, taskID, false)
+	return embeddings, err
+}
+
+// EmbedBatchWithTaskContext is EmbedBatchContext using the LoRA adapter for
+// taskType, like EmbedBatchWithTask.
+func (m *Model) EmbedBatchWithTaskContext(ctx context.Context, texts []string, taskType string) ([][]float32, int, error) {
+	taskID, err := m.resolveTask(taskType)
+	if err != nil {
+		return nil, 0, err
+	}
+	return m.embedBatch(ctx, texts, taskID, true)
+}
+
+// resolveTask returns the task id of taskType. The tokenizer must
+// implement TaskTokenizer.
+func (m *Model) resolveTask(taskType string) (int64, error) {
+	taskTokenizer, ok := m.tokenizer.(TaskTokenizer)
+	if !ok {
+		return 0, fmt.Errorf("tokenizer cannot resolve task %q", taskType)
+	}
+	taskID, err := taskTokenizer.GetTaskID(taskType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve task %q: %w", taskType, err)
+	}
+	return taskID, nil
+}
+
+// embedBatch embeds texts and returns the total of their attention masks.
+// With checkLength set, inputs longer than MaxLength are rejected up front.
+func (m *Model) embedBatch(ctx context.Context, texts []string, taskID int64, checkLength bool) ([][]float32, int, error) {
+	if m.emptyInput == EmptyInputError {
+		for i, text := range texts {
+			if isEmptyText(text) {
+				return nil, 0, fmt.Errorf("input %d: %w", i, ErrEmptyInput)
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	start := time.Now()
+	ids, masks, err := m.encodeBatch(texts)
+	if err != nil {
+		return nil, 0, err
+	}
+	m.observeTokenize(start)
+	maxLength := m.MaxLength()
+	lengths := make([]int, len(texts))
+	tokens := 0
+	for i := range ids {
+		lengths[i] = len(ids[i])
+		if checkLength && maxLength > 0 && lengths[i] > maxLength {
+			return nil, 0, &InputTooLongError{Index: i, Tokens: lengths[i], MaxLength: maxLength}
+		}
+		for _, v := range masks[i] {
+			tokens += int(v)
+		}
+	}
+
+	var deadline time.Time
+	if m.batchSoftTimeout > 0 {
+		deadline = m.clock().Add(m.batchSoftTimeout)
+	}
+
+	results := make([][]float32, len(texts))
+	batches := packBatches(lengths, m.maxTokensPerBatch)
+	for n, batch := range batches {
+		if !deadline.IsZero() && m.clock().After(deadline) {
+			return results, tokens, newPartialBatchError(batches[n:])
+		}
+
+		embeddings, err := m.runPadded(ctx, ids, masks, batch, taskID)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i, idx := range batch {
+			results[idx] = embeddings[i]
+			if isEmptyText(texts[idx]) && m.emptyInput == EmptyInputZero {
+				clear(results[idx])
+			}
+		}
+	}
+
+	return results, tokens, nil
+}
+
+// clock returns the current time for the soft timeout, from m.now if a
+// test has set it.
+func (m *Model) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+func newPartialBatchError(remaining [][]int) *PartialBatchError {
+	var unprocessed []int
+	for _, batch := range remaining {
+		unprocessed = append(unprocessed, batch...)
+	}
+	sort.Ints(unprocessed)
+	return &PartialBatchError{Unprocessed: unprocessed}
+}
+
+// runPadded right-pads the selected rows to a common length and embeds them
+// in a single inference run.
+func (m *Model) runPadded(ctx context.Context, ids, masks [][]int64, rows []int, taskID int64) ([][]float32, error) {
+	seqLen := 0
+	for _, idx := range rows {
+		if len(ids[idx]) > seqLen {
+			seqLen = len(ids[idx])
+		}
+	}
+
+	batchSize := len(rows)
+	inputIds := make([]int64, batchSize*seqLen)
+	attentionMask := make([]int64, batchSize*seqLen)
+	for b, idx := range rows {
+		row := inputIds[b*seqLen : (b+1)*seqLen]
+		n := copy(row, ids[idx])
+		copy(attentionMask[b*seqLen:], masks[idx])
+		if n == seqLen {
+			continue
+		}
+		padID, err := m.padID()
+		if err != nil {
+			return nil, err
+		}
+		for i := n; i < seqLen; i++ {
+			row[i] = padID
+		}
+	}
+
+	flat, err := m.run(ctx, inputIds, attentionMask, batchSize, seqLen, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	embedDim := len(flat) / batchSize
+	embeddings := make([][]float32, batchSize)
+	for b := range embeddings {
+		// Cap each row so appending to one cannot overwrite the next.
+		end := (b + 1) * embedDim
+		embeddings[b] = flat[b*embedDim : end : end]
+	}
+	return embeddings, nil
+}
+
+// ErrNoPadToken is returned when texts of different lengths are batched
+// with a PadTokenizer that has no pad token.
+var ErrNoPadToken = errors.New("tokenizer has no pad token; batching texts of different lengths needs one")
+
+// padID returns the id padding positions get. Tokenizers that do not
+// implement PadTokenizer pad with 0; the attention mask hides those
+// positions, but a model with a different pad id may still attend to them
+// differently.
+func (m *Model) padID() (int64, error) {
+	tok, ok := m.tokenizer.(PadTokenizer)
+	if !ok {
+		return 0, nil
+	}
+	id := tok.PadID()
+	if id < 0 {
+		return 0, ErrNoPadToken
+	}
+	return id, nil
+}
+
+// packBatches groups input indices into batches whose padded size
+// (len(batch) × longest length in batch) does not exceed maxTokens. Indices
+// are sorted by length first to minimise padding. An input that is longer
+// than maxTokens on its own is placed in a batch by itself. A maxTokens of
+// zero or less puts everything in one batch.
+func packBatches(lengths []int, maxTokens int) [][]int {
+	if len(lengths) == 0 {
+		return nil
+	}
+
+	order := make([]int, len(lengths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return lengths[order[a]] < lengths[order[b]]
+	})
+
+	if maxTokens <= 0 {
+		return [][]int{order}
+	}
+
+	var batches [][]int
+	var current []int
+	for _, idx := range order {
+		// Lengths are ascending, so the incoming row sets the padded width.
+		if len(current) > 0 && (len(current)+1)*lengths[idx] > maxTokens {
+			batches = append(batches, current)
+			current = nil
+		}
+		current = append(current, idx)
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
@@ -0,0 +1,395 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPackBatchesRespectsTokenLimit(t *testing.T) {
+	lengths := []int{3, 40, 7, 12, 5, 90, 8, 8, 25, 4, 60, 2}
+	maxTokens := 64
+
+	batches := packBatches(lengths, maxTokens)
+
+	seen := make(map[int]bool)
+	for i, batch := range batches {
+		longest := 0
+		for _, idx := range batch {
+			if seen[idx] {
+				t.Fatalf("index %d packed twice", idx)
+			}
+			seen[idx] = true
+			if lengths[idx] > longest {
+				longest = lengths[idx]
+			}
+		}
+
+		padded := len(batch) * longest
+		if padded > maxTokens && len(batch) > 1 {
+			t.Errorf("batch %d has %d padded tokens, limit is %d", i, padded, maxTokens)
+		}
+	}
+
+	if len(seen) != len(lengths) {
+		t.Errorf("packed %d inputs, want %d", len(seen), len(lengths))
+	}
+}
+
+func TestPackBatchesOversizedInputIsolated(t *testing.T) {
+	batches := packBatches([]int{10, 100, 10}, 50)
+
+	for _, batch := range batches {
+		for _, idx := range batch {
+			if idx == 1 && len(batch) != 1 {
+				t.Errorf("oversized input shares a batch: %v", batch)
+			}
+		}
+	}
+}
+
+func TestPackBatchesNoLimit(t *testing.T) {
+	batches := packBatches([]int{5, 1, 3}, 0)
+
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("expected a single batch of 3, got %v", batches)
+	}
+	if batches[0][0] != 1 || batches[0][2] != 0 {
+		t.Errorf("expected inputs sorted by length, got %v", batches[0])
+	}
+}
+
+// lengthTokenizer encodes a text as one token per byte.
+type lengthTokenizer struct{}
+
+func (lengthTokenizer) Encode(text string) ([]int64, []int64) {
+	ids := make([]int64, len(text))
+	mask := make([]int64, len(text))
+	for i := range mask {
+		mask[i] = 1
+	}
+	return ids, mask
+}
+
+// slowSession sleeps for delay on every run and returns all-ones hidden
+// states. It records the task ID of each run.
+type slowSession struct {
+	delay   time.Duration
+	runs    int
+	taskIDs []int64
+}
+
+func (s *slowSession) run(in batchInput) ([]float32, int, error) {
+	s.runs++
+	s.taskIDs = append(s.taskIDs, in.taskID)
+	time.Sleep(s.delay)
+	const embedDim = 4
+	out := make([]float32, in.batchSize*in.seqLen*embedDim)
+	for i := range out {
+		out[i] = 1
+	}
+	return out, embedDim, nil
+}
+
+func (s *slowSession) destroy() {}
+
+// fakeClock is a clock that only moves when advanced.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// tickingSession advances clock by tick on every run instead of taking any
+// real time.
+type tickingSession struct {
+	slowSession
+	clock *fakeClock
+	tick  time.Duration
+}
+
+func (s *tickingSession) run(in batchInput) ([]float32, int, error) {
+	s.clock.now = s.clock.now.Add(s.tick)
+	return s.slowSession.run(in)
+}
+
+func TestEmbedBatchSoftTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	sess := &tickingSession{clock: clock, tick: 30 * time.Millisecond}
+	m := &Model{
+		session:           sess,
+		tokenizer:         lengthTokenizer{},
+		maxTokensPerBatch: 4,
+		batchSoftTimeout:  45 * time.Millisecond,
+		now:               clock.Now,
+	}
+
+	// Every text is 4 tokens long, so each gets its own run.
+	texts := []string{"aaaa", "bbbb", "cccc", "dddd", "eeee"}
+	results, err := m.EmbedBatch(texts)
+
+	var partial *PartialBatchError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected *PartialBatchError, got %v", err)
+	}
+	if sess.runs != 2 {
+		t.Fatalf("expected 2 runs before the deadline, got %d", sess.runs)
+	}
+	if want := []int{2, 3, 4}; !reflect.DeepEqual(partial.Unprocessed, want) {
+		t.Errorf("Unprocessed = %v, want %v", partial.Unprocessed, want)
+	}
+	for i, emb := range results {
+		done := i < 2
+		if done && len(emb) != 4 {
+			t.Errorf("result %d: expected an embedding, got %v", i, emb)
+		}
+		if !done && emb != nil {
+			t.Errorf("result %d: expected nil for unprocessed input, got %v", i, emb)
+		}
+	}
+}
+
+func TestEmbedBatchWithoutSoftTimeout(t *testing.T) {
+	m := &Model{
+		session:           &slowSession{},
+		tokenizer:         lengthTokenizer{},
+		maxTokensPerBatch: 4,
+	}
+
+	results, err := m.EmbedBatch([]string{"aaaa", "bb", "cccc"})
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	for i, emb := range results {
+		if len(emb) != 4 {
+			t.Errorf("result %d: expected an embedding, got %v", i, emb)
+		}
+	}
+}
+
+func TestEmbedBatchRowsDoNotAlias(t *testing.T) {
+	m := &Model{session: &slowSession{}, tokenizer: lengthTokenizer{}}
+
+	// Both texts share one run, so their rows come from the same output.
+	results, err := m.EmbedBatch([]string{"aa", "bb"})
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	want := slices.Clone(results[1])
+	_ = append(results[0], 42)
+	if !reflect.DeepEqual(results[1], want) {
+		t.Errorf("appending to result 0 changed result 1 to %v, want %v", results[1], want)
+	}
+}
+
+func TestEmbedBatchWithTask(t *testing.T) {
+	sess := &slowSession{}
+	m := &Model{
+		session:   sess,
+		tokenizer: stubTaskTokenizer{tasks: []string{"retrieval.query", "retrieval.passage", "text-matching"}},
+		taskID:    2,
+	}
+
+	if _, err := m.EmbedBatchWithTask([]string{"a"}, "retrieval.passage"); err != nil {
+		t.Fatalf("EmbedBatchWithTask: %v", err)
+	}
+	if _, err := m.EmbedBatch([]string{"a"}); err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if want := []int64{1, 2}; !reflect.DeepEqual(sess.taskIDs, want) {
+		t.Errorf("task IDs = %v, want %v", sess.taskIDs, want)
+	}
+
+	if _, err := m.EmbedBatchWithTask([]string{"a"}, "nope"); err == nil {
+		t.Error("expected an error for an unknown task")
+	}
+
+	if _, _, err := m.EmbedBatchWithTaskContext(context.Background(), []string{"a"}, "text-matching"); err != nil {
+		t.Fatalf("EmbedBatchWithTaskContext: %v", err)
+	}
+	if want := []int64{1, 2, 2}; !reflect.DeepEqual(sess.taskIDs, want) {
+		t.Errorf("task IDs = %v, want %v", sess.taskIDs, want)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := m.EmbedBatchWithTaskContext(ctx, []string{"a"}, "text-matching"); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled context: err = %v, want context.Canceled", err)
+	}
+
+	m = &Model{session: sess, tokenizer: stubTokenizer{}}
+	if _, err := m.EmbedBatchWithTask([]string{"a"}, "retrieval.query"); err == nil {
+		t.Error("expected an error for a tokenizer without tasks")
+	}
+}
+
+// padTokenizer is lengthTokenizer with a configurable pad id.
+type padTokenizer struct {
+	lengthTokenizer
+	padID int64
+}
+
+func (p padTokenizer) PadID() int64 { return p.padID }
+
+// recordingSession records the input ids of every run.
+type recordingSession struct {
+	positionSession
+	inputIds [][]int64
+}
+
+func (s *recordingSession) run(in batchInput) ([]float32, int, error) {
+	s.inputIds = append(s.inputIds, append([]int64(nil), in.inputIds...))
+	return s.positionSession.run(in)
+}
+
+func TestEmbedBatchPadsWithPadID(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenizer Tokenizer
+		want      []int64
+		wantErr   error
+	}{
+		// lengthTokenizer encodes every byte as id 0.
+		{"pad id", padTokenizer{padID: 7}, []int64{0, 7, 7, 0, 0, 0}, nil},
+		{"tokenizer without PadID pads with 0", lengthTokenizer{}, []int64{0, 0, 0, 0, 0, 0}, nil},
+		{"no pad token", padTokenizer{padID: -1}, nil, ErrNoPadToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := &recordingSession{}
+			m := &Model{session: sess, tokenizer: tt.tokenizer}
+
+			_, err := m.EmbedBatch([]string{"abc", "a"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			// Rows are sorted by length: "a" first, padded to three.
+			if len(sess.inputIds) != 1 || !reflect.DeepEqual(sess.inputIds[0], tt.want) {
+				t.Errorf("input ids = %v, want %v", sess.inputIds, tt.want)
+			}
+		})
+	}
+
+	// Texts of equal length need no padding, so no pad token either.
+	m := &Model{session: &recordingSession{}, tokenizer: padTokenizer{padID: -1}}
+	if _, err := m.EmbedBatch([]string{"ab", "cd"}); err != nil {
+		t.Errorf("equal-length batch: %v", err)
+	}
+}
+
+// batchTokenizer is lengthTokenizer with EncodeBatch, counting its calls.
+// With drop set, EncodeBatch loses the last text.
+type batchTokenizer struct {
+	lengthTokenizer
+	maxLength int
+	drop      bool
+	calls     *int
+}
+
+func (b batchTokenizer) EncodeBatch(texts []string) ([][]int64, [][]int64) {
+	*b.calls++
+	if b.drop {
+		texts = texts[:len(texts)-1]
+	}
+	ids := make([][]int64, len(texts))
+	masks := make([][]int64, len(texts))
+	for i, text := range texts {
+		ids[i], masks[i] = b.Encode(text)
+	}
+	return ids, masks
+}
+
+func (b batchTokenizer) ModelMaxLength() int { return b.maxLength }
+
+func TestEmbedBatchUsesEncodeBatch(t *testing.T) {
+	var calls int
+	sess := &recordingSession{}
+	m := &Model{
+		session:   sess,
+		tokenizer: batchTokenizer{maxLength: 3, calls: &calls},
+		truncate:  true,
+	}
+
+	if _, err := m.EmbedBatch([]string{"abcdef", "ab", "abc"}); err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("EncodeBatch called %d times, want once", calls)
+	}
+	// "abcdef" is truncated to three tokens like the others.
+	if len(sess.inputIds) != 1 || len(sess.inputIds[0]) != 3*3 {
+		t.Errorf("input ids = %v, want one batch of 3x3", sess.inputIds)
+	}
+
+	m.tokenizer = batchTokenizer{calls: &calls, drop: true}
+	if _, err := m.EmbedBatch([]string{"ab", "cd"}); err == nil {
+		t.Error("expected an error when EncodeBatch returns too few encodings")
+	}
+}
+
+func TestEmbedBatchContext(t *testing.T) {
+	var calls int
+	tests := []struct {
+		name       string
+		truncate   bool
+		texts      []string
+		wantTokens int
+		wantErr    bool
+	}{
+		{"counts tokens", false, []string{"ab", "abc"}, 5, false},
+		{"rejects an input over max length", false, []string{"ab", "abcdef"}, 0, true},
+		{"counts truncated tokens", true, []string{"ab", "abcdef"}, 6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := &slowSession{}
+			m := &Model{
+				session:   sess,
+				tokenizer: batchTokenizer{maxLength: 4, calls: &calls},
+				truncate:  tt.truncate,
+			}
+
+			embeddings, tokens, err := m.EmbedBatchContext(context.Background(), tt.texts)
+			if tt.wantErr {
+				var tooLong *InputTooLongError
+				if !errors.As(err, &tooLong) {
+					t.Fatalf("expected *InputTooLongError, got %v", err)
+				}
+				if *tooLong != (InputTooLongError{Index: 1, Tokens: 6, MaxLength: 4}) {
+					t.Errorf("error = %+v", *tooLong)
+				}
+				if sess.runs != 0 {
+					t.Errorf("expected no inference for a rejected batch, got %d runs", sess.runs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EmbedBatchContext: %v", err)
+			}
+			if len(embeddings) != len(tt.texts) {
+				t.Errorf("got %d embeddings, want %d", len(embeddings), len(tt.texts))
+			}
+			if tokens != tt.wantTokens {
+				t.Errorf("tokens = %d, want %d", tokens, tt.wantTokens)
+			}
+		})
+	}
+
+	sess := &slowSession{}
+	m := &Model{session: sess, tokenizer: lengthTokenizer{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := m.EmbedBatchContext(ctx, []string{"ab"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled context: err = %v, want context.Canceled", err)
+	}
+	if sess.runs != 0 {
+		t.Errorf("expected no inference after cancellation, got %d runs", sess.runs)
+	}
+}
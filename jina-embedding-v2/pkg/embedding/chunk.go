@@ -0,0 +1,105 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/pooling"
+)
+
+// EmbedLongText embeds a text of any length by splitting it into
+// overlapping windows (see EmbedChunks) and averaging the window embeddings
+// into one re-normalized vector.
+func (m *Model) EmbedLongText(text string, chunkTokens, overlapTokens int) ([]float32, error) {
+	chunks, err := m.EmbedChunks(text, chunkTokens, overlapTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	embedDim := len(chunks[0])
+	sum := make([]float32, embedDim)
+	for _, chunk := range chunks {
+		for i, v := range chunk {
+			sum[i] += v
+		}
+	}
+	for i := range sum {
+		sum[i] /= float32(len(chunks))
+	}
+	return pooling.L2Normalize(sum, 1, embedDim), nil
+}
+
+// EmbedChunks tokenizes text once and embeds it as a sequence of windows of
+// at most chunkTokens tokens, consecutive windows sharing overlapTokens
+// tokens. chunkTokens includes the leading and trailing special tokens,
+// which every window gets, so chunkTokens no larger than MaxLength keeps
+// every window within the model's limit. The window embeddings are returned
+// in text order; a text that fits in one window yields one embedding.
+func (m *Model) EmbedChunks(text string, chunkTokens, overlapTokens int) ([][]float32, error) {
+	if chunkTokens < 3 {
+		return nil, fmt.Errorf("chunk size must be at least 3 tokens, got %d", chunkTokens)
+	}
+	// Room left for text once the two special tokens are in.
+	width := chunkTokens - 2
+	if overlapTokens < 0 || overlapTokens >= width {
+		return nil, fmt.Errorf("overlap must be in [0, %d) for %d-token chunks, got %d", width, chunkTokens, overlapTokens)
+	}
+	if isEmptyText(text) && m.emptyInput == EmptyInputError {
+		return nil, ErrEmptyInput
+	}
+
+	start := time.Now()
+	inputIds, _ := m.tokenizer.Encode(text)
+	m.observeTokenize(start)
+	if len(inputIds) < 2 {
+		return nil, fmt.Errorf("tokenizer returned %d tokens, want at least the two special tokens", len(inputIds))
+	}
+
+	windows := chunkWindows(inputIds, width, overlapTokens)
+	masks := make([][]int64, len(windows))
+	lengths := make([]int, len(windows))
+	for i, window := range windows {
+		masks[i] = make([]int64, len(window))
+		for j := range masks[i] {
+			masks[i][j] = 1
+		}
+		lengths[i] = len(window)
+	}
+
+	chunks := make([][]float32, len(windows))
+	for _, batch := range packBatches(lengths, m.maxTokensPerBatch) {
+		embeddings, err := m.runPadded(context.Background(), windows, masks, batch, m.taskID)
+		if err != nil {
+			return nil, err
+		}
+		for i, idx := range batch {
+			chunks[idx] = embeddings[i]
+		}
+	}
+	if isEmptyText(text) && m.emptyInput == EmptyInputZero {
+		clear(chunks[0])
+	}
+	return chunks, nil
+}
+
+// chunkWindows splits the tokens between the first and last of ids (the
+// special tokens) into windows of at most width tokens advancing by
+// width-overlap, and wraps each window in the special tokens again.
+func chunkWindows(ids []int64, width, overlap int) [][]int64 {
+	first, last := ids[0], ids[len(ids)-1]
+	content := ids[1 : len(ids)-1]
+
+	var windows [][]int64
+	for start := 0; ; start += width - overlap {
+		end := min(start+width, len(content))
+		window := make([]int64, 0, end-start+2)
+		window = append(window, first)
+		window = append(window, content[start:end]...)
+		window = append(window, last)
+		windows = append(windows, window)
+		if end == len(content) {
+			return windows
+		}
+	}
+}
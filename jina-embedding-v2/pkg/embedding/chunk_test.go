@@ -0,0 +1,75 @@
+package embedding
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestChunkWindows(t *testing.T) {
+	// <s> 1..8 </s>, as longTokenizer encodes a 10-byte text.
+	ids := []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	tests := []struct {
+		name           string
+		ids            []int64
+		width, overlap int
+		want           [][]int64
+	}{
+		{"fits", ids, 8, 0, [][]int64{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}}},
+		{"no overlap", ids, 3, 0, [][]int64{{0, 1, 2, 3, 9}, {0, 4, 5, 6, 9}, {0, 7, 8, 9}}},
+		{"overlap", ids, 3, 1, [][]int64{{0, 1, 2, 3, 9}, {0, 3, 4, 5, 9}, {0, 5, 6, 7, 9}, {0, 7, 8, 9}}},
+		{"exact fit", ids, 4, 0, [][]int64{{0, 1, 2, 3, 4, 9}, {0, 5, 6, 7, 8, 9}}},
+		{"empty", []int64{0, 9}, 4, 0, [][]int64{{0, 9}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkWindows(tt.ids, tt.width, tt.overlap); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkWindows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbedChunks(t *testing.T) {
+	m := &Model{session: positionSession{}, tokenizer: longTokenizer{maxLength: 4}}
+
+	// 10 tokens, 5-token chunks with 1 token of overlap: 4 windows.
+	chunks, err := m.EmbedChunks("0123456789", 5, 1)
+	if err != nil {
+		t.Fatalf("EmbedChunks: %v", err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4", len(chunks))
+	}
+
+	long, err := m.EmbedLongText("0123456789", 5, 1)
+	if err != nil {
+		t.Fatalf("EmbedLongText: %v", err)
+	}
+	var norm float64
+	for _, v := range long {
+		norm += float64(v) * float64(v)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("EmbedLongText result has squared norm %v, want 1", norm)
+	}
+
+	// A text that fits in one chunk is embedded exactly like Embed.
+	want, _ := m.Embed("abc")
+	got, err := m.EmbedLongText("abc", 5, 1)
+	if err != nil || CosineSimilarity(got, want) < 0.99999 {
+		t.Errorf("EmbedLongText(short) = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestEmbedChunksValidates(t *testing.T) {
+	m := &Model{session: positionSession{}, tokenizer: longTokenizer{}}
+
+	for _, tt := range []struct{ chunk, overlap int }{{2, 0}, {5, 3}, {5, -1}} {
+		if _, err := m.EmbedChunks("0123456789", tt.chunk, tt.overlap); err == nil {
+			t.Errorf("EmbedChunks(chunk=%d, overlap=%d): expected an error", tt.chunk, tt.overlap)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package embedding
+
+// CoreMLFlags configures the onnxruntime CoreML execution provider. The
+// values are the COREML_FLAG_* bits of onnxruntime's
+// coreml_provider_factory.h and can be combined with |.
+type CoreMLFlags uint32
+
+const (
+	// CoreMLUseCPUOnly runs the CoreML graph on the CPU only, mainly to
+	// debug numerical differences.
+	CoreMLUseCPUOnly CoreMLFlags = 0x001
+	// CoreMLEnableOnSubgraph also offloads operators inside control-flow
+	// subgraphs.
+	CoreMLEnableOnSubgraph CoreMLFlags = 0x002
+	// CoreMLOnlyEnableDeviceWithANE enables the provider only on devices
+	// with an Apple Neural Engine.
+	CoreMLOnlyEnableDeviceWithANE CoreMLFlags = 0x004
+	// CoreMLOnlyAllowStaticInputShapes offloads only operators whose input
+	// shapes are fixed. Embedding inputs vary in length, so this usually
+	// keeps most of the model on the CPU.
+	CoreMLOnlyAllowStaticInputShapes CoreMLFlags = 0x008
+	// CoreMLCreateMLProgram compiles to the newer ML Program format, which
+	// supports more operators than NeuralNetwork.
+	CoreMLCreateMLProgram CoreMLFlags = 0x010
+	// CoreMLUseCPUAndGPU allows the CPU and GPU but not the Neural Engine.
+	CoreMLUseCPUAndGPU CoreMLFlags = 0x020
+)
+
+// WithCoreMLProvider runs the model through onnxruntime's CoreML execution
+// provider on macOS, which can place supported operators on the Apple
+// Neural Engine or GPU; the rest stay on the CPU. It needs an onnxruntime
+// build with CoreML support, as the macOS release archives are. On other
+// platforms the option is ignored and the model runs on the CPU.
+func WithCoreMLProvider(flags CoreMLFlags) Option {
+	return func(m *Model) {
+		m.coreML = coreMLSupported
+		m.coreMLFlags = flags
+	}
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package embedding
+
+import (
+	"fmt"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+const coreMLSupported = true
+
+func appendCoreMLProvider(options *ort.SessionOptions, flags CoreMLFlags) error {
+	if err := options.AppendExecutionProviderCoreML(uint32(flags)); err != nil {
+		return fmt.Errorf("failed to enable the CoreML execution provider: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package embedding
+
+import ort "github.com/yalue/onnxruntime_go"
+
+// coreMLSupported is false off macOS, where WithCoreMLProvider leaves the
+// model on the CPU.
+const coreMLSupported = false
+
+func appendCoreMLProvider(options *ort.SessionOptions, flags CoreMLFlags) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package embedding
+
+import "testing"
+
+func TestWithCoreMLProvider(t *testing.T) {
+	m := &Model{}
+	WithCoreMLProvider(CoreMLCreateMLProgram | CoreMLOnlyEnableDeviceWithANE)(m)
+
+	if m.coreML != coreMLSupported {
+		t.Errorf("coreML = %v, want %v on this platform", m.coreML, coreMLSupported)
+	}
+	if m.coreMLFlags != 0x014 {
+		t.Errorf("flags = %#x, want 0x14", m.coreMLFlags)
+	}
+
+	if !coreMLSupported {
+		// Off macOS the option must not change the session options.
+		options, err := m.sessionOptions()
+		if err != nil || options != nil {
+			t.Errorf("sessionOptions = %v, %v; want onnxruntime's defaults", options, err)
+		}
+	}
+}
@@ -1,147 +1,858 @@
 package embedding
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
-	"runtime"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/learn-onnx/jina-embedding-v2/pkg/pooling"
 	ort "github.com/yalue/onnxruntime_go"
 )
 
-func meanPooling(modelOutput []float32, attentionMask []int64, batchSize, seqLen, embedDim int) []float32 {
-	result := make([]float32, batchSize*embedDim)
+// Tokenizer turns text into the input ids and attention mask the model
+// reads. Encode is all a tokenizer needs; Model detects the optional
+// interfaces below (TaskTokenizer, TaskLister, MaxLengthTokenizer,
+// TokenCounter, PadTokenizer and BatchTokenizer) by type assertion, so an external
+// tokenizer, such as a binding to HuggingFace tokenizers, can be dropped
+// in with whichever of their methods it has.
+type Tokenizer interface {
+	Encode(text string) ([]int64, []int64)
+}
 
-	for b := 0; b < batchSize; b++ {
-		var sumMask float32
-		for i := 0; i < embedDim; i++ {
-			var sumEmbedding float32
-			for s := 0; s < seqLen; s++ {
-				maskVal := float32(attentionMask[b*seqLen+s])
-				embeddingVal := modelOutput[b*seqLen*embedDim+s*embedDim+i]
-				sumEmbedding += embeddingVal * maskVal
-				if i == 0 {
-					sumMask += maskVal
-				}
-			}
-			if sumMask < 1e-9 {
-				sumMask = 1e-9
-			}
-			result[b*embedDim+i] = sumEmbedding / sumMask
+// TaskTokenizer is implemented by tokenizers that can map a task name to the
+// index of its LoRA adapter, as the jina-embeddings-v3 tokenizer does.
+type TaskTokenizer interface {
+	Tokenizer
+	GetTaskID(taskType string) (int64, error)
+}
+
+// TaskLister is implemented by tokenizers that know the model's LoRA
+// adapters, in task id order, so task ids can be checked against them.
+type TaskLister interface {
+	Tokenizer
+	Tasks() []string
+}
+
+// ErrInvalidTask is returned, before running the model, for a task id
+// outside the model's LoRA adapters; onnxruntime would otherwise fail with
+// an index error deep inside the graph.
+var ErrInvalidTask = errors.New("invalid task id")
+
+// ErrClosed is returned by the embedding methods of a Model after Close,
+// for example one a Registry replaced while a request still held it.
+var ErrClosed = errors.New("model is closed")
+
+// MaxLengthTokenizer is implemented by tokenizers that know the longest
+// input, in tokens, the model accepts.
+type MaxLengthTokenizer interface {
+	Tokenizer
+	ModelMaxLength() int
+}
+
+// TokenCounter is implemented by tokenizers that can count the tokens of a
+// text without encoding it; the count must equal len of Encode's ids.
+type TokenCounter interface {
+	Tokenizer
+	CountTokens(text string) int
+}
+
+// PadTokenizer is implemented by tokenizers that know the id of their pad
+// token. PadID returns -1 when there is none.
+type PadTokenizer interface {
+	Tokenizer
+	PadID() int64
+}
+
+// BatchTokenizer is implemented by tokenizers that encode many texts faster
+// together than one at a time. EncodeBatch returns the ids and attention
+// mask of every text, in order, each as Encode would.
+type BatchTokenizer interface {
+	Tokenizer
+	EncodeBatch(texts []string) ([][]int64, [][]int64)
+}
+
+type Model struct {
+	// mu serialises inference: the session and its buffers are shared
+	// between calls.
+	mu      sync.Mutex
+	session session
+
+	tokenizer   Tokenizer
+	inputNames  []string
+	inputTypes  map[string]string
+	outputName  string
+	embedDim    int
+	inputSpecs  []TensorSpec
+	outputSpecs []TensorSpec
+
+	maxTokensPerBatch int
+	batchSoftTimeout  time.Duration
+	now               func() time.Time
+	queryPrefix       string
+	passagePrefix     string
+	task              string
+	taskID            int64
+	queryTaskID       int64
+	passageTaskID     int64
+	quantize          bool
+	preferQuantized   bool
+	intraOpThreads    int
+	interOpThreads    int
+	deterministic     bool
+	coreML            bool
+	coreMLFlags       CoreMLFlags
+	truncate          bool
+	metrics           MetricsHook
+	pooling           Pooling
+	prePooled         bool
+	tokenWeights      map[int64]float32
+	emptyInput        EmptyInput
+	languageDetector  LanguageDetector
+	languageRoutes    map[string]LanguageRoute
+	resolvedRoutes    map[string]languageRoute
+
+	buildInfo BuildInfo
+}
+
+// BuildInfo describes how a Model was loaded.
+type BuildInfo struct {
+	// ModelPath is the file the session was created from.
+	ModelPath string
+	// Quantization is "none" for the original weights, "int8" for a
+	// pre-quantized export or "dynamic-int8".
+	Quantization string
+}
+
+// Option configures a Model at construction time.
+type Option func(*Model)
+
+// WithMaxTokensPerBatch caps the padded token count (rows × longest row) of
+// every inference run issued by EmbedBatch. Zero disables the cap.
+func WithMaxTokensPerBatch(n int) Option {
+	return func(m *Model) {
+		m.maxTokensPerBatch = n
+	}
+}
+
+// WithBatchSoftTimeout bounds how long EmbedBatch keeps starting new
+// inference runs. A run already in progress is not interrupted; once d has
+// elapsed, EmbedBatch stops and returns what it has together with a
+// *PartialBatchError. Zero disables the timeout.
+func WithBatchSoftTimeout(d time.Duration) Option {
+	return func(m *Model) {
+		m.batchSoftTimeout = d
+	}
+}
+
+// WithInputNames overrides the input names read from the model metadata.
+// Every name must be one Embed knows how to fill.
+func WithInputNames(names []string) Option {
+	return func(m *Model) {
+		m.inputNames = names
+	}
+}
+
+// WithOutputName sets the name of the per-token hidden state output, e.g.
+// "text_embeds" for jina-embeddings-v3. Defaults to "last_hidden_state".
+func WithOutputName(name string) Option {
+	return func(m *Model) {
+		m.outputName = name
+	}
+}
+
+// WithEmbedDim sets the hidden size the model is expected to have, so a
+// different model fails with *ErrDimMismatch instead of producing
+// embeddings of the wrong size. By default the size is whatever the model
+// declares.
+func WithEmbedDim(n int) Option {
+	return func(m *Model) {
+		m.embedDim = n
+	}
+}
+
+// WithQueryPrefix sets the instruction prepended to texts by EmbedQuery.
+func WithQueryPrefix(prefix string) Option {
+	return func(m *Model) {
+		m.queryPrefix = prefix
+	}
+}
+
+// WithPassagePrefix sets the instruction prepended to texts by EmbedPassage.
+func WithPassagePrefix(prefix string) Option {
+	return func(m *Model) {
+		m.passagePrefix = prefix
+	}
+}
+
+// WithTask selects the LoRA adapter used by models with a task_id input,
+// e.g. "retrieval.query" or "text-matching" for jina-embeddings-v3. The
+// tokenizer must implement TaskTokenizer to resolve the name.
+func WithTask(taskType string) Option {
+	return func(m *Model) {
+		m.task = taskType
+	}
+}
+
+// WithDynamicQuantization loads the dynamically quantized int8 variant of
+// the model, which uses less memory and is faster on CPU. onnxruntime cannot
+// quantize in-process through its C API, so the variant must already exist
+// next to the model, named by convention: model.onnx -> model.quant.onnx
+// (see `make model/model.quant.onnx`).
+func WithDynamicQuantization() Option {
+	return func(m *Model) {
+		m.quantize = true
+	}
+}
+
+// WithTruncation cuts inputs longer than MaxLength down to MaxLength tokens,
+// keeping the trailing special token, instead of passing them to the model
+// as is.
+func WithTruncation() Option {
+	return func(m *Model) {
+		m.truncate = true
+	}
+}
+
+// WithQuantized selects an int8 quantized variant of the model. It prefers
+// model_quantized.onnx, the name used by the Hugging Face ONNX exports (see
+// `make model/model_quantized.onnx`), and falls back to model.quant.onnx as
+// produced for WithDynamicQuantization. The session is created with all
+// graph optimizations enabled so the quantized operators get fused.
+func WithQuantized(quantized bool) Option {
+	return func(m *Model) {
+		m.preferQuantized = quantized
+	}
+}
+
+// WithIntraOpThreads limits the threads onnxruntime uses inside a single
+// operator, e.g. a matrix multiply. Zero keeps onnxruntime's default of one
+// thread per physical core.
+//
+// onnxruntime runs its own thread pool, independent of the Go scheduler:
+// GOMAXPROCS does not bound it, and its threads compete with goroutines for
+// the same cores. On a shared host, set this (and possibly lower GOMAXPROCS)
+// so the two together fit the cores you want to use.
+func WithIntraOpThreads(n int) Option {
+	return func(m *Model) {
+		m.intraOpThreads = n
+	}
+}
+
+// WithInterOpThreads limits the threads onnxruntime uses to run independent
+// operators in parallel. onnxruntime runs operators sequentially by default,
+// so this only matters for models that benefit from parallel execution.
+// Zero keeps onnxruntime's default. See WithIntraOpThreads for how these
+// threads relate to GOMAXPROCS.
+func WithInterOpThreads(n int) Option {
+	return func(m *Model) {
+		m.interOpThreads = n
+	}
+}
+
+// WithDeterministic runs onnxruntime on a single thread with sequential
+// operator execution, so that the same input always yields a bit-identical
+// embedding, e.g. for regression snapshots. It overrides WithIntraOpThreads
+// and WithInterOpThreads. With several threads, the order in which partial
+// sums are combined varies from run to run and may change the last bits of
+// the result; the price of avoiding that is inference on one core.
+// Tokenization is deterministic either way. EmbedBatch pads each input to
+// the longest of its batch, so compare snapshots made with the same batching.
+func WithDeterministic(deterministic bool) Option {
+	return func(m *Model) {
+		m.deterministic = deterministic
+	}
+}
+
+// threads returns the thread count to configure in place of n, which is
+// always one for a deterministic Model.
+func (m *Model) threads(n int) int {
+	if m.deterministic {
+		return 1
+	}
+	return n
+}
+
+// resolveRetrievalTasks points EmbedQuery and EmbedPassage at the
+// "retrieval.query" and "retrieval.passage" adapters when the tokenizer knows
+// them, falling back to the default task otherwise.
+func (m *Model) resolveRetrievalTasks(tokenizer TaskTokenizer) {
+	if id, err := tokenizer.GetTaskID("retrieval.query"); err == nil {
+		m.queryTaskID = id
+	}
+	if id, err := tokenizer.GetTaskID("retrieval.passage"); err == nil {
+		m.passageTaskID = id
+	}
+}
+
+// quantizedModelPath returns the conventional location of the dynamically
+// quantized variant of modelPath.
+func quantizedModelPath(modelPath string) string {
+	ext := filepath.Ext(modelPath)
+	return strings.TrimSuffix(modelPath, ext) + ".quant" + ext
+}
+
+// findQuantizedModel returns the quantized variant of modelPath that
+// WithQuantized loads, with its BuildInfo quantization label.
+func findQuantizedModel(modelPath string) (string, string, error) {
+	candidates := []struct{ path, quantization string }{
+		{filepath.Join(filepath.Dir(modelPath), "model_quantized.onnx"), "int8"},
+		{quantizedModelPath(modelPath), "dynamic-int8"},
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(c.path); err == nil {
+			return c.path, c.quantization, nil
 		}
 	}
-	return result
+	return "", "", fmt.Errorf("quantized model not found: tried %s and %s", candidates[0].path, candidates[1].path)
 }
 
-func l2Normalize(embeddings []float32, batchSize, embedDim int) []float32 {
-	result := make([]float32, len(embeddings))
+func NewModel(modelPath string, tokenizer Tokenizer, opts ...Option) (*Model, error) {
+	m, err := newModel(tokenizer, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	for b := 0; b < batchSize; b++ {
-		var norm float32
-		for i := 0; i < embedDim; i++ {
-			val := embeddings[b*embedDim+i]
-			norm += val * val
+	m.buildInfo = BuildInfo{ModelPath: modelPath, Quantization: "none"}
+	if m.preferQuantized {
+		path, quantization, err := findQuantizedModel(modelPath)
+		if err != nil {
+			return nil, err
+		}
+		modelPath = path
+		m.buildInfo = BuildInfo{ModelPath: modelPath, Quantization: quantization}
+	} else if m.quantize {
+		modelPath = quantizedModelPath(modelPath)
+		if _, err := os.Stat(modelPath); err != nil {
+			return nil, fmt.Errorf("quantized model not found: %w", err)
 		}
-		norm = float32(math.Sqrt(float64(norm)))
+		m.buildInfo = BuildInfo{ModelPath: modelPath, Quantization: "dynamic-int8"}
+	}
+
+	if err := m.openSession(modelSource{path: modelPath}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
 
-		for i := 0; i < embedDim; i++ {
-			result[b*embedDim+i] = embeddings[b*embedDim+i] / norm
+// NewModelFromBytes is NewModel for a model already in memory, e.g. one
+// embedded with go:embed or fetched from object storage. data is not
+// retained after the session is created. WithDynamicQuantization and
+// WithQuantized are not supported; pass the quantized model's bytes instead.
+func NewModelFromBytes(data []byte, tokenizer Tokenizer, opts ...Option) (*Model, error) {
+	m, err := newModel(tokenizer, opts)
+	if err != nil {
+		return nil, err
+	}
+	if m.quantize || m.preferQuantized {
+		return nil, fmt.Errorf("quantization options need a model path; load the quantized model's bytes instead")
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("model data is empty")
+	}
+
+	m.buildInfo = BuildInfo{Quantization: "none"}
+	if err := m.openSession(modelSource{data: data}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// newModel applies opts and resolves the configured tasks.
+func newModel(tokenizer Tokenizer, opts []Option) (*Model, error) {
+	m := &Model{
+		tokenizer:  tokenizer,
+		outputName: "last_hidden_state",
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.task != "" {
+		taskTokenizer, ok := tokenizer.(TaskTokenizer)
+		if !ok {
+			return nil, fmt.Errorf("tokenizer cannot resolve task %q", m.task)
+		}
+		taskID, err := taskTokenizer.GetTaskID(m.task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve task %q: %w", m.task, err)
 		}
+		m.taskID = taskID
 	}
-	return result
+
+	m.queryTaskID, m.passageTaskID = m.taskID, m.taskID
+	if taskTokenizer, ok := tokenizer.(TaskTokenizer); ok {
+		m.resolveRetrievalTasks(taskTokenizer)
+	}
+	if err := m.resolveLanguageRoutes(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
 }
 
-type Tokenizer interface {
-	Encode(text string) ([]int64, []int64)
+// modelSource is an ONNX model either on disk (path) or in memory (data).
+type modelSource struct {
+	path string
+	data []byte
 }
 
-type Model struct {
-	session   *ort.DynamicAdvancedSession
-	tokenizer Tokenizer
+func (src modelSource) inputOutputInfo() ([]ort.InputOutputInfo, []ort.InputOutputInfo, error) {
+	if src.data != nil {
+		return ort.GetInputOutputInfoWithONNXData(src.data)
+	}
+	return ort.GetInputOutputInfo(src.path)
+}
+
+func (src modelSource) newSession(inputNames, outputNames []string, options *ort.SessionOptions) (*ort.DynamicAdvancedSession, error) {
+	if src.data != nil {
+		return ort.NewDynamicAdvancedSessionWithONNXData(src.data, inputNames, outputNames, options)
+	}
+	return ort.NewDynamicAdvancedSession(src.path, inputNames, outputNames, options)
 }
 
-func NewModel(modelPath string, tokenizer Tokenizer) (*Model, error) {
-	switch runtime.GOOS {
-	case "linux":
-		ort.SetSharedLibraryPath("/usr/local/lib/onnxruntime/lib/libonnxruntime.so")
-	case "darwin":
-		ort.SetSharedLibraryPath("/usr/local/lib/onnxruntime/libonnxruntime.dylib")
-	default:
-		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+// sessionOptions returns the onnxruntime session options implied by the
+// Model's options, or nil to use onnxruntime's defaults.
+func (m *Model) sessionOptions() (*ort.SessionOptions, error) {
+	if !m.preferQuantized && m.intraOpThreads <= 0 && m.interOpThreads <= 0 && !m.coreML && !m.deterministic {
+		return nil, nil
 	}
 
-	err := ort.InitializeEnvironment()
+	options, err := ort.NewSessionOptions()
 	if err != nil {
 		return nil, err
 	}
 
-	session, err := ort.NewDynamicAdvancedSession(modelPath,
-		[]string{"input_ids", "attention_mask", "token_type_ids"},
-		[]string{"last_hidden_state"}, nil)
-	if err != nil {
+	if err := m.applySessionOptions(options); err != nil {
+		options.Destroy()
 		return nil, err
 	}
+	return options, nil
+}
+
+func (m *Model) applySessionOptions(options *ort.SessionOptions) error {
+	if m.preferQuantized {
+		if err := options.SetGraphOptimizationLevel(ort.GraphOptimizationLevelEnableAll); err != nil {
+			return err
+		}
+	}
+	if m.deterministic {
+		if err := options.SetExecutionMode(ort.ExecutionModeSequential); err != nil {
+			return fmt.Errorf("failed to set sequential execution: %w", err)
+		}
+	}
+	if threads := m.threads(m.intraOpThreads); threads > 0 {
+		if err := options.SetIntraOpNumThreads(threads); err != nil {
+			return fmt.Errorf("failed to set intra-op threads: %w", err)
+		}
+	}
+	if threads := m.threads(m.interOpThreads); threads > 0 {
+		if err := options.SetInterOpNumThreads(threads); err != nil {
+			return fmt.Errorf("failed to set inter-op threads: %w", err)
+		}
+	}
+	if m.coreML {
+		if err := appendCoreMLProvider(options, m.coreMLFlags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openSession initializes onnxruntime and creates the inference session.
+func (m *Model) openSession(src modelSource) error {
+	if _, err := CheckRuntime(); err != nil {
+		return err
+	}
+
+	inputInfo, outputInfo, err := src.inputOutputInfo()
+	if err != nil {
+		return err
+	}
+	m.inputSpecs = tensorSpecs(inputInfo)
+	m.outputSpecs = tensorSpecs(outputInfo)
+	if err := checkOutputName(m.outputName, m.outputSpecs); err != nil {
+		return err
+	}
+	embedDim, err := outputDim(m.outputName, m.outputSpecs, m.prePooled)
+	if err != nil {
+		return err
+	}
+	if embedDim > 0 && m.embedDim > 0 && embedDim != m.embedDim {
+		return &ErrDimMismatch{Expected: m.embedDim, Got: embedDim}
+	}
+	m.inputTypes = make(map[string]string, len(inputInfo))
+	for _, info := range inputInfo {
+		m.inputTypes[info.Name] = dataTypeName(info.DataType)
+	}
 
-	return &Model{
-		session:   session,
-		tokenizer: tokenizer,
-	}, nil
+	inputNames := m.inputNames
+	if len(inputNames) == 0 {
+		for _, info := range inputInfo {
+			inputNames = append(inputNames, info.Name)
+		}
+	}
+
+	if err := checkInputNames(inputNames); err != nil {
+		return err
+	}
+	if m.task == "" && slices.Contains(inputNames, "task_id") {
+		return fmt.Errorf("model has a task_id input; select a task with WithTask")
+	}
+
+	options, err := m.sessionOptions()
+	if err != nil {
+		return err
+	}
+	if options != nil {
+		defer options.Destroy()
+	}
+
+	ortSess, err := src.newSession(inputNames, []string{m.outputName}, options)
+	if err != nil {
+		return err
+	}
+
+	m.session = &ortSession{
+		session:     ortSess,
+		inputNames:  inputNames,
+		embedDim:    embedDim,
+		expectedDim: m.embedDim,
+		pooled:      m.prePooled,
+	}
+	retainRuntime()
+	m.inputNames = inputNames
+
+	return nil
+}
+
+// InputTypes returns the element type of each model input as declared in
+// the model, keyed by input name: "int64", "int32", "float", "float16" and
+// so on.
+func (m *Model) InputTypes() map[string]string {
+	types := make(map[string]string, len(m.inputTypes))
+	for name, t := range m.inputTypes {
+		types[name] = t
+	}
+	return types
+}
+
+// dataTypeName turns ONNX_TENSOR_ELEMENT_DATA_TYPE_INT64 into "int64".
+func dataTypeName(t ort.TensorElementDataType) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "ONNX_TENSOR_ELEMENT_DATA_TYPE_"))
+}
+
+// checkInputNames rejects inputs Embed does not know how to fill, so a model
+// expecting anything else fails at load time instead of inside session.Run.
+func checkInputNames(names []string) error {
+	for _, name := range names {
+		switch name {
+		case "input_ids", "attention_mask", "token_type_ids", "position_ids", "task_id":
+		default:
+			return fmt.Errorf("unsupported model input: %s", name)
+		}
+	}
+	return nil
 }
 
+// positionIds returns 0..seqLen-1 for every row of a [batchSize, seqLen]
+// batch.
+func positionIds(batchSize, seqLen int) []int64 {
+	positions := make([]int64, batchSize*seqLen)
+	for b := 0; b < batchSize; b++ {
+		for s := 0; s < seqLen; s++ {
+			positions[b*seqLen+s] = int64(s)
+		}
+	}
+	return positions
+}
+
+// BuildInfo reports which model file was loaded and the quantization applied.
+func (m *Model) BuildInfo() BuildInfo {
+	return m.buildInfo
+}
+
+// Close releases the model's session. Embedding with the Model afterwards
+// fails with ErrClosed.
 func (m *Model) Close() {
-	if m.session != nil {
-		m.session.Destroy()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.session == nil {
+		return
+	}
+	_, usesRuntime := m.session.(*ortSession)
+	m.session.destroy()
+	m.session = nil
+	if usesRuntime {
+		releaseRuntime()
 	}
-	ort.DestroyEnvironment()
 }
 
 func (m *Model) Embed(inputText string) ([]float32, error) {
-	inputIds, attentionMask := m.tokenizer.Encode(inputText)
+	return m.embed(context.Background(), "", inputText, m.taskID)
+}
+
+// EmbedContext is Embed giving up with ctx.Err() once ctx is done. The
+// context is checked before tokenizing and again once the model is free,
+// just before inference; onnxruntime offers no way to interrupt a run
+// already in progress, so that one still finishes.
+func (m *Model) EmbedContext(ctx context.Context, text string) ([]float32, error) {
+	return m.embed(ctx, "", text, m.taskID)
+}
+
+// EmbedQuery embeds a search query: the query prefix is prepended and, for
+// models with a task_id input, the "retrieval.query" adapter is used.
+func (m *Model) EmbedQuery(text string) ([]float32, error) {
+	return m.embed(context.Background(), m.queryPrefix, text, m.queryTaskID)
+}
 
-	tokenTypeIds := make([]int64, len(inputIds))
-	for i := range tokenTypeIds {
-		tokenTypeIds[i] = 0
+// EmbedPassage embeds a document passage: the passage prefix is prepended
+// and, for models with a task_id input, the "retrieval.passage" adapter is
+// used.
+func (m *Model) EmbedPassage(text string) ([]float32, error) {
+	return m.embed(context.Background(), m.passagePrefix, text, m.passageTaskID)
+}
+
+// MaxLength returns the longest input, in tokens, the model accepts, or 0 if
+// the tokenizer does not implement MaxLengthTokenizer.
+func (m *Model) MaxLength() int {
+	if t, ok := m.tokenizer.(MaxLengthTokenizer); ok {
+		return t.ModelMaxLength()
 	}
+	return 0
+}
 
-	batchSize := 1
-	seqLen := len(inputIds)
-	embedDim := 768
+// TokenCount returns the number of tokens text occupies, including special
+// tokens: the sum of its attention mask. With WithTruncation the count is
+// after truncation.
+func (m *Model) TokenCount(text string) int {
+	if counter, ok := m.tokenizer.(TokenCounter); ok {
+		count := counter.CountTokens(text)
+		if maxLength := m.MaxLength(); m.truncate && maxLength > 0 && count > maxLength {
+			return maxLength
+		}
+		return count
+	}
 
-	inputIdsShape := ort.NewShape(int64(batchSize), int64(seqLen))
-	inputIdsTensor, err := ort.NewTensor(inputIdsShape, inputIds)
-	if err != nil {
+	_, attentionMask := m.encode(text)
+	count := 0
+	for _, v := range attentionMask {
+		count += int(v)
+	}
+	return count
+}
+
+// encode tokenizes text, truncating it when WithTruncation is set.
+func (m *Model) encode(text string) ([]int64, []int64) {
+	return m.truncateEncoding(m.tokenizer.Encode(text))
+}
+
+// encodeBatch tokenizes texts with EncodeBatch when the tokenizer is a
+// BatchTokenizer, otherwise one at a time, truncating each like encode.
+func (m *Model) encodeBatch(texts []string) ([][]int64, [][]int64, error) {
+	batchTokenizer, ok := m.tokenizer.(BatchTokenizer)
+	if !ok {
+		ids := make([][]int64, len(texts))
+		masks := make([][]int64, len(texts))
+		for i, text := range texts {
+			ids[i], masks[i] = m.encode(text)
+		}
+		return ids, masks, nil
+	}
+
+	ids, masks := batchTokenizer.EncodeBatch(texts)
+	if len(ids) != len(texts) || len(masks) != len(texts) {
+		return nil, nil, fmt.Errorf("EncodeBatch returned %d ids and %d masks for %d texts", len(ids), len(masks), len(texts))
+	}
+	for i := range ids {
+		ids[i], masks[i] = m.truncateEncoding(ids[i], masks[i])
+	}
+	return ids, masks, nil
+}
+
+// truncateEncoding applies WithTruncation to one encoded text.
+func (m *Model) truncateEncoding(inputIds, attentionMask []int64) ([]int64, []int64) {
+	maxLength := m.MaxLength()
+	if !m.truncate || maxLength <= 0 || len(inputIds) <= maxLength {
+		return inputIds, attentionMask
+	}
+	return truncateTokens(inputIds, maxLength), truncateTokens(attentionMask, maxLength)
+}
+
+// truncateTokens returns the first maxLength-1 values of s followed by its
+// last value, so a trailing end-of-sequence token survives. s is not
+// modified.
+func truncateTokens(s []int64, maxLength int) []int64 {
+	out := make([]int64, maxLength)
+	copy(out, s[:maxLength-1])
+	out[maxLength-1] = s[len(s)-1]
+	return out
+}
+
+// embed embeds prefix+text, applying the WithEmptyInput policy when text
+// is empty.
+func (m *Model) embed(ctx context.Context, prefix, text string, taskID int64) ([]float32, error) {
+	if isEmptyText(text) && m.emptyInput == EmptyInputError {
+		return nil, ErrEmptyInput
+	}
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	defer func() { _ = inputIdsTensor.Destroy() }()
 
-	attentionMaskShape := ort.NewShape(int64(batchSize), int64(seqLen))
-	attentionMaskTensor, err := ort.NewTensor(attentionMaskShape, attentionMask)
+	prefix, taskID = m.routeLanguage(prefix, text, taskID)
+	start := time.Now()
+	inputIds, attentionMask := m.encode(prefix + text)
+	m.observeTokenize(start)
+
+	embedding, err := m.run(ctx, inputIds, attentionMask, 1, len(inputIds), taskID)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = attentionMaskTensor.Destroy() }()
+	if isEmptyText(text) && m.emptyInput == EmptyInputZero {
+		clear(embedding)
+	}
+	return embedding, nil
+}
+
+// EmbedTokenIDs embeds text already turned into token ids by another
+// tokenizer, special tokens included, skipping the Model's own tokenizer.
+// Every id is attended to. Truncation, the default task, pooling and
+// normalization apply as for Embed.
+func (m *Model) EmbedTokenIDs(ids []int64) ([]float32, error) {
+	attentionMask := make([]int64, len(ids))
+	for i := range attentionMask {
+		attentionMask[i] = 1
+	}
+	return m.EmbedTokenIDsWithMask(ids, attentionMask)
+}
 
-	tokenTypeIdsShape := ort.NewShape(int64(batchSize), int64(seqLen))
-	tokenTypeIdsTensor, err := ort.NewTensor(tokenTypeIdsShape, tokenTypeIds)
+// EmbedTokenIDsWithMask is EmbedTokenIDs with an explicit attention mask,
+// e.g. for ids right-padded to a common length: positions whose mask is 0
+// are left out of pooling. mask must be as long as ids and hold only 0s and
+// 1s.
+func (m *Model) EmbedTokenIDsWithMask(ids, mask []int64) ([]float32, error) {
+	if len(ids) != len(mask) {
+		return nil, fmt.Errorf("attention mask has %d values for %d token ids", len(mask), len(ids))
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no token ids: %w", ErrEmptyInput)
+	}
+	for i, v := range mask {
+		if v != 0 && v != 1 {
+			return nil, fmt.Errorf("attention mask value %d at position %d, want 0 or 1", v, i)
+		}
+	}
+
+	inputIds, attentionMask := m.truncateEncoding(ids, mask)
+	return m.run(context.Background(), inputIds, attentionMask, 1, len(inputIds), m.taskID)
+}
+
+// EmbedTokens returns the unpooled, unnormalized hidden state of every
+// token of text, [seqLen][embedDim], along with the token ids, for
+// late-interaction retrieval or custom pooling. Truncation and the default
+// task apply as for Embed.
+func (m *Model) EmbedTokens(text string) ([][]float32, []int64, error) {
+	if m.prePooled {
+		return nil, nil, errors.New("EmbedTokens needs per-token hidden states, but the model is pre-pooled")
+	}
+	if isEmptyText(text) && m.emptyInput == EmptyInputError {
+		return nil, nil, ErrEmptyInput
+	}
+
+	start := time.Now()
+	inputIds, attentionMask := m.encode(text)
+	m.observeTokenize(start)
+	if len(inputIds) == 0 {
+		return nil, nil, fmt.Errorf("tokenizer returned no tokens: %w", ErrEmptyInput)
+	}
+	if err := m.checkTaskID(m.taskID); err != nil {
+		return nil, nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.session == nil {
+		return nil, nil, ErrClosed
+	}
+	defer m.observeInference(time.Now())
+
+	rawOutput, embedDim, err := m.session.run(batchInput{
+		inputIds:      inputIds,
+		attentionMask: attentionMask,
+		batchSize:     1,
+		seqLen:        len(inputIds),
+		taskID:        m.taskID,
+	})
 	if err != nil {
+		return nil, nil, err
+	}
+
+	// rawOutput may be the session's output buffer, so copy it before the
+	// lock is released.
+	hidden := make([][]float32, len(inputIds))
+	for s := range hidden {
+		hidden[s] = slices.Clone(rawOutput[s*embedDim : (s+1)*embedDim])
+	}
+	return hidden, inputIds, nil
+}
+
+// checkTaskID rejects a task id the model has no adapter for: a negative
+// one, or, when the tokenizer is a TaskLister, one past its last task.
+// Models without a task_id input ignore the id.
+func (m *Model) checkTaskID(taskID int64) error {
+	if !slices.Contains(m.inputNames, "task_id") {
+		return nil
+	}
+	if taskID < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidTask, taskID)
+	}
+	if lister, ok := m.tokenizer.(TaskLister); ok {
+		if n := len(lister.Tasks()); taskID >= int64(n) {
+			return fmt.Errorf("%w: %d, the model has %d task adapters", ErrInvalidTask, taskID, n)
+		}
+	}
+	return nil
+}
+
+// run runs one batch and pools it. ctx is checked after waiting for the
+// model, since another caller's inference may have held it for a while.
+func (m *Model) run(ctx context.Context, inputIds, attentionMask []int64, batchSize, seqLen int, taskID int64) ([]float32, error) {
+	if seqLen == 0 {
+		return nil, fmt.Errorf("tokenizer returned no tokens: %w", ErrEmptyInput)
+	}
+	if err := m.checkTaskID(taskID); err != nil {
 		return nil, err
 	}
-	defer func() { _ = tokenTypeIdsTensor.Destroy() }()
 
-	outputShape := ort.NewShape(int64(batchSize), int64(seqLen), int64(embedDim))
-	outputTensor, err := ort.NewEmptyTensor[float32](outputShape)
-	if err != nil {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	defer func() { _ = outputTensor.Destroy() }()
+	if m.session == nil {
+		return nil, ErrClosed
+	}
+	defer m.observeInference(time.Now())
 
-	err = m.session.Run([]ort.Value{inputIdsTensor, attentionMaskTensor, tokenTypeIdsTensor}, []ort.Value{outputTensor})
+	rawOutput, embedDim, err := m.session.run(batchInput{
+		inputIds:      inputIds,
+		attentionMask: attentionMask,
+		batchSize:     batchSize,
+		seqLen:        seqLen,
+		taskID:        taskID,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	rawOutput := outputTensor.GetData()
-	pooledEmbeddings := meanPooling(rawOutput, attentionMask, batchSize, seqLen, embedDim)
-	finalEmbeddings := l2Normalize(pooledEmbeddings, batchSize, embedDim)
+	pooledEmbeddings := m.pool(rawOutput, inputIds, attentionMask, batchSize, seqLen, embedDim)
+	finalEmbeddings := pooling.L2Normalize(pooledEmbeddings, batchSize, embedDim)
 
 	return finalEmbeddings, nil
 }
@@ -0,0 +1,437 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+func TestCheckInputNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []string
+		wantErr bool
+	}{
+		{
+			name:   "bert style",
+			inputs: []string{"input_ids", "attention_mask", "token_type_ids"},
+		},
+		{
+			name:   "without token_type_ids",
+			inputs: []string{"input_ids", "attention_mask"},
+		},
+		{
+			name:   "with position_ids",
+			inputs: []string{"input_ids", "attention_mask", "position_ids"},
+		},
+		{
+			name:    "unknown input",
+			inputs:  []string{"input_ids", "pixel_values"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkInputNames(tt.inputs)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPositionIds(t *testing.T) {
+	got := positionIds(2, 4)
+	want := []int64{0, 1, 2, 3, 0, 1, 2, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("positionIds(2, 4) = %v, want %v", got, want)
+	}
+}
+
+type stubTokenizer struct{}
+
+func (stubTokenizer) Encode(text string) ([]int64, []int64) {
+	return []int64{0, 2}, []int64{1, 1}
+}
+
+func TestNewModelTaskNeedsTaskTokenizer(t *testing.T) {
+	_, err := NewModel("model.onnx", stubTokenizer{}, WithTask("retrieval.query"))
+	if err == nil {
+		t.Fatal("expected an error for a tokenizer without GetTaskID")
+	}
+}
+
+type stubTaskTokenizer struct {
+	stubTokenizer
+	tasks []string
+}
+
+func (s stubTaskTokenizer) GetTaskID(taskType string) (int64, error) {
+	for i, task := range s.tasks {
+		if task == taskType {
+			return int64(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown task %q", taskType)
+}
+
+func (s stubTaskTokenizer) Tasks() []string { return s.tasks }
+
+func TestResolveRetrievalTasks(t *testing.T) {
+	tok := stubTaskTokenizer{tasks: []string{"retrieval.query", "retrieval.passage", "separation", "classification", "text-matching"}}
+
+	m := &Model{taskID: 4, queryTaskID: 4, passageTaskID: 4}
+	m.resolveRetrievalTasks(tok)
+
+	if m.queryTaskID != 0 || m.passageTaskID != 1 {
+		t.Errorf("query/passage task ids = %d/%d, want 0/1", m.queryTaskID, m.passageTaskID)
+	}
+	if m.queryTaskID == m.passageTaskID {
+		t.Error("query and passage must use different adapters")
+	}
+}
+
+func TestResolveRetrievalTasksFallback(t *testing.T) {
+	m := &Model{taskID: 2, queryTaskID: 2, passageTaskID: 2}
+	m.resolveRetrievalTasks(stubTaskTokenizer{tasks: []string{"text-matching"}})
+
+	if m.queryTaskID != 2 || m.passageTaskID != 2 {
+		t.Errorf("expected fallback to the default task, got %d/%d", m.queryTaskID, m.passageTaskID)
+	}
+}
+
+// longTokenizer encodes every text as ids 0..n-1 with n = len(text).
+type longTokenizer struct{ maxLength int }
+
+func (longTokenizer) Encode(text string) ([]int64, []int64) {
+	ids := make([]int64, len(text))
+	mask := make([]int64, len(text))
+	for i := range ids {
+		ids[i] = int64(i)
+		mask[i] = 1
+	}
+	return ids, mask
+}
+
+func (l longTokenizer) ModelMaxLength() int { return l.maxLength }
+
+func TestTruncation(t *testing.T) {
+	tok := longTokenizer{maxLength: 4}
+
+	m := &Model{tokenizer: tok}
+	if got := m.TokenCount("abcdef"); got != 6 {
+		t.Errorf("without truncation TokenCount = %d, want 6", got)
+	}
+
+	m = &Model{tokenizer: tok, truncate: true}
+	ids, mask := m.encode("abcdef")
+	if want := []int64{0, 1, 2, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("truncated ids = %v, want %v", ids, want)
+	}
+	if len(mask) != 4 {
+		t.Errorf("truncated mask has length %d, want 4", len(mask))
+	}
+	if got := m.TokenCount("ab"); got != 2 {
+		t.Errorf("short input TokenCount = %d, want 2", got)
+	}
+}
+
+// countingTokenizer is longTokenizer with CountTokens.
+type countingTokenizer struct {
+	longTokenizer
+	counted *int
+}
+
+func (c countingTokenizer) CountTokens(text string) int {
+	*c.counted++
+	return len(text)
+}
+
+func TestTokenCountUsesCounter(t *testing.T) {
+	var counted int
+	tok := countingTokenizer{longTokenizer{maxLength: 4}, &counted}
+
+	for _, tt := range []struct {
+		truncate bool
+		want     int
+	}{{false, 6}, {true, 4}} {
+		m := &Model{tokenizer: tok, truncate: tt.truncate}
+		if got := m.TokenCount("abcdef"); got != tt.want {
+			t.Errorf("truncate=%v: TokenCount = %d, want %d", tt.truncate, got, tt.want)
+		}
+	}
+	if counted != 2 {
+		t.Errorf("CountTokens called %d times, want 2", counted)
+	}
+}
+
+func TestNewModelFromBytesRejects(t *testing.T) {
+	if _, err := NewModelFromBytes(nil, stubTokenizer{}); err == nil {
+		t.Error("expected an error for empty model data")
+	}
+	if _, err := NewModelFromBytes([]byte("onnx"), stubTokenizer{}, WithDynamicQuantization()); err == nil {
+		t.Error("expected an error for WithDynamicQuantization")
+	}
+}
+
+func TestFindQuantizedModel(t *testing.T) {
+	dir := t.TempDir()
+	modelPath := filepath.Join(dir, "model.onnx")
+
+	if _, _, err := findQuantizedModel(modelPath); err == nil {
+		t.Error("expected an error when no quantized model exists")
+	}
+
+	touch := func(name string) {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	touch("model.quant.onnx")
+	path, quantization, err := findQuantizedModel(modelPath)
+	if err != nil || path != filepath.Join(dir, "model.quant.onnx") || quantization != "dynamic-int8" {
+		t.Errorf("findQuantizedModel = %q, %q, %v; want the dynamic fallback", path, quantization, err)
+	}
+
+	touch("model_quantized.onnx")
+	path, quantization, err = findQuantizedModel(modelPath)
+	if err != nil || path != filepath.Join(dir, "model_quantized.onnx") || quantization != "int8" {
+		t.Errorf("findQuantizedModel = %q, %q, %v; want model_quantized.onnx", path, quantization, err)
+	}
+}
+
+func TestDataTypeName(t *testing.T) {
+	tests := []struct {
+		in   ort.TensorElementDataType
+		want string
+	}{
+		{ort.TensorElementDataTypeInt64, "int64"},
+		{ort.TensorElementDataTypeInt32, "int32"},
+		{ort.TensorElementDataTypeFloat, "float"},
+		{ort.TensorElementDataTypeFloat16, "float16"},
+	}
+
+	for _, tt := range tests {
+		if got := dataTypeName(tt.in); got != tt.want {
+			t.Errorf("dataTypeName(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEmbedTokens(t *testing.T) {
+	m := &Model{session: positionSession{}, tokenizer: stubTokenizer{}}
+
+	hidden, ids, err := m.EmbedTokens("apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{0, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	// positionSession gives token s the hidden state [s+1, 1], unpooled
+	// and unnormalized.
+	if want := [][]float32{{1, 1}, {2, 1}}; !reflect.DeepEqual(hidden, want) {
+		t.Errorf("hidden states = %v, want %v", hidden, want)
+	}
+
+	m.emptyInput = EmptyInputError
+	if _, _, err := m.EmbedTokens(""); err != ErrEmptyInput {
+		t.Errorf("EmbedTokens(\"\") error = %v, want ErrEmptyInput", err)
+	}
+}
+
+func TestEmbedContext(t *testing.T) {
+	sess := &recordingSession{}
+	m := &Model{session: sess, tokenizer: stubTokenizer{}}
+
+	if _, err := m.EmbedContext(context.Background(), "apple"); err != nil {
+		t.Fatalf("EmbedContext: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.EmbedContext(ctx, "apple"); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled context: error = %v, want context.Canceled", err)
+	}
+
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	if _, err := m.EmbedContext(expired, "apple"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expired context: error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// A context cancelled while another inference holds the model is
+	// noticed once the model is free, before running.
+	ctx, cancel = context.WithCancel(context.Background())
+	m.mu.Lock()
+	done := make(chan error)
+	go func() {
+		_, err := m.EmbedContext(ctx, "apple")
+		done <- err
+	}()
+	cancel()
+	m.mu.Unlock()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled while waiting: error = %v, want context.Canceled", err)
+	}
+
+	if len(sess.inputIds) != 1 {
+		t.Errorf("session ran %d times, want only the first call", len(sess.inputIds))
+	}
+}
+
+// badTaskTokenizer resolves every task to id.
+type badTaskTokenizer struct {
+	stubTaskTokenizer
+	id int64
+}
+
+func (b badTaskTokenizer) GetTaskID(taskType string) (int64, error) { return b.id, nil }
+
+func TestInvalidTask(t *testing.T) {
+	tasks := []string{"retrieval.query", "text-matching"}
+	tests := []struct {
+		name    string
+		taskID  int64
+		wantErr error
+	}{
+		{"first", 0, nil},
+		{"last", 1, nil},
+		{"past the end", 2, ErrInvalidTask},
+		{"negative", -1, ErrInvalidTask},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := &recordingSession{}
+			m := &Model{
+				session:    sess,
+				tokenizer:  badTaskTokenizer{stubTaskTokenizer{tasks: tasks}, tt.taskID},
+				inputNames: []string{"input_ids", "attention_mask", "task_id"},
+				taskID:     tt.taskID,
+			}
+
+			_, err := m.Embed("apple")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Embed error = %v, want %v", err, tt.wantErr)
+			}
+			_, err = m.EmbedBatchWithTask([]string{"apple"}, "anything")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("EmbedBatchWithTask error = %v, want %v", err, tt.wantErr)
+			}
+			_, _, err = m.EmbedTokens("apple")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("EmbedTokens error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(sess.inputIds) != 0 {
+				t.Error("the model ran with an invalid task id")
+			}
+		})
+	}
+
+	// Without a task_id input the id is never used.
+	m := &Model{session: positionSession{}, tokenizer: stubTaskTokenizer{tasks: tasks}, taskID: 7}
+	if _, err := m.Embed("apple"); err != nil {
+		t.Errorf("Embed without a task_id input: %v", err)
+	}
+}
+
+func TestEmbedTokenIDs(t *testing.T) {
+	m := &Model{session: positionSession{}, tokenizer: stubTokenizer{}}
+
+	want, err := m.Embed("apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stubTokenizer encodes every text as ids [0, 2].
+	got, err := m.EmbedTokenIDs([]int64{0, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EmbedTokenIDs = %v, want Embed's %v", got, want)
+	}
+
+	if _, err := m.EmbedTokenIDs(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("EmbedTokenIDs(nil) error = %v, want ErrEmptyInput", err)
+	}
+
+	// Truncation keeps the last id, as for Embed.
+	sess := &recordingSession{}
+	m = &Model{session: sess, tokenizer: longTokenizer{maxLength: 3}, truncate: true}
+	ids := []int64{0, 5, 6, 7, 2}
+	if _, err := m.EmbedTokenIDs(ids); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{0, 5, 2}; len(sess.inputIds) != 1 || !reflect.DeepEqual(sess.inputIds[0], want) {
+		t.Errorf("model input = %v, want %v", sess.inputIds, want)
+	}
+	if ids[2] != 6 {
+		t.Error("EmbedTokenIDs modified its argument")
+	}
+}
+
+func TestEmbedTokenIDsWithMask(t *testing.T) {
+	m := &Model{session: positionSession{}, tokenizer: stubTokenizer{}}
+
+	// Masking out a trailing pad token leaves the embedding unchanged.
+	want, err := m.EmbedTokenIDs([]int64{0, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.EmbedTokenIDsWithMask([]int64{0, 2, 1}, []int64{1, 1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("padded EmbedTokenIDsWithMask = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name      string
+		ids, mask []int64
+	}{
+		{"short mask", []int64{0, 2}, []int64{1}},
+		{"long mask", []int64{0, 2}, []int64{1, 1, 1}},
+		{"non-binary mask", []int64{0, 2}, []int64{1, 2}},
+		{"negative mask", []int64{0, 2}, []int64{1, -1}},
+	}
+	for _, tt := range tests {
+		if _, err := m.EmbedTokenIDsWithMask(tt.ids, tt.mask); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	if _, err := m.EmbedTokenIDsWithMask(nil, nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("empty input error = %v, want ErrEmptyInput", err)
+	}
+}
+
+func TestDeterministicThreads(t *testing.T) {
+	m, err := newModel(stubTokenizer{}, []Option{WithIntraOpThreads(4), WithInterOpThreads(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.threads(m.intraOpThreads); got != 4 {
+		t.Errorf("intra-op threads = %d, want 4", got)
+	}
+
+	m, err = newModel(stubTokenizer{}, []Option{WithIntraOpThreads(4), WithInterOpThreads(2), WithDeterministic(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intra, inter := m.threads(m.intraOpThreads), m.threads(m.interOpThreads); intra != 1 || inter != 1 {
+		t.Errorf("deterministic threads = %d intra-op, %d inter-op, want 1 and 1", intra, inter)
+	}
+}
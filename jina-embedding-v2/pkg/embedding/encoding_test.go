@@ -0,0 +1,113 @@
+package embedding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeEmbedding(t *testing.T) {
+	vecs := [][]float32{
+		{},
+		{1},
+		{0.25, -1.5, float32(math.Inf(1)), math.SmallestNonzeroFloat32},
+	}
+
+	var buf bytes.Buffer
+	for _, vec := range vecs {
+		if err := EncodeEmbedding(&buf, vec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := 4*len(vecs) + 4*5; buf.Len() != want {
+		t.Errorf("encoded %d bytes, want %d", buf.Len(), want)
+	}
+
+	for i, want := range vecs {
+		got, err := DecodeEmbedding(&buf)
+		if err != nil {
+			t.Fatalf("decode %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("decode %d = %v, want %v", i, got, want)
+		}
+	}
+	if _, err := DecodeEmbedding(&buf); err != io.EOF {
+		t.Errorf("decode past the end: err = %v, want io.EOF", err)
+	}
+}
+
+func TestDecodeEmbeddingErrors(t *testing.T) {
+	var full bytes.Buffer
+	if err := EncodeEmbedding(&full, []float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	huge := binary.LittleEndian.AppendUint32(nil, maxEncodedLength+1)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated length", full.Bytes()[:2], io.ErrUnexpectedEOF},
+		{"truncated values", full.Bytes()[:10], io.ErrUnexpectedEOF},
+		{"length over limit", huge, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeEmbedding(bytes.NewReader(tt.data))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeEmbeddings(t *testing.T) {
+	vecs := make([][]float32, 100)
+	for i := range vecs {
+		vecs[i] = make([]float32, 768)
+		for j := range vecs[i] {
+			vecs[i][j] = float32(math.Sin(float64(i*768 + j)))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeEmbeddings(&buf, vecs); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonData, err := json.Marshal(vecs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ratio := float64(len(jsonData)) / float64(buf.Len()); ratio < 2.5 {
+		t.Errorf("binary encoding is only %.1fx smaller than JSON", ratio)
+	}
+
+	got, err := DecodeEmbeddings(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, vecs) {
+		t.Error("decoded embeddings differ from the encoded ones")
+	}
+
+	var short bytes.Buffer
+	if err := EncodeEmbeddings(&short, vecs[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeEmbeddings(bytes.NewReader(short.Bytes()[:short.Len()-4*768-4])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("decode with a missing embedding: err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,104 @@
+package embedding
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// Token ids FakeTokenizer reserves, following the XLM-RoBERTa layout.
+const (
+	fakeBosID = 0
+	fakePadID = 1
+	fakeEosID = 2
+	// fakeFirstWordID is the smallest id FakeTokenizer gives a word.
+	fakeFirstWordID = 3
+)
+
+// FakeTokenizer is a deterministic Tokenizer for tests that need no
+// tokenizer files. It lowercases text, splits it on whitespace and maps
+// each word to an id by hashing it, wrapping the result in <s> (0) and </s>
+// (2); <pad> is 1. Equal words always get equal ids.
+type FakeTokenizer struct {
+	// VocabSize bounds the ids; zero means 1000.
+	VocabSize int
+}
+
+func (t FakeTokenizer) Encode(text string) ([]int64, []int64) {
+	words := strings.Fields(strings.ToLower(text))
+	ids := make([]int64, 0, len(words)+2)
+	ids = append(ids, fakeBosID)
+	for _, word := range words {
+		ids = append(ids, t.wordID(word))
+	}
+	ids = append(ids, fakeEosID)
+
+	mask := make([]int64, len(ids))
+	for i := range mask {
+		mask[i] = 1
+	}
+	return ids, mask
+}
+
+// PadID implements PadTokenizer.
+func (t FakeTokenizer) PadID() int64 {
+	return fakePadID
+}
+
+// CountTokens implements TokenCounter.
+func (t FakeTokenizer) CountTokens(text string) int {
+	return len(strings.Fields(text)) + 2
+}
+
+func (t FakeTokenizer) wordID(word string) int64 {
+	size := t.VocabSize
+	if size <= fakeFirstWordID {
+		size = 1000
+	}
+	h := fnv.New64a()
+	h.Write([]byte(word))
+	return fakeFirstWordID + int64(h.Sum64()%uint64(size-fakeFirstWordID))
+}
+
+// NewFakeModel returns a Model that runs without onnxruntime or model
+// files: its hidden state for a token is a fixed pseudo-random vector of
+// embedDim values derived from the token id, so pooling, normalization,
+// batching and the other Model logic can be tested in isolation. Options
+// that configure the onnxruntime session have no effect.
+func NewFakeModel(tokenizer Tokenizer, embedDim int, opts ...Option) (*Model, error) {
+	m, err := newModel(tokenizer, opts)
+	if err != nil {
+		return nil, err
+	}
+	m.session = &fakeSession{embedDim: embedDim}
+	m.buildInfo = BuildInfo{Quantization: "none"}
+	return m, nil
+}
+
+// fakeSession is the session behind NewFakeModel.
+type fakeSession struct {
+	embedDim int
+}
+
+func (s *fakeSession) run(in batchInput) ([]float32, int, error) {
+	out := make([]float32, in.batchSize*in.seqLen*s.embedDim)
+	for i, id := range in.inputIds {
+		fakeHiddenState(out[i*s.embedDim:(i+1)*s.embedDim], id, in.taskID)
+	}
+	return out, s.embedDim, nil
+}
+
+func (s *fakeSession) destroy() {}
+
+// fakeHiddenState fills dst with values in [-1, 1) determined by the token
+// id and task, using splitmix64 so that nearby ids are uncorrelated.
+func fakeHiddenState(dst []float32, id, taskID int64) {
+	state := uint64(id)<<8 ^ uint64(taskID)
+	for i := range dst {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		z ^= z >> 31
+		dst[i] = float32(z>>11)/float32(1<<53)*2 - 1
+	}
+}
@@ -0,0 +1,126 @@
+package embedding
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFakeTokenizer(t *testing.T) {
+	tok := FakeTokenizer{VocabSize: 50}
+
+	ids, mask := tok.Encode("The cat  sat on the MAT")
+	if len(ids) != 8 || ids[0] != fakeBosID || ids[7] != fakeEosID {
+		t.Fatalf("ids = %v, want <s> + 6 words + </s>", ids)
+	}
+	if ids[1] != ids[5] {
+		t.Errorf("\"The\" and \"the\" got ids %d and %d, want equal", ids[1], ids[5])
+	}
+	for _, id := range ids[1:7] {
+		if id < fakeFirstWordID || id >= 50 {
+			t.Errorf("word id %d outside [%d, 50)", id, fakeFirstWordID)
+		}
+	}
+	if !reflect.DeepEqual(mask, []int64{1, 1, 1, 1, 1, 1, 1, 1}) {
+		t.Errorf("mask = %v", mask)
+	}
+	if n := tok.CountTokens("The cat  sat on the MAT"); n != len(ids) {
+		t.Errorf("CountTokens = %d, want %d", n, len(ids))
+	}
+
+	again, _ := tok.Encode("the cat sat on the mat")
+	if !reflect.DeepEqual(ids, again) {
+		t.Errorf("Encode is not deterministic: %v vs %v", ids, again)
+	}
+}
+
+func TestFakeModel(t *testing.T) {
+	m, err := NewFakeModel(FakeTokenizer{}, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	a, err := m.Embed("the quick brown fox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 16 {
+		t.Fatalf("len = %d, want 16", len(a))
+	}
+	if norm := math.Sqrt(float64(dot(a, a))); math.Abs(norm-1) > 1e-5 {
+		t.Errorf("norm = %v, want 1", norm)
+	}
+
+	again, _ := m.Embed("The quick brown fox")
+	if !reflect.DeepEqual(a, again) {
+		t.Error("equal token ids should give equal embeddings")
+	}
+	other, _ := m.Embed("a lazy dog")
+	if reflect.DeepEqual(a, other) {
+		t.Error("different texts gave equal embeddings")
+	}
+
+	// The batch path pads the shorter text; its embedding must not change.
+	batch, err := m.EmbedBatch([]string{"the quick brown fox", "a lazy dog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range [][]float32{a, other} {
+		for j := range want {
+			if math.Abs(float64(batch[i][j]-want[j])) > 1e-6 {
+				t.Errorf("batch[%d] = %v, want %v", i, batch[i], want)
+				break
+			}
+		}
+	}
+}
+
+func TestFakeModelCLSPooling(t *testing.T) {
+	m, err := NewFakeModel(FakeTokenizer{}, 8, WithPooling(CLSPooling))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every text starts with <s>, so CLS pooling ignores the words.
+	a, _ := m.Embed("apple")
+	b, _ := m.Embed("banana split")
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("CLS embeddings differ: %v vs %v", a, b)
+	}
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// TestFakeBatchMatchesSingle is TestEmbedBatchMatchesSingle on the fake
+// model, so the padding logic is checked without the real one.
+func TestFakeBatchMatchesSingle(t *testing.T) {
+	texts := []string{"apples are red", "the quick brown fox jumps over the lazy dog", "a b"}
+
+	for _, opt := range []Option{WithPooling(MeanPooling), WithPooling(CLSPooling), WithWeightedMean(map[int64]float32{fakeBosID: 0})} {
+		m, err := NewFakeModel(FakeTokenizer{}, 8, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		batch, err := m.EmbedBatch(texts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, text := range texts {
+			single, _ := m.Embed(text)
+			for j := range single {
+				if d := batch[i][j] - single[j]; d > 1e-6 || d < -1e-6 {
+					t.Errorf("%v: row %d = %v, want %v", m.pooling, i, batch[i], single)
+					break
+				}
+			}
+		}
+	}
+}
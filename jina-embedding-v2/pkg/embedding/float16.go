@@ -0,0 +1,103 @@
+package embedding
+
+import "math"
+
+// Float32ToFloat16 converts vec to IEEE 754 half precision, rounding to
+// nearest even. Magnitudes of 65520 and above become ±Inf. Below 2^-14
+// values are subnormal, in steps of 2^-24, the smallest of them, so only
+// magnitudes up to 2^-25 round to zero; embeddings are normalized, so
+// neither overflow nor underflow happens in practice.
+//
+// Half precision keeps about three decimal digits, enough to hold the
+// cosine similarity of a normalized embedding and its float32 original
+// above 0.9999 at half the size.
+func Float32ToFloat16(vec []float32) []uint16 {
+	h := make([]uint16, len(vec))
+	for i, v := range vec {
+		h[i] = float32ToFloat16(v)
+	}
+	return h
+}
+
+// Float16ToFloat32 reverses Float32ToFloat16. Every half-precision value,
+// subnormals included, is exactly representable as a float32.
+func Float16ToFloat32(h []uint16) []float32 {
+	vec := make([]float32, len(h))
+	for i, v := range h {
+		vec[i] = float16ToFloat32(v)
+	}
+	return vec
+}
+
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00 // quiet NaN
+		}
+		return sign | 0x7c00
+	}
+
+	// e is the exponent rebiased for half precision.
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00
+	}
+	if e <= 0 {
+		if e < -10 {
+			return sign
+		}
+		// Subnormal: shift the mantissa, implicit bit included, into the
+		// ten bits half precision has for it.
+		mant |= 0x800000
+		shift := uint(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	half := uint16(e)<<10 | uint16(mant>>13)
+	// A carry out of the mantissa correctly bumps the exponent, up to Inf.
+	if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return sign | half
+}
+
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0:
+		v := float32(mant) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	default:
+		return math.Float32frombits(sign | (exp-15+127)<<23 | mant<<13)
+	}
+}
+
+// EmbedFloat16 is Embed returning the embedding in IEEE half precision, for
+// clients that would rather receive two bytes per dimension than four. See
+// Float32ToFloat16.
+func (m *Model) EmbedFloat16(text string) ([]uint16, error) {
+	vec, err := m.Embed(text)
+	if err != nil {
+		return nil, err
+	}
+	return Float32ToFloat16(vec), nil
+}
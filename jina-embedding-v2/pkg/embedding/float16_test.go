@@ -0,0 +1,98 @@
+package embedding
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/pooling"
+)
+
+func TestFloat32ToFloat16(t *testing.T) {
+	tests := []struct {
+		in   float32
+		want uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.1, 0x2e66},
+		{65504, 0x7bff},
+		{65520, 0x7c00}, // rounds up to Inf
+		{1e6, 0x7c00},
+		{float32(math.Inf(-1)), 0xfc00},
+		{1.0 / (1 << 24), 0x0001}, // smallest subnormal
+		{1.0 / (1 << 14), 0x0400}, // smallest normal
+		{1.0 / (1 << 26), 0x0000}, // underflows
+		{1.0 / (1 << 25), 0x0000}, // halfway to the smallest subnormal, rounds to even
+		{1.5 / (1 << 25), 0x0001}, // rounds up to the smallest subnormal
+		{1 + 1.0/2048, 0x3c00},    // halfway, rounds to even
+		{1 + 3.0/2048, 0x3c02},    // halfway, rounds to even
+	}
+
+	for _, tt := range tests {
+		if got := Float32ToFloat16([]float32{tt.in})[0]; got != tt.want {
+			t.Errorf("Float32ToFloat16(%v) = %#04x, want %#04x", tt.in, got, tt.want)
+		}
+	}
+
+	if got := Float16ToFloat32(Float32ToFloat16([]float32{float32(math.NaN())}))[0]; !math.IsNaN(float64(got)) {
+		t.Errorf("NaN round trip = %v", got)
+	}
+}
+
+func TestFloat16RoundTrip(t *testing.T) {
+	// Every finite half converts to float32 and back unchanged.
+	for h := 0; h < 1<<16; h++ {
+		if h&0x7c00 == 0x7c00 {
+			continue
+		}
+		if got := float32ToFloat16(float16ToFloat32(uint16(h))); got != uint16(h) {
+			t.Fatalf("%#04x round trips to %#04x", h, got)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		vec := make([]float32, 768)
+		for j := range vec {
+			vec[j] = float32(rng.NormFloat64())
+		}
+		vec = pooling.L2Normalize(vec, 1, len(vec))
+
+		got := Float16ToFloat32(Float32ToFloat16(vec))
+		for j := range vec {
+			// Half precision has an 11-bit significand.
+			if d := math.Abs(float64(got[j] - vec[j])); d > math.Abs(float64(vec[j]))/2048+1.0/(1<<25) {
+				t.Fatalf("vector %d, dimension %d: %v became %v", i, j, vec[j], got[j])
+			}
+		}
+		if sim := CosineSimilarity(vec, got); sim < 0.9999 {
+			t.Fatalf("vector %d: cosine similarity after round trip = %v, want >= 0.9999", i, sim)
+		}
+	}
+}
+
+func TestEmbedFloat16(t *testing.T) {
+	m, err := NewFakeModel(FakeTokenizer{}, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	want, err := m.Embed("This is an apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	half, err := m.EmbedFloat16("This is an apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(half) != len(want) {
+		t.Fatalf("got %d dimensions, want %d", len(half), len(want))
+	}
+	if sim := CosineSimilarity(Float16ToFloat32(half), want); sim < 0.9999 {
+		t.Errorf("cosine similarity to Embed = %v, want >= 0.9999", sim)
+	}
+}
@@ -0,0 +1,100 @@
+package embedding
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// TensorSpec describes one input or output of the ONNX model as declared in
+// the model file.
+type TensorSpec struct {
+	Name string
+	// DataType is the element type: "int64", "float", "float16" and so on.
+	DataType string
+	// Shape lists the dimensions; a dynamic dimension such as batch size or
+	// sequence length is -1.
+	Shape []int64
+}
+
+// ModelInfo returns the inputs and outputs the model declares, in model
+// order, so a configuration can be checked against them before inference.
+// Embed feeds only the inputs listed by WithInputNames (all of them by
+// default) and reads the output selected with WithOutputName. It fails for
+// a Model that was not loaded from an ONNX model.
+func (m *Model) ModelInfo() (inputs []TensorSpec, outputs []TensorSpec, err error) {
+	if m.inputSpecs == nil && m.outputSpecs == nil {
+		return nil, nil, errors.New("model metadata is not available: no ONNX model loaded")
+	}
+	return cloneSpecs(m.inputSpecs), cloneSpecs(m.outputSpecs), nil
+}
+
+// tensorSpecs converts the metadata onnxruntime reports.
+func tensorSpecs(infos []ort.InputOutputInfo) []TensorSpec {
+	specs := make([]TensorSpec, len(infos))
+	for i, info := range infos {
+		specs[i] = TensorSpec{
+			Name:     info.Name,
+			DataType: dataTypeName(info.DataType),
+			Shape:    slices.Clone([]int64(info.Dimensions)),
+		}
+	}
+	return specs
+}
+
+func cloneSpecs(specs []TensorSpec) []TensorSpec {
+	cloned := make([]TensorSpec, len(specs))
+	for i, spec := range specs {
+		cloned[i] = spec
+		cloned[i].Shape = slices.Clone(spec.Shape)
+	}
+	return cloned
+}
+
+// checkOutputName fails unless the model has an output called name, listing
+// the ones it does have.
+func checkOutputName(name string, outputs []TensorSpec) error {
+	names := make([]string, len(outputs))
+	for i, output := range outputs {
+		if output.Name == name {
+			return nil
+		}
+		names[i] = output.Name
+	}
+	return fmt.Errorf("model has no output %q (outputs: %v); select one with WithOutputName", name, names)
+}
+
+// ErrDimMismatch is returned when the model's hidden size differs from the
+// one expected with WithEmbedDim: at load time if the model declares its
+// output dimension, otherwise by the first inference.
+type ErrDimMismatch struct {
+	Expected int
+	Got      int
+}
+
+func (e *ErrDimMismatch) Error() string {
+	return fmt.Sprintf("model output dimension is %d, expected %d", e.Got, e.Expected)
+}
+
+// outputDim returns the hidden size of the output called name, which must
+// be shaped [batch, sequence, hidden], or [batch, hidden] if pooled. It is 0
+// when the model leaves the hidden size dynamic, to be read from the output
+// of each run instead.
+func outputDim(name string, outputs []TensorSpec, pooled bool) (int, error) {
+	for _, output := range outputs {
+		if output.Name != name {
+			continue
+		}
+		rank, want := 3, "[batch, sequence, hidden]"
+		if pooled {
+			rank, want = 2, "[batch, hidden]"
+		}
+		if len(output.Shape) != rank {
+			return 0, fmt.Errorf("output %q has shape %v, want %s", name, output.Shape, want)
+		}
+		return int(max(output.Shape[len(output.Shape)-1], 0)), nil
+	}
+	return 0, fmt.Errorf("model has no output %q", name)
+}
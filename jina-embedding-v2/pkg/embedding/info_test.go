@@ -0,0 +1,104 @@
+package embedding
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+func TestTensorSpecs(t *testing.T) {
+	got := tensorSpecs([]ort.InputOutputInfo{
+		{Name: "input_ids", Dimensions: ort.NewShape(-1, -1), DataType: ort.TensorElementDataTypeInt64},
+		{Name: "last_hidden_state", Dimensions: ort.NewShape(-1, -1, 768), DataType: ort.TensorElementDataTypeFloat},
+	})
+	want := []TensorSpec{
+		{Name: "input_ids", DataType: "int64", Shape: []int64{-1, -1}},
+		{Name: "last_hidden_state", DataType: "float", Shape: []int64{-1, -1, 768}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tensorSpecs = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckOutputName(t *testing.T) {
+	outputs := []TensorSpec{{Name: "last_hidden_state"}, {Name: "pooler_output"}}
+
+	if err := checkOutputName("pooler_output", outputs); err != nil {
+		t.Errorf("checkOutputName(pooler_output) = %v", err)
+	}
+	err := checkOutputName("sentence_embedding", outputs)
+	if err == nil || !strings.Contains(err.Error(), "last_hidden_state pooler_output") {
+		t.Errorf("checkOutputName(sentence_embedding) = %v, want an error listing the outputs", err)
+	}
+}
+
+func TestModelInfo(t *testing.T) {
+	m := &Model{
+		inputSpecs:  []TensorSpec{{Name: "input_ids", DataType: "int64", Shape: []int64{-1, -1}}},
+		outputSpecs: []TensorSpec{{Name: "last_hidden_state", DataType: "float", Shape: []int64{-1, -1, 768}}},
+	}
+
+	inputs, outputs, err := m.ModelInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(inputs, m.inputSpecs) || !reflect.DeepEqual(outputs, m.outputSpecs) {
+		t.Errorf("ModelInfo = %+v, %+v", inputs, outputs)
+	}
+
+	// The result is a copy.
+	inputs[0].Shape[0] = 1
+	if m.inputSpecs[0].Shape[0] != -1 {
+		t.Error("modifying the returned specs changed the model's")
+	}
+
+	fake, _ := NewFakeModel(FakeTokenizer{}, 4)
+	if _, _, err := fake.ModelInfo(); err == nil {
+		t.Error("ModelInfo on a fake model should fail")
+	}
+}
+
+func TestOutputDim(t *testing.T) {
+	tests := []struct {
+		name    string
+		shape   []int64
+		want    int
+		wantErr bool
+	}{
+		{"base", []int64{-1, -1, 768}, 768, false},
+		{"small", []int64{-1, -1, 384}, 384, false},
+		{"dynamic hidden size", []int64{-1, -1, -1}, 0, false},
+		{"pooled output", []int64{-1, 768}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := outputDim("last_hidden_state", []TensorSpec{{Name: "last_hidden_state", Shape: tt.shape}}, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("outputDim error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("outputDim = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got, err := outputDim("sentence_embedding", []TensorSpec{{Name: "sentence_embedding", Shape: []int64{-1, 384}}}, true); err != nil || got != 384 {
+		t.Errorf("outputDim of a pooled output = %d, %v; want 384", got, err)
+	}
+	if _, err := outputDim("last_hidden_state", []TensorSpec{{Name: "last_hidden_state", Shape: []int64{-1, -1, 768}}}, true); err == nil {
+		t.Error("outputDim should reject a per-token output for a pre-pooled model")
+	}
+	if _, err := outputDim("text_embeds", []TensorSpec{{Name: "last_hidden_state", Shape: []int64{-1, -1, 768}}}, false); err == nil {
+		t.Error("outputDim of a missing output should fail")
+	}
+}
+
+func TestErrDimMismatch(t *testing.T) {
+	var err error = &ErrDimMismatch{Expected: 768, Got: 384}
+	if want := "model output dimension is 384, expected 768"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
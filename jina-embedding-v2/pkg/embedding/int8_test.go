@@ -0,0 +1,48 @@
+package embedding
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/pooling"
+)
+
+func TestQuantizeInt8RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		vec := make([]float32, 768)
+		for j := range vec {
+			vec[j] = float32(rng.NormFloat64())
+		}
+		vec = pooling.L2Normalize(vec, 1, len(vec))
+
+		q, scale := QuantizeInt8(vec)
+		got := DequantizeInt8(q, scale)
+
+		if sim := CosineSimilarity(vec, got); sim < 0.999 {
+			t.Fatalf("vector %d: cosine similarity after round trip = %v, want >= 0.999", i, sim)
+		}
+	}
+}
+
+func TestQuantizeInt8Range(t *testing.T) {
+	q, scale := QuantizeInt8([]float32{0.5, -1, 0, 0.25})
+	want := []int8{64, -127, 0, 32}
+	for i := range want {
+		if q[i] != want[i] {
+			t.Fatalf("QuantizeInt8 = %v, want %v", q, want)
+		}
+	}
+	if scale != float32(1)/127 {
+		t.Errorf("scale = %v, want 1/127", scale)
+	}
+
+	q, scale = QuantizeInt8([]float32{0, 0})
+	if scale != 0 || q[0] != 0 || q[1] != 0 {
+		t.Errorf("zero vector quantized to %v with scale %v", q, scale)
+	}
+	if got := DequantizeInt8(q, scale); got[0] != 0 || got[1] != 0 {
+		t.Errorf("zero vector dequantized to %v", got)
+	}
+}
@@ -0,0 +1,80 @@
+package embedding
+
+import "fmt"
+
+// LanguageDetector returns the language of text, for instance an ISO 639-1
+// code such as "en" or "de", or "" if it cannot tell. It lets a
+// multilingual pipeline plug in the detector of its choice without this
+// package depending on one.
+type LanguageDetector func(text string) string
+
+// LanguageRoute is what a detected language changes about how a text is
+// embedded.
+type LanguageRoute struct {
+	// Prefix is prepended to the text, ahead of any query or passage
+	// prefix.
+	Prefix string
+	// Task, if set, selects the LoRA adapter used instead of the one the
+	// call would otherwise use. The tokenizer must implement TaskTokenizer.
+	Task string
+}
+
+// languageRoute is a LanguageRoute with its task resolved.
+type languageRoute struct {
+	prefix string
+	task   bool
+	taskID int64
+}
+
+// WithLanguageDetector calls detect on every text given to Embed,
+// EmbedContext, EmbedQuery or EmbedPassage, before it is tokenized, and
+// applies the route for the language it returns. Languages without a route
+// are embedded as usual. The batch methods embed all their inputs with one
+// task and do not consult the detector.
+func WithLanguageDetector(detect LanguageDetector, routes map[string]LanguageRoute) Option {
+	return func(m *Model) {
+		m.languageDetector = detect
+		m.languageRoutes = routes
+	}
+}
+
+// resolveLanguageRoutes resolves the tasks of the WithLanguageDetector
+// routes.
+func (m *Model) resolveLanguageRoutes() error {
+	if len(m.languageRoutes) == 0 {
+		return nil
+	}
+	m.resolvedRoutes = make(map[string]languageRoute, len(m.languageRoutes))
+	for lang, route := range m.languageRoutes {
+		resolved := languageRoute{prefix: route.Prefix}
+		if route.Task != "" {
+			taskTokenizer, ok := m.tokenizer.(TaskTokenizer)
+			if !ok {
+				return fmt.Errorf("tokenizer cannot resolve task %q for language %q", route.Task, lang)
+			}
+			taskID, err := taskTokenizer.GetTaskID(route.Task)
+			if err != nil {
+				return fmt.Errorf("failed to resolve task %q for language %q: %w", route.Task, lang, err)
+			}
+			resolved.task, resolved.taskID = true, taskID
+		}
+		m.resolvedRoutes[lang] = resolved
+	}
+	return nil
+}
+
+// routeLanguage returns the prefix and task to embed text with, applying
+// the route for its detected language to the ones the call asked for.
+func (m *Model) routeLanguage(prefix, text string, taskID int64) (string, int64) {
+	if m.languageDetector == nil {
+		return prefix, taskID
+	}
+	route, ok := m.resolvedRoutes[m.languageDetector(text)]
+	if !ok {
+		return prefix, taskID
+	}
+	if route.task {
+		taskID = route.taskID
+	}
+	return route.prefix + prefix, taskID
+}
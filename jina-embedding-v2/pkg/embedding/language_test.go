@@ -0,0 +1,96 @@
+package embedding
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLanguageDetectorPrefix(t *testing.T) {
+	detect := func(text string) string {
+		if text == "bonjour le monde" {
+			return "fr"
+		}
+		return "en"
+	}
+	routed, err := NewFakeModel(FakeTokenizer{}, 8,
+		WithQueryPrefix("query: "),
+		WithLanguageDetector(detect, map[string]LanguageRoute{"fr": {Prefix: "french "}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := NewFakeModel(FakeTokenizer{}, 8, WithQueryPrefix("query: "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  func() ([]float32, error)
+		want func() ([]float32, error)
+	}{
+		{"routed", func() ([]float32, error) { return routed.Embed("bonjour le monde") },
+			func() ([]float32, error) { return plain.Embed("french bonjour le monde") }},
+		{"routed query", func() ([]float32, error) { return routed.EmbedQuery("bonjour le monde") },
+			func() ([]float32, error) { return plain.Embed("french query: bonjour le monde") }},
+		{"no route", func() ([]float32, error) { return routed.Embed("hello world") },
+			func() ([]float32, error) { return plain.Embed("hello world") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.got()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := tt.want()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("embedding differs from the expected prefixed text")
+			}
+		})
+	}
+}
+
+func TestLanguageDetectorTask(t *testing.T) {
+	tok := stubTaskTokenizer{tasks: []string{"retrieval.query", "retrieval.passage", "text-matching"}}
+	var detected []string
+	detect := func(text string) string {
+		detected = append(detected, text)
+		return text
+	}
+	m, err := newModel(tok, []Option{
+		WithTask("text-matching"),
+		WithLanguageDetector(detect, map[string]LanguageRoute{"de": {Task: "retrieval.passage"}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := &slowSession{}
+	m.session = sess
+
+	for _, text := range []string{"de", "en"} {
+		if _, err := m.Embed(text); err != nil {
+			t.Fatalf("Embed(%q): %v", text, err)
+		}
+	}
+	if want := []int64{1, 2}; !reflect.DeepEqual(sess.taskIDs, want) {
+		t.Errorf("task IDs = %v, want %v", sess.taskIDs, want)
+	}
+	if want := []string{"de", "en"}; !reflect.DeepEqual(detected, want) {
+		t.Errorf("detector saw %v, want %v", detected, want)
+	}
+
+	_, err = newModel(tok, []Option{
+		WithLanguageDetector(detect, map[string]LanguageRoute{"de": {Task: "nonexistent"}}),
+	})
+	if err == nil {
+		t.Error("expected an error for a route with an unknown task")
+	}
+	_, err = newModel(stubTokenizer{}, []Option{
+		WithLanguageDetector(detect, map[string]LanguageRoute{"de": {Task: "retrieval.passage"}}),
+	})
+	if err == nil {
+		t.Error("expected an error for a route task without a TaskTokenizer")
+	}
+}
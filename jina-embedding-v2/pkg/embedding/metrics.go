@@ -0,0 +1,37 @@
+package embedding
+
+import "time"
+
+// MetricsHook receives timings from a Model, so callers can export them to
+// a metrics system of their choice without this package depending on one.
+// Methods are called synchronously from the embedding goroutine and must
+// not block.
+type MetricsHook interface {
+	// OnTokenize reports the time spent tokenizing the input of one Embed
+	// or EmbedBatch call.
+	OnTokenize(d time.Duration)
+	// OnInference reports the time spent in one inference run, including
+	// pooling and normalization but not waiting for another run to finish.
+	OnInference(d time.Duration)
+}
+
+// WithMetrics reports tokenization and inference timings to hook.
+func WithMetrics(hook MetricsHook) Option {
+	return func(m *Model) {
+		m.metrics = hook
+	}
+}
+
+// observeTokenize reports the time since start to the metrics hook, if any.
+func (m *Model) observeTokenize(start time.Time) {
+	if m.metrics != nil {
+		m.metrics.OnTokenize(time.Since(start))
+	}
+}
+
+// observeInference reports the time since start to the metrics hook, if any.
+func (m *Model) observeInference(start time.Time) {
+	if m.metrics != nil {
+		m.metrics.OnInference(time.Since(start))
+	}
+}
@@ -0,0 +1,50 @@
+package embedding
+
+import (
+	"testing"
+	"time"
+)
+
+// recordingHook collects the durations it is given.
+type recordingHook struct {
+	tokenize  []time.Duration
+	inference []time.Duration
+}
+
+func (h *recordingHook) OnTokenize(d time.Duration)  { h.tokenize = append(h.tokenize, d) }
+func (h *recordingHook) OnInference(d time.Duration) { h.inference = append(h.inference, d) }
+
+func TestMetricsHook(t *testing.T) {
+	hook := &recordingHook{}
+	m := &Model{
+		session:           &slowSession{delay: 5 * time.Millisecond},
+		tokenizer:         lengthTokenizer{},
+		maxTokensPerBatch: 4,
+	}
+	WithMetrics(hook)(m)
+
+	if _, err := m.Embed("abc"); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(hook.tokenize) != 1 || len(hook.inference) != 1 {
+		t.Fatalf("after Embed: %d tokenize and %d inference calls, want 1 and 1", len(hook.tokenize), len(hook.inference))
+	}
+	if hook.inference[0] < 5*time.Millisecond {
+		t.Errorf("inference took %v, want at least the session's 5ms", hook.inference[0])
+	}
+
+	// Two 4-token texts under a 4-token cap: one tokenize, two runs.
+	if _, err := m.EmbedBatch([]string{"aaaa", "bbbb"}); err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if len(hook.tokenize) != 2 || len(hook.inference) != 3 {
+		t.Errorf("after EmbedBatch: %d tokenize and %d inference calls, want 2 and 3", len(hook.tokenize), len(hook.inference))
+	}
+}
+
+func TestNoMetricsHook(t *testing.T) {
+	m := &Model{session: &slowSession{}, tokenizer: lengthTokenizer{}}
+	if _, err := m.Embed("abc"); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+}
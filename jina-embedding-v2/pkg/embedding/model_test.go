@@ -0,0 +1,373 @@
+package embedding
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
+)
+
+// The pure-Go tokenizer implements every optional interface except
+// BatchTokenizer.
+var (
+	_ TaskTokenizer      = (*tokenizer.SentencePieceTokenizer)(nil)
+	_ TaskLister         = (*tokenizer.SentencePieceTokenizer)(nil)
+	_ MaxLengthTokenizer = (*tokenizer.SentencePieceTokenizer)(nil)
+	_ TokenCounter       = (*tokenizer.SentencePieceTokenizer)(nil)
+	_ PadTokenizer       = (*tokenizer.SentencePieceTokenizer)(nil)
+)
+
+// modelDir is where `make download-model` puts the jina-embeddings-v2 files.
+// Override it with EMBEDDING_MODEL_DIR.
+func modelDir() string {
+	if dir := os.Getenv("EMBEDDING_MODEL_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join("..", "..", "model")
+}
+
+// loadTestModel loads the real model, skipping when the model files or the
+// onnxruntime library are not installed.
+func loadTestModel(tb testing.TB, opts ...Option) *Model {
+	tb.Helper()
+
+	dir := modelDir()
+	modelPath := filepath.Join(dir, "model.onnx")
+	if _, err := os.Stat(modelPath); err != nil {
+		tb.Skipf("model not available at %s (run make download-model)", modelPath)
+	}
+
+	tok := tokenizer.NewSentencePieceTokenizer()
+	err := tok.LoadFromLocal(filepath.Join(dir, "tokenizer.json"), filepath.Join(dir, "config.json"))
+	if err != nil {
+		tb.Skipf("tokenizer not available: %v", err)
+	}
+
+	m, err := NewModel(modelPath, tok, opts...)
+	if err != nil {
+		tb.Skipf("onnxruntime not available: %v", err)
+	}
+	tb.Cleanup(m.Close)
+	return m
+}
+
+func TestQuantizedModelPath(t *testing.T) {
+	got := quantizedModelPath(filepath.Join("model", "model.onnx"))
+	want := filepath.Join("model", "model.quant.onnx")
+	if got != want {
+		t.Errorf("quantizedModelPath = %q, want %q", got, want)
+	}
+}
+
+func TestDynamicQuantizationMatchesFP32(t *testing.T) {
+	quantPath := quantizedModelPath(filepath.Join(modelDir(), "model.onnx"))
+	if _, err := os.Stat(quantPath); err != nil {
+		t.Skipf("quantized model not available at %s (run make model/model.quant.onnx)", quantPath)
+	}
+
+	text := "This is an apple"
+
+	fp32 := loadTestModel(t)
+	want, err := fp32.Embed(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp32.Close()
+
+	quant := loadTestModel(t, WithDynamicQuantization())
+	if info := quant.BuildInfo(); info.Quantization != "dynamic-int8" || info.ModelPath != quantPath {
+		t.Errorf("unexpected BuildInfo: %+v", info)
+	}
+	got, err := quant.Embed(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sim := CosineSimilarity(want, got); sim < 0.98 {
+		t.Errorf("quantized embedding similarity %v, want >= 0.98", sim)
+	}
+}
+
+func TestEmbedQueryAndPassageDiffer(t *testing.T) {
+	m := loadTestModel(t,
+		WithQueryPrefix("query: "),
+		WithPassagePrefix("passage: "))
+
+	query, err := m.EmbedQuery("This is an apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passage, err := m.EmbedPassage("This is an apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sim := CosineSimilarity(query, passage); sim > 0.9999 {
+		t.Errorf("query and passage embeddings are identical (similarity %v)", sim)
+	}
+}
+
+// TestEmbedBatchMatchesSingle runs three sentences of different lengths
+// through one padded batch and checks every row against embedding that
+// sentence on its own, so padding must not leak into pooling.
+func TestEmbedBatchMatchesSingle(t *testing.T) {
+	texts := []string{
+		"Apples are red.",
+		"The quick brown fox jumps over the lazy dog near the riverbank.",
+		"A considerably longer sentence, padded to nothing, that forces the two shorter ones in the batch to carry several padding tokens each.",
+	}
+
+	for _, pooling := range []Pooling{MeanPooling, CLSPooling} {
+		t.Run(pooling.String(), func(t *testing.T) {
+			m := loadTestModel(t, WithPooling(pooling))
+
+			batch, err := m.EmbedBatch(texts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(batch) != len(texts) {
+				t.Fatalf("got %d embeddings, want %d", len(batch), len(texts))
+			}
+
+			for i, text := range texts {
+				single, err := m.Embed(text)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(batch[i]) != len(single) {
+					t.Fatalf("row %d has %d dimensions, want %d", i, len(batch[i]), len(single))
+				}
+				for j := range single {
+					if d := batch[i][j] - single[j]; d > 1e-4 || d < -1e-4 {
+						t.Errorf("row %d differs from Embed at dimension %d: %v vs %v", i, j, batch[i][j], single[j])
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEmbedSameLength embeds the same text repeatedly, so every call
+// after the first reuses the cached tensors.
+func BenchmarkEmbedSameLength(b *testing.B) {
+	m := loadTestModel(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.Embed("This is an apple"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEmbedVaryingLength alternates between two lengths, forcing fresh
+// tensors on every call. Compare allocs/op with BenchmarkEmbedSameLength.
+func BenchmarkEmbedVaryingLength(b *testing.B) {
+	m := loadTestModel(b)
+	texts := []string{"This is an apple", "This is an apple and a banana"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.Embed(texts[i%2]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestNewModelFromBytesMatchesPath(t *testing.T) {
+	m := loadTestModel(t)
+	want, err := m.Embed("This is an apple")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	// onnxruntime allows one environment per process.
+	m.Close()
+
+	data, err := os.ReadFile(filepath.Join(modelDir(), "model.onnx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBytes, err := NewModelFromBytes(data, m.tokenizer)
+	if err != nil {
+		t.Fatalf("NewModelFromBytes: %v", err)
+	}
+	defer fromBytes.Close()
+
+	got, err := fromBytes.Embed("This is an apple")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if sim := CosineSimilarity(got, want); sim < 0.9999 {
+		t.Errorf("in-memory model embedding differs from file model: similarity %v", sim)
+	}
+}
+
+func TestQuantizedMatchesFP32(t *testing.T) {
+	modelPath := filepath.Join(modelDir(), "model.onnx")
+	quantPath, _, err := findQuantizedModel(modelPath)
+	if err != nil {
+		t.Skipf("%v (run make model/model_quantized.onnx)", err)
+	}
+
+	text := "This is an apple"
+
+	fp32 := loadTestModel(t)
+	want, err := fp32.Embed(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp32.Close()
+
+	quant := loadTestModel(t, WithQuantized(true))
+	if info := quant.BuildInfo(); info.ModelPath != quantPath {
+		t.Errorf("loaded %s, want %s", info.ModelPath, quantPath)
+	}
+	got, err := quant.Embed(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sim := CosineSimilarity(want, got); sim < 0.98 {
+		t.Errorf("quantized embedding similarity %v, want > 0.98", sim)
+	}
+}
+
+func TestThreadOptions(t *testing.T) {
+	m := loadTestModel(t, WithIntraOpThreads(2), WithInterOpThreads(1))
+
+	embedding, err := m.Embed("This is an apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embedding) == 0 {
+		t.Error("empty embedding")
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	m := loadTestModel(t, WithDeterministic(true), WithIntraOpThreads(4))
+
+	first, err := m.Embed("This is an apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		again, err := m.Embed("This is an apple")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(first, again) {
+			t.Fatalf("run %d differs from the first", i+2)
+		}
+	}
+}
+
+func TestInputTypes(t *testing.T) {
+	m := loadTestModel(t)
+
+	types := m.InputTypes()
+	// jina-embeddings-v2 declares int64 input_ids and attention_mask (and,
+	// depending on the export, token_type_ids).
+	for _, name := range []string{"input_ids", "attention_mask"} {
+		if types[name] != "int64" {
+			t.Errorf("input %s has type %q, want int64", name, types[name])
+		}
+	}
+	for name := range types {
+		if !slices.Contains(m.inputNames, name) {
+			t.Errorf("InputTypes reports %s, which is not a model input", name)
+		}
+	}
+}
+
+// benchmarkCorpus builds n short sentences of varied, deterministic length.
+func benchmarkCorpus(n int) []string {
+	subjects := []string{"The apple", "A red bicycle", "Our quarterly report", "The small dog", "This sentence"}
+	endings := []string{
+		"is on the table.",
+		"was left outside in the rain overnight.",
+		"needs to be reviewed before Friday, ideally by someone on the finance team.",
+		"runs across the park every morning while the sun is still low.",
+	}
+
+	corpus := make([]string, n)
+	for i := range corpus {
+		corpus[i] = fmt.Sprintf("%s %s", subjects[i%len(subjects)], endings[i%len(endings)])
+	}
+	return corpus
+}
+
+// BenchmarkEmbedBatchSize embeds the same 128-text corpus through EmbedBatch
+// in chunks of 1, 8, 32 and 64, reporting throughput (texts/s) and the
+// latency of one EmbedBatch call (ms/batch) for each size. Point
+// EMBEDDING_MODEL_DIR at a smaller model for quicker, steadier runs:
+//
+//	go test -run '^$' -bench EmbedBatchSize ./pkg/embedding
+func BenchmarkEmbedBatchSize(b *testing.B) {
+	m := loadTestModel(b)
+	corpus := benchmarkCorpus(128)
+
+	for _, size := range []int{1, 8, 32, 64} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			var batches int
+			var elapsed time.Duration
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for start := 0; start < len(corpus); start += size {
+					end := min(start+size, len(corpus))
+					t := time.Now()
+					if _, err := m.EmbedBatch(corpus[start:end]); err != nil {
+						b.Fatal(err)
+					}
+					elapsed += time.Since(t)
+					batches++
+				}
+			}
+
+			b.ReportMetric(float64(b.N*len(corpus))/elapsed.Seconds(), "texts/s")
+			b.ReportMetric(elapsed.Seconds()*1000/float64(batches), "ms/batch")
+		})
+	}
+}
+
+func TestModelInfoReal(t *testing.T) {
+	m := loadTestModel(t)
+
+	inputs, outputs, err := m.ModelInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		if len(input.Shape) != 2 {
+			t.Errorf("input %s has shape %v, want [batch, sequence]", input.Name, input.Shape)
+		}
+	}
+	if err := checkOutputName(m.outputName, outputs); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithEmbedDimReal(t *testing.T) {
+	m := loadTestModel(t, WithEmbedDim(768))
+	if _, err := m.Embed("This is an apple"); err != nil {
+		t.Fatalf("Embed with the model's own dimension: %v", err)
+	}
+	tok := m.tokenizer
+	// onnxruntime allows one environment per process.
+	m.Close()
+
+	_, err := NewModel(filepath.Join(modelDir(), "model.onnx"), tok, WithEmbedDim(384))
+	var mismatch *ErrDimMismatch
+	if !errors.As(err, &mismatch) || mismatch.Expected != 384 || mismatch.Got != 768 {
+		t.Errorf("NewModel with WithEmbedDim(384) error = %v, want ErrDimMismatch{384, 768}", err)
+	}
+}
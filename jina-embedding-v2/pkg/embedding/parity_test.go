@@ -0,0 +1,61 @@
+package embedding
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/pyclient"
+)
+
+// parityTexts cover plain prose, casing, punctuation, numbers and
+// non-ASCII text, where a tokenizer that differs from the HuggingFace one
+// (special tokens, greedy instead of Unigram segmentation) shows up first.
+var parityTexts = []string{
+	"This is an apple",
+	"The quick brown fox jumps over the lazy dog.",
+	"ONNX Runtime, PyTorch & TensorFlow: 3 frameworks in 2024!",
+	"unbelievably overcomplicated tokenization",
+	"Café naïve résumé — déjà vu",
+}
+
+// TestGoMatchesPython embeds the same texts through the Go path and the
+// Python reference in py/main.py (transformers tokenizer, onnxruntime,
+// numpy mean pooling) and requires cosine similarity of at least 0.999.
+// It starts the Python server with uv, so it only runs with
+// PYTHON_PARITY=1:
+//
+//	PYTHON_PARITY=1 go test -run GoMatchesPython ./pkg/embedding
+func TestGoMatchesPython(t *testing.T) {
+	if os.Getenv("PYTHON_PARITY") == "" {
+		t.Skip("set PYTHON_PARITY=1 to compare against the Python reference")
+	}
+	m := loadTestModel(t)
+
+	pyDir, err := filepath.Abs(filepath.Join("..", "..", "py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := pyclient.NewPyClientWithAutostart(pyDir)
+	if err != nil {
+		t.Fatalf("failed to start the Python server: %v", err)
+	}
+	defer client.Close()
+
+	for _, text := range parityTexts {
+		goEmbedding, err := m.Embed(text)
+		if err != nil {
+			t.Fatalf("Go Embed(%q): %v", text, err)
+		}
+		pyEmbedding, err := client.Embed(text)
+		if err != nil {
+			t.Fatalf("Python Embed(%q): %v", text, err)
+		}
+		if len(goEmbedding) != len(pyEmbedding) {
+			t.Fatalf("%q: Go embedding has %d dimensions, Python %d", text, len(goEmbedding), len(pyEmbedding))
+		}
+		if sim := CosineSimilarity(goEmbedding, pyEmbedding); sim < 0.999 {
+			t.Errorf("%q: Go and Python embeddings have similarity %v, want >= 0.999", text, sim)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package embedding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/pooling"
+)
+
+// Pooling selects how per-token hidden states are combined into one
+// embedding.
+type Pooling int
+
+const (
+	// MeanPooling averages the hidden states of all attended tokens. It is
+	// what jina-embeddings-v2 was trained with and the default.
+	MeanPooling Pooling = iota
+	// CLSPooling uses the hidden state of the first token (<s>/[CLS]).
+	CLSPooling
+	// WeightedMeanPooling is mean pooling with each token's hidden state
+	// scaled by a per-token-ID weight; see WithWeightedMean.
+	WeightedMeanPooling
+)
+
+func (p Pooling) String() string {
+	switch p {
+	case MeanPooling:
+		return "mean"
+	case CLSPooling:
+		return "cls"
+	case WeightedMeanPooling:
+		return "weighted-mean"
+	default:
+		return fmt.Sprintf("Pooling(%d)", int(p))
+	}
+}
+
+// WithPooling selects the pooling strategy. The default is MeanPooling.
+func WithPooling(p Pooling) Option {
+	return func(m *Model) {
+		m.pooling = p
+	}
+}
+
+// WithWeightedMean selects WeightedMeanPooling: the hidden state of a token
+// with ID id contributes weights[id] times as much as in mean pooling, and
+// the sum is divided by the total weight of the attended tokens. Tokens not
+// in weights have weight 1, so SIF-style inverse-frequency weights can
+// down-weight stopwords and leave everything else alone. weights is copied.
+func WithWeightedMean(weights map[int64]float32) Option {
+	copied := make(map[int64]float32, len(weights))
+	for id, w := range weights {
+		copied[id] = w
+	}
+	return func(m *Model) {
+		m.pooling = WeightedMeanPooling
+		m.tokenWeights = copied
+	}
+}
+
+// WithPrePooled is for models that pool inside the graph and output one
+// [batch, embedDim] embedding per text, such as a "sentence_embedding"
+// output, named with WithOutputName. Embed then only L2-normalizes that
+// output; the pooling strategy is ignored, and EmbedTokens fails since
+// there are no per-token states to return.
+func WithPrePooled(prePooled bool) Option {
+	return func(m *Model) {
+		m.prePooled = prePooled
+	}
+}
+
+// ErrEmptyInput is returned for an empty text when the Model was created
+// with WithEmptyInput(EmptyInputError). It is also returned, whatever the
+// policy, when the tokenizer turns a text into no tokens at all, since there
+// is then nothing to run the model on.
+var ErrEmptyInput = errors.New("empty input")
+
+// EmptyInput selects what embedding an empty text gets. A text of only
+// whitespace counts as empty.
+//
+// The tokenizer still wraps "" in its special tokens, so the model sees a
+// sequence like <s></s>: mean pooling averages those two hidden states and
+// CLS pooling returns the <s> state. Either way the result is a normalized
+// vector that says nothing about the text but is not distinguishable from a
+// real embedding. An input with no attended tokens at all, as a tokenizer
+// may return for "", pools to zeros and is returned as a zero vector rather
+// than NaN.
+type EmptyInput int
+
+const (
+	// EmptyInputEmbed embeds the special tokens as described above. It is
+	// the default.
+	EmptyInputEmbed EmptyInput = iota
+	// EmptyInputZero returns an all-zero vector of the model's dimension,
+	// whatever the pooling strategy.
+	EmptyInputZero
+	// EmptyInputError fails with ErrEmptyInput.
+	EmptyInputError
+)
+
+// WithEmptyInput selects how Embed, EmbedQuery, EmbedPassage and EmbedBatch
+// treat an empty text. Query and passage prefixes are not counted: EmbedQuery("")
+// is empty input even with WithQueryPrefix.
+func WithEmptyInput(e EmptyInput) Option {
+	return func(m *Model) {
+		m.emptyInput = e
+	}
+}
+
+// isEmptyText reports whether text is empty for the WithEmptyInput policy.
+func isEmptyText(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
+
+// pool combines the [batchSize, seqLen, embedDim] hidden states into one
+// [batchSize, embedDim] embedding per row using the configured strategy.
+// With WithPrePooled the output already is one row per text and is
+// returned as is.
+func (m *Model) pool(modelOutput []float32, inputIds, attentionMask []int64, batchSize, seqLen, embedDim int) []float32 {
+	if m.prePooled {
+		return modelOutput
+	}
+	switch m.pooling {
+	case CLSPooling:
+		return pooling.CLS(modelOutput, batchSize, seqLen, embedDim)
+	case WeightedMeanPooling:
+		return pooling.WeightedMean(modelOutput, inputIds, attentionMask, m.tokenWeights, batchSize, seqLen, embedDim)
+	}
+	return pooling.Mean(modelOutput, attentionMask, batchSize, seqLen, embedDim)
+}
@@ -0,0 +1,203 @@
+package embedding
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// positionSession returns hidden states [s+1, 1] for the token at position
+// s, so mean and CLS pooling give different results.
+type positionSession struct{}
+
+func (positionSession) run(in batchInput) ([]float32, int, error) {
+	const embedDim = 2
+	out := make([]float32, in.batchSize*in.seqLen*embedDim)
+	for b := 0; b < in.batchSize; b++ {
+		for s := 0; s < in.seqLen; s++ {
+			out[(b*in.seqLen+s)*embedDim] = float32(s + 1)
+			out[(b*in.seqLen+s)*embedDim+1] = 1
+		}
+	}
+	return out, embedDim, nil
+}
+
+func (positionSession) destroy() {}
+
+func TestEmptyInput(t *testing.T) {
+	// stubTokenizer encodes every text, including "", as <s></s>.
+	tests := []struct {
+		name       string
+		pooling    Pooling
+		emptyInput EmptyInput
+		want       []float32
+		wantErr    error
+	}{
+		{"mean embeds special tokens", MeanPooling, EmptyInputEmbed, unit(1.5, 1), nil},
+		{"cls embeds bos", CLSPooling, EmptyInputEmbed, unit(1, 1), nil},
+		{"mean zero", MeanPooling, EmptyInputZero, []float32{0, 0}, nil},
+		{"cls zero", CLSPooling, EmptyInputZero, []float32{0, 0}, nil},
+		{"mean error", MeanPooling, EmptyInputError, nil, ErrEmptyInput},
+		{"cls error", CLSPooling, EmptyInputError, nil, ErrEmptyInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Model{session: positionSession{}, tokenizer: stubTokenizer{}, queryPrefix: "query: "}
+			WithPooling(tt.pooling)(m)
+			WithEmptyInput(tt.emptyInput)(m)
+
+			for _, text := range []string{"", "   ", " \t\n"} {
+				for _, embed := range []func(string) ([]float32, error){m.Embed, m.EmbedQuery, single(m.EmbedBatch)} {
+					got, err := embed(text)
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("%q: error = %v, want %v", text, err, tt.wantErr)
+					}
+					if tt.wantErr != nil {
+						continue
+					}
+					if len(got) != len(tt.want) {
+						t.Fatalf("%q: got %v, want %v", text, got, tt.want)
+					}
+					for i := range got {
+						if math.IsNaN(float64(got[i])) || math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+							t.Errorf("%q: got %v, want %v", text, got, tt.want)
+							break
+						}
+					}
+				}
+			}
+
+			// A non-empty text is unaffected by the policy.
+			if got, err := m.Embed("apple"); err != nil || got[0] == 0 {
+				t.Errorf("Embed(\"apple\") = %v, %v", got, err)
+			}
+		})
+	}
+}
+
+// unit returns (x, y) scaled to unit length.
+func unit(x, y float32) []float32 {
+	n := float32(math.Hypot(float64(x), float64(y)))
+	return []float32{x / n, y / n}
+}
+
+// single adapts a batch embedding function to one text.
+func single(embedBatch func([]string) ([][]float32, error)) func(string) ([]float32, error) {
+	return func(text string) ([]float32, error) {
+		out, err := embedBatch([]string{text})
+		if err != nil {
+			return nil, err
+		}
+		return out[0], nil
+	}
+}
+
+func TestWithWeightedMean(t *testing.T) {
+	weights := map[int64]float32{0: 0}
+	// stubTokenizer encodes every text as ids [0 2]; dropping id 0 leaves
+	// the hidden state of position 1, [2, 1].
+	m := &Model{session: positionSession{}, tokenizer: stubTokenizer{}}
+	WithWeightedMean(weights)(m)
+	weights[0] = 1
+
+	got, err := m.Embed("apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := unit(2, 1)
+	if math.Abs(float64(got[0]-want[0])) > 1e-6 || math.Abs(float64(got[1]-want[1])) > 1e-6 {
+		t.Errorf("Embed = %v, want %v", got, want)
+	}
+	if m.pooling.String() != "weighted-mean" {
+		t.Errorf("pooling = %v, want weighted-mean", m.pooling)
+	}
+}
+
+// maskedTokenizer encodes every text as two tokens, both masked out.
+type maskedTokenizer struct{}
+
+func (maskedTokenizer) Encode(text string) ([]int64, []int64) {
+	return []int64{0, 2}, []int64{0, 0}
+}
+
+func TestFullyMaskedInputIsZero(t *testing.T) {
+	m := &Model{session: positionSession{}, tokenizer: maskedTokenizer{}}
+
+	for _, embed := range []func(string) ([]float32, error){m.Embed, single(m.EmbedBatch)} {
+		got, err := embed("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range got {
+			if v != 0 {
+				t.Fatalf("got %v, want a zero vector", got)
+			}
+		}
+	}
+}
+
+// noTokenizer encodes every text as no tokens at all.
+type noTokenizer struct{}
+
+func (noTokenizer) Encode(text string) ([]int64, []int64) {
+	return nil, nil
+}
+
+func TestNoTokensIsEmptyInput(t *testing.T) {
+	m := &Model{session: positionSession{}, tokenizer: noTokenizer{}}
+
+	for _, embed := range []func(string) ([]float32, error){m.Embed, single(m.EmbedBatch)} {
+		if _, err := embed(""); !errors.Is(err, ErrEmptyInput) {
+			t.Errorf("error = %v, want ErrEmptyInput", err)
+		}
+	}
+	if _, _, err := m.EmbedTokens(""); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("EmbedTokens error = %v, want ErrEmptyInput", err)
+	}
+}
+
+// pooledSession is a pre-pooled model: row b of the batch gets the
+// embedding [seqLen, 3+b], whatever the tokens.
+type pooledSession struct{}
+
+func (pooledSession) run(in batchInput) ([]float32, int, error) {
+	out := make([]float32, 0, in.batchSize*2)
+	for b := 0; b < in.batchSize; b++ {
+		out = append(out, float32(in.seqLen), float32(3+b))
+	}
+	return out, 2, nil
+}
+
+func (pooledSession) destroy() {}
+
+func TestPrePooled(t *testing.T) {
+	for _, p := range []Pooling{MeanPooling, CLSPooling} {
+		m := &Model{session: pooledSession{}, tokenizer: stubTokenizer{}, prePooled: true, pooling: p}
+
+		got, err := m.Embed("apple")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// stubTokenizer gives two tokens: [2, 3] normalized, not pooled.
+		want := []float32{0.5547002, 0.8320503}
+		for i := range want {
+			if math.Abs(float64(got[i]-want[i])) > 1e-6 {
+				t.Fatalf("%v pooling: Embed = %v, want %v", p, got, want)
+			}
+		}
+
+		batch, err := m.EmbedBatch([]string{"apple", "pear"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batch) != 2 || len(batch[1]) != 2 {
+			t.Fatalf("EmbedBatch = %v, want two 2-dimensional embeddings", batch)
+		}
+	}
+
+	m := &Model{session: pooledSession{}, tokenizer: stubTokenizer{}, prePooled: true}
+	if _, _, err := m.EmbedTokens("apple"); err == nil {
+		t.Error("EmbedTokens on a pre-pooled model should fail")
+	}
+}
@@ -0,0 +1,76 @@
+package embedding
+
+import (
+	"slices"
+	"sync"
+)
+
+// Registry holds named Models so that one process can serve several of
+// them, routing each request by the model it names; server.RegistryHandler
+// serves one over HTTP. The Registry owns the Models registered with it:
+// CloseAll closes them all, and a Model replaced by Register is closed, so
+// callers still holding it get ErrClosed. The zero value is an
+// empty Registry ready to use, and a Registry is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	models map[string]*Model
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds m under name. A Model already registered under name is
+// replaced and closed, unless it is m itself.
+func (r *Registry) Register(name string, m *Model) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.models == nil {
+		r.models = make(map[string]*Model)
+	}
+	old := r.models[name]
+	r.models[name] = m
+	if old != nil && old != m {
+		old.Close()
+	}
+}
+
+// Get returns the Model registered under name.
+func (r *Registry) Get(name string) (*Model, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.models[name]
+	return m, ok
+}
+
+// Names returns the registered names in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.models))
+	for name := range r.models {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// CloseAll closes every registered Model and empties the Registry. A Model
+// registered under several names is closed once.
+func (r *Registry) CloseAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	closed := make(map[*Model]bool, len(r.models))
+	for _, m := range r.models {
+		if !closed[m] {
+			m.Close()
+			closed[m] = true
+		}
+	}
+	r.models = nil
+}
@@ -0,0 +1,93 @@
+package embedding
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	newModel := func() *Model {
+		m, err := NewFakeModel(FakeTokenizer{}, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	var r Registry
+	if _, ok := r.Get("small"); ok {
+		t.Fatal("Get on an empty Registry found a model")
+	}
+
+	small, base := newModel(), newModel()
+	r.Register("small", small)
+	r.Register("base", base)
+	r.Register("default", base)
+
+	if m, ok := r.Get("small"); !ok || m != small {
+		t.Errorf("Get(small) = %p, %v, want %p, true", m, ok, small)
+	}
+	if m, ok := r.Get("default"); !ok || m != base {
+		t.Errorf("Get(default) = %p, %v, want %p, true", m, ok, base)
+	}
+	if _, ok := r.Get("large"); ok {
+		t.Error("Get(large) found a model that was never registered")
+	}
+	if names := r.Names(); !reflect.DeepEqual(names, []string{"base", "default", "small"}) {
+		t.Errorf("Names = %v", names)
+	}
+	if _, err := small.Embed("hello world"); err != nil {
+		t.Fatalf("Embed on a registered model: %v", err)
+	}
+
+	// Replacing a model closes the old one.
+	replacement := newModel()
+	r.Register("small", replacement)
+	if small.session != nil {
+		t.Error("replaced model was not closed")
+	}
+	if m, _ := r.Get("small"); m != replacement {
+		t.Errorf("Get(small) after Register = %p, want %p", m, replacement)
+	}
+
+	r.CloseAll()
+	if replacement.session != nil || base.session != nil {
+		t.Error("CloseAll left a model open")
+	}
+	if names := r.Names(); len(names) != 0 {
+		t.Errorf("Names after CloseAll = %v, want none", names)
+	}
+	if _, ok := r.Get("base"); ok {
+		t.Error("Get after CloseAll found a model")
+	}
+}
+
+func TestRegistryReplacedModelIsClosed(t *testing.T) {
+	var r Registry
+	old, err := NewFakeModel(FakeTokenizer{}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Register("a", old)
+
+	held, _ := r.Get("a")
+	replacement, err := NewFakeModel(FakeTokenizer{}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Register("a", replacement)
+
+	if _, err := held.Embed("hello world"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Embed on a replaced model: err = %v, want ErrClosed", err)
+	}
+	if _, err := held.EmbedBatch([]string{"hello", "world"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("EmbedBatch on a replaced model: err = %v, want ErrClosed", err)
+	}
+	if _, _, err := held.EmbedTokens("hello world"); !errors.Is(err, ErrClosed) {
+		t.Errorf("EmbedTokens on a replaced model: err = %v, want ErrClosed", err)
+	}
+	if _, err := replacement.Embed("hello world"); err != nil {
+		t.Errorf("Embed on the replacement: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package embedding
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// LibraryPathEnv names the environment variable that overrides the location
+// of the onnxruntime shared library: libonnxruntime.so, libonnxruntime.dylib
+// or onnxruntime.dll.
+const LibraryPathEnv = "ONNXRUNTIME_LIB"
+
+// defaultLibraryPaths are the locations the README install steps use.
+var defaultLibraryPaths = map[string]string{
+	"linux":  "/usr/local/lib/onnxruntime/lib/libonnxruntime.so",
+	"darwin": "/usr/local/lib/onnxruntime/libonnxruntime.dylib",
+	// The lib directory of the Windows release archive, extracted into
+	// Program Files.
+	"windows": `C:\Program Files\onnxruntime\lib\onnxruntime.dll`,
+}
+
+// sharedLibraryPath returns $ONNXRUNTIME_LIB if set, otherwise the default
+// install location for this operating system.
+func sharedLibraryPath() (string, error) {
+	if path := os.Getenv(LibraryPathEnv); path != "" {
+		return path, nil
+	}
+	path, ok := defaultLibraryPaths[runtime.GOOS]
+	if !ok {
+		return "", fmt.Errorf("unsupported operating system: %s; set %s to the onnxruntime library", runtime.GOOS, LibraryPathEnv)
+	}
+	return path, nil
+}
+
+// RequiredRuntimeVersion is the oldest onnxruntime release whose C API
+// matches the one onnxruntime_go was built against.
+const RequiredRuntimeVersion = "1.22"
+
+// CheckRuntime loads the onnxruntime shared library, if it isn't loaded yet,
+// and returns its version. When the library can't be used the error says
+// which version was found and which one is expected. NewModel calls it, so
+// calling it first is only needed to report problems early.
+func CheckRuntime() (version string, err error) {
+	if ort.IsInitialized() {
+		return ort.GetVersion(), nil
+	}
+	if err := initializeRuntime(); err != nil {
+		return ort.GetVersion(), err
+	}
+	return ort.GetVersion(), nil
+}
+
+// runtimeUsers counts the open Models with an onnxruntime session. The
+// environment is process-wide, so it is only destroyed when the last of them
+// is closed; otherwise closing one Model, e.g. from Registry.CloseAll, would
+// break the others.
+var (
+	runtimeMu    sync.Mutex
+	runtimeUsers int
+)
+
+// retainRuntime records a Model with an onnxruntime session.
+func retainRuntime() {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	runtimeUsers++
+}
+
+// releaseRuntime undoes retainRuntime, destroying the environment once no
+// Model uses it.
+func releaseRuntime() {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	runtimeUsers--
+	if runtimeUsers == 0 {
+		ort.DestroyEnvironment()
+	}
+}
+
+// initializeRuntime loads the onnxruntime shared library, turning the common
+// failures into errors that say what to fix.
+func initializeRuntime() error {
+	path, err := sharedLibraryPath()
+	if err != nil {
+		return err
+	}
+	if err := checkSharedLibrary(path); err != nil {
+		return err
+	}
+
+	ort.SetSharedLibraryPath(path)
+	if err := ort.InitializeEnvironment(); err != nil {
+		// The version is known once the library is loaded, even if its
+		// API turned out to be incompatible.
+		if version := ort.GetVersion(); version != "" && !versionAtLeast(version, RequiredRuntimeVersion) {
+			return fmt.Errorf("onnxruntime %s at %s is too old: onnxruntime %s or newer is required; "+
+				"install a matching release or point %s at one: %w", version, path, RequiredRuntimeVersion, LibraryPathEnv, err)
+		}
+		return fmt.Errorf("failed to load onnxruntime from %s: %w (onnxruntime_go needs onnxruntime %s or newer; "+
+			"install a matching release or point %s at one)", path, err, RequiredRuntimeVersion, LibraryPathEnv)
+	}
+	return nil
+}
+
+// versionAtLeast reports whether the dotted version is at least min,
+// comparing numeric components. Anything after a non-digit in a component,
+// like "-rc1", is ignored.
+func versionAtLeast(version, min string) bool {
+	v, m := strings.Split(version, "."), strings.Split(min, ".")
+	for i := range m {
+		var a int
+		if i < len(v) {
+			a = leadingInt(v[i])
+		}
+		if b := leadingInt(m[i]); a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+func leadingInt(s string) int {
+	n := 0
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			break
+		}
+		n = n*10 + int(r-'0')
+	}
+	return n
+}
+
+// checkSharedLibrary reports a missing library, or one built for a different
+// CPU architecture, before dlopen turns either into an opaque error.
+func checkSharedLibrary(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("onnxruntime library not found at %s: install onnxruntime there (see README) or set %s to its location",
+				path, LibraryPathEnv)
+		}
+		return fmt.Errorf("cannot access onnxruntime library at %s: %w", path, err)
+	}
+
+	if arch, ok := libraryArch(path); ok && arch != runtime.GOARCH {
+		return fmt.Errorf("onnxruntime library at %s is built for %s, but this program is %s: install the %s build or set %s",
+			path, arch, runtime.GOARCH, runtime.GOARCH, LibraryPathEnv)
+	}
+	return nil
+}
+
+// libraryArch returns the GOARCH name of the CPU a shared library targets.
+// It reports false when the file isn't a single-architecture ELF, Mach-O or
+// PE binary, or the architecture is one Go has no name for here.
+func libraryArch(path string) (string, bool) {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		switch f.Machine {
+		case elf.EM_X86_64:
+			return "amd64", true
+		case elf.EM_AARCH64:
+			return "arm64", true
+		case elf.EM_386:
+			return "386", true
+		case elf.EM_ARM:
+			return "arm", true
+		}
+		return "", false
+	}
+
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		switch f.Cpu {
+		case macho.CpuAmd64:
+			return "amd64", true
+		case macho.CpuArm64:
+			return "arm64", true
+		}
+		return "", false
+	}
+
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		switch f.Machine {
+		case pe.IMAGE_FILE_MACHINE_AMD64:
+			return "amd64", true
+		case pe.IMAGE_FILE_MACHINE_ARM64:
+			return "arm64", true
+		case pe.IMAGE_FILE_MACHINE_I386:
+			return "386", true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,101 @@
+package embedding
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckSharedLibraryMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "libonnxruntime.so")
+
+	err := checkSharedLibrary(path)
+	if err == nil {
+		t.Fatal("expected an error for a missing library")
+	}
+	for _, want := range []string{"not found", path, LibraryPathEnv} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestCheckSharedLibraryMatchingArch(t *testing.T) {
+	// The test binary itself is built for this platform.
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	if err := checkSharedLibrary(exe); err != nil {
+		t.Errorf("checkSharedLibrary(%s): %v", exe, err)
+	}
+}
+
+// writePE writes a minimal PE file: a DOS header pointing at a COFF header
+// for machine, with no sections, padded so debug/pe's fixed-size reads
+// succeed.
+func writePE(t *testing.T, machine uint16) string {
+	t.Helper()
+	const peOffset = 0x40
+	data := make([]byte, 256)
+	copy(data, "MZ")
+	binary.LittleEndian.PutUint32(data[0x3c:], peOffset)
+	copy(data[peOffset:], "PE\x00\x00")
+	binary.LittleEndian.PutUint16(data[peOffset+4:], machine)
+
+	path := filepath.Join(t.TempDir(), "onnxruntime.dll")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLibraryArchPE(t *testing.T) {
+	for machine, want := range map[uint16]string{
+		pe.IMAGE_FILE_MACHINE_AMD64: "amd64",
+		pe.IMAGE_FILE_MACHINE_ARM64: "arm64",
+		pe.IMAGE_FILE_MACHINE_I386:  "386",
+	} {
+		arch, ok := libraryArch(writePE(t, machine))
+		if !ok || arch != want {
+			t.Errorf("libraryArch(PE %#x) = %q, %v; want %q", machine, arch, ok, want)
+		}
+	}
+}
+
+func TestSharedLibraryPathEnv(t *testing.T) {
+	t.Setenv(LibraryPathEnv, "/opt/ort/libonnxruntime.so")
+
+	path, err := sharedLibraryPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/opt/ort/libonnxruntime.so" {
+		t.Errorf("sharedLibraryPath() = %q, want the %s override", path, LibraryPathEnv)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version, min string
+		want         bool
+	}{
+		{"1.22.0", "1.22", true},
+		{"1.22", "1.22", true},
+		{"1.23.1", "1.22", true},
+		{"2.0.0", "1.22", true},
+		{"1.21.1", "1.22", false},
+		{"1.9.0", "1.22", false},
+		{"1.22.0-rc1", "1.22", true},
+		{"", "1.22", false},
+	}
+
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, tt.min); got != tt.want {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", tt.version, tt.min, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package embedding
+
+import (
+	"fmt"
+	"slices"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// batchInput is one right-padded [batchSize, seqLen] batch.
+type batchInput struct {
+	inputIds      []int64
+	attentionMask []int64
+	batchSize     int
+	seqLen        int
+	taskID        int64
+}
+
+// session runs the model on a batch and returns the flattened
+// [batchSize, seqLen, embedDim] hidden states, or [batchSize, embedDim]
+// embeddings for a pre-pooled model, along with embedDim. The
+// returned slice may be reused by the next call. Model serialises calls, so
+// implementations need not be safe for concurrent use.
+type session interface {
+	run(in batchInput) ([]float32, int, error)
+	destroy()
+}
+
+// ortSession is the onnxruntime-backed session.
+type ortSession struct {
+	session    *ort.DynamicAdvancedSession
+	inputNames []string
+	// embedDim is the hidden size the model declares, or 0 if it is
+	// dynamic, in which case onnxruntime allocates the output of every run.
+	embedDim int
+	// expectedDim is the size set with WithEmbedDim, or 0.
+	expectedDim int
+	// pooled is set for a model whose output is [batch, embedDim].
+	pooled bool
+
+	// tensors is the most recently used tensor set, so that steady-state
+	// traffic with a stable padded length reuses its buffers instead of
+	// allocating new tensors per call.
+	tensors *tensorSet
+}
+
+type tensorSet struct {
+	batchSize int
+	seqLen    int
+	inputs    []*ort.Tensor[int64]
+	output    *ort.Tensor[float32]
+}
+
+func (ts *tensorSet) destroy() {
+	for _, input := range ts.inputs {
+		_ = input.Destroy()
+	}
+	if ts.output != nil {
+		_ = ts.output.Destroy()
+	}
+}
+
+func (ts *tensorSet) values() []ort.Value {
+	values := make([]ort.Value, len(ts.inputs))
+	for i, input := range ts.inputs {
+		values[i] = input
+	}
+	return values
+}
+
+func (s *ortSession) run(in batchInput) ([]float32, int, error) {
+	tensors, err := s.tensorsFor(in.batchSize, in.seqLen)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, name := range s.inputNames {
+		switch name {
+		case "input_ids":
+			copy(tensors.inputs[i].GetData(), in.inputIds)
+		case "attention_mask":
+			copy(tensors.inputs[i].GetData(), in.attentionMask)
+		case "task_id":
+			tensors.inputs[i].GetData()[0] = in.taskID
+		}
+	}
+
+	if tensors.output == nil {
+		return s.runAllocated(tensors, in)
+	}
+
+	err = s.session.Run(tensors.values(), []ort.Value{tensors.output})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return tensors.output.GetData(), s.embedDim, nil
+}
+
+// runAllocated runs a model without a declared hidden size, letting
+// onnxruntime allocate the output and reading the size from its shape.
+func (s *ortSession) runAllocated(tensors *tensorSet, in batchInput) ([]float32, int, error) {
+	outputs := []ort.Value{nil}
+	if err := s.session.Run(tensors.values(), outputs); err != nil {
+		return nil, 0, err
+	}
+	defer outputs[0].Destroy()
+
+	shape := outputs[0].GetShape()
+	want := ort.NewShape(int64(in.batchSize), int64(in.seqLen), -1)
+	if s.pooled {
+		want = ort.NewShape(int64(in.batchSize), -1)
+	}
+	if len(shape) != len(want) || !slices.Equal(shape[:len(shape)-1], want[:len(want)-1]) {
+		return nil, 0, fmt.Errorf("model output has shape %v, want %v, where -1 is the hidden size", shape, want)
+	}
+	embedDim := int(shape[len(shape)-1])
+	if s.expectedDim > 0 && embedDim != s.expectedDim {
+		return nil, 0, &ErrDimMismatch{Expected: s.expectedDim, Got: embedDim}
+	}
+	output, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, 0, fmt.Errorf("model output is %v, want a float32 tensor", outputs[0].GetONNXType())
+	}
+	return slices.Clone(output.GetData()), embedDim, nil
+}
+
+func (s *ortSession) destroy() {
+	if s.tensors != nil {
+		s.tensors.destroy()
+		s.tensors = nil
+	}
+	_ = s.session.Destroy()
+}
+
+// tensorsFor returns tensors shaped for a [batchSize, seqLen] batch, reusing
+// the cached set when the shape is unchanged. Inputs whose contents depend
+// only on the shape (token_type_ids, position_ids) are filled once here.
+func (s *ortSession) tensorsFor(batchSize, seqLen int) (*tensorSet, error) {
+	if ts := s.tensors; ts != nil && ts.batchSize == batchSize && ts.seqLen == seqLen {
+		return ts, nil
+	}
+
+	if s.tensors != nil {
+		s.tensors.destroy()
+		s.tensors = nil
+	}
+
+	ts := &tensorSet{batchSize: batchSize, seqLen: seqLen}
+
+	inputShape := ort.NewShape(int64(batchSize), int64(seqLen))
+	for _, name := range s.inputNames {
+		var tensor *ort.Tensor[int64]
+		var err error
+		switch name {
+		case "task_id":
+			tensor, err = ort.NewEmptyTensor[int64](ort.NewShape(1))
+		case "position_ids":
+			tensor, err = ort.NewTensor(inputShape, positionIds(batchSize, seqLen))
+		default:
+			tensor, err = ort.NewEmptyTensor[int64](inputShape)
+		}
+		if err != nil {
+			ts.destroy()
+			return nil, err
+		}
+		ts.inputs = append(ts.inputs, tensor)
+	}
+
+	if s.embedDim > 0 {
+		outputShape := ort.NewShape(int64(batchSize), int64(seqLen), int64(s.embedDim))
+		if s.pooled {
+			outputShape = ort.NewShape(int64(batchSize), int64(s.embedDim))
+		}
+		output, err := ort.NewEmptyTensor[float32](outputShape)
+		if err != nil {
+			ts.destroy()
+			return nil, err
+		}
+		ts.output = output
+	}
+
+	s.tensors = ts
+	return ts, nil
+}
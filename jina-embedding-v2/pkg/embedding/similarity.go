@@ -0,0 +1,68 @@
+package embedding
+
+import (
+	"fmt"
+	"math"
+)
+
+// CosineSimilarity returns the cosine of the angle between a and b, in
+// [-1, 1]. Vectors of different lengths, or with a zero norm, have a
+// similarity of 0.
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// CosineDistance returns 1 - CosineSimilarity(a, b), in [0, 2]. This is the
+// value Weaviate reports as _additional { distance } for classes using the
+// "cosine" distance metric, so it can be compared directly when debugging.
+func CosineDistance(a, b []float32) float32 {
+	return 1 - CosineSimilarity(a, b)
+}
+
+// PairedSimilarities returns the cosine similarity of each pair a[i], b[i].
+func PairedSimilarities(a, b [][]float32) ([]float32, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("paired similarities: %d vectors vs %d", len(a), len(b))
+	}
+
+	sims := make([]float32, len(a))
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return nil, fmt.Errorf("paired similarities: pair %d has dimensions %d and %d", i, len(a[i]), len(b[i]))
+		}
+		sims[i] = CosineSimilarity(a[i], b[i])
+	}
+	return sims, nil
+}
+
+// PairedTextSimilarities embeds both sides with a single EmbedBatch call and
+// returns the cosine similarity of each pair aTexts[i], bTexts[i].
+func (m *Model) PairedTextSimilarities(aTexts, bTexts []string) ([]float32, error) {
+	if len(aTexts) != len(bTexts) {
+		return nil, fmt.Errorf("paired similarities: %d texts vs %d", len(aTexts), len(bTexts))
+	}
+
+	texts := make([]string, 0, len(aTexts)+len(bTexts))
+	texts = append(texts, aTexts...)
+	texts = append(texts, bTexts...)
+
+	embeddings, err := m.EmbedBatch(texts)
+	if err != nil {
+		return nil, err
+	}
+	return PairedSimilarities(embeddings[:len(aTexts)], embeddings[len(aTexts):])
+}
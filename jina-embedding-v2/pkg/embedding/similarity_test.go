@@ -0,0 +1,67 @@
+package embedding
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float32
+	}{
+		{"identical", []float32{1, 2, 3}, []float32{1, 2, 3}, 0},
+		{"orthogonal", []float32{1, 0}, []float32{0, 1}, 1},
+		{"opposite", []float32{1, 0}, []float32{-1, 0}, 2},
+		{"45 degrees", []float32{1, 0}, []float32{1, 1}, float32(1 - 1/math.Sqrt2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CosineDistance(tt.a, tt.b)
+			if math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("CosineDistance = %v, want %v", got, tt.want)
+			}
+
+			sim := CosineSimilarity(tt.a, tt.b)
+			if math.Abs(float64(got-(1-sim))) > 1e-6 {
+				t.Errorf("CosineDistance = %v, 1 - CosineSimilarity = %v", got, 1-sim)
+			}
+		})
+	}
+}
+
+func TestCosineSimilarityZeroVector(t *testing.T) {
+	if got := CosineSimilarity([]float32{0, 0}, []float32{1, 0}); got != 0 {
+		t.Errorf("CosineSimilarity with zero vector = %v, want 0", got)
+	}
+}
+
+func TestPairedSimilarities(t *testing.T) {
+	a := [][]float32{{1, 0}, {1, 0}, {1, 0}, {3, 4}}
+	b := [][]float32{{1, 0}, {0, 1}, {-1, 0}, {4, 3}}
+	want := []float32{1, 0, -1, 24.0 / 25}
+
+	got, err := PairedSimilarities(a, b)
+	if err != nil {
+		t.Fatalf("PairedSimilarities: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d similarities, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-6 {
+			t.Errorf("pair %d: similarity %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPairedSimilaritiesMismatch(t *testing.T) {
+	if _, err := PairedSimilarities([][]float32{{1}}, nil); err == nil {
+		t.Error("expected an error for different numbers of vectors")
+	}
+	if _, err := PairedSimilarities([][]float32{{1, 0}}, [][]float32{{1}}); err == nil {
+		t.Error("expected an error for different dimensions")
+	}
+}
@@ -0,0 +1,65 @@
+package embedding
+
+import (
+	"errors"
+	"slices"
+)
+
+// EmbedResult is a single output of EmbedStream. Index is the position of the
+// text in the input stream.
+type EmbedResult struct {
+	Index     int
+	Embedding []float32
+	Err       error
+}
+
+// EmbedStream embeds texts as they arrive, running one batched inference per
+// batchSize inputs (or fewer once texts is closed). Results are emitted in
+// input order and the returned channel is closed after the last one. If a
+// batch fails, every text in it is reported with the error and streaming
+// continues with the next batch. If the WithBatchSoftTimeout deadline cuts a
+// batch short, its finished embeddings are still emitted and only the
+// unprocessed texts carry the *PartialBatchError.
+func (m *Model) EmbedStream(texts <-chan string, batchSize int) <-chan EmbedResult {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	results := make(chan EmbedResult, batchSize)
+
+	go func() {
+		defer close(results)
+
+		next := 0
+		batch := make([]string, 0, batchSize)
+		flush := func() {
+			embeddings, err := m.EmbedBatch(batch)
+			var partial *PartialBatchError
+			errors.As(err, &partial)
+			for i := range batch {
+				result := EmbedResult{Index: next + i, Err: err}
+				if partial != nil && !slices.Contains(partial.Unprocessed, i) {
+					result.Err = nil
+				}
+				if result.Err == nil {
+					result.Embedding = embeddings[i]
+				}
+				results <- result
+			}
+			next += len(batch)
+			batch = batch[:0]
+		}
+
+		for text := range texts {
+			batch = append(batch, text)
+			if len(batch) == batchSize {
+				flush()
+			}
+		}
+		if len(batch) > 0 {
+			flush()
+		}
+	}()
+
+	return results
+}
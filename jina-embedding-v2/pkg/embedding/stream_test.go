@@ -0,0 +1,139 @@
+package embedding
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// failingSession is a fakeSession whose runs numbered in fail, counting
+// from 1, return errRunFailed.
+type failingSession struct {
+	fakeSession
+	runs int
+	fail map[int]bool
+}
+
+var errRunFailed = errors.New("run failed")
+
+func (s *failingSession) run(in batchInput) ([]float32, int, error) {
+	s.runs++
+	if s.fail[s.runs] {
+		return nil, 0, errRunFailed
+	}
+	return s.fakeSession.run(in)
+}
+
+// streamAll sends texts through EmbedStream and collects every result.
+func streamAll(m *Model, texts []string, batchSize int) []EmbedResult {
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for _, text := range texts {
+			in <- text
+		}
+	}()
+
+	var results []EmbedResult
+	for result := range m.EmbedStream(in, batchSize) {
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestEmbedStream(t *testing.T) {
+	m, err := NewFakeModel(FakeTokenizer{}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two words each, so every batch is a single run. Five texts in
+	// batches of two leave a final partial batch of one.
+	texts := []string{"red apple", "green pear", "blue plum", "ripe fig", "sour lime"}
+
+	results := streamAll(m, texts, 2)
+	if len(results) != len(texts) {
+		t.Fatalf("got %d results, want %d", len(results), len(texts))
+	}
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		if result.Err != nil {
+			t.Errorf("result %d: %v", i, result.Err)
+			continue
+		}
+		want, err := m.Embed(texts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result.Embedding, want) {
+			t.Errorf("result %d is not the embedding of %q", i, texts[i])
+		}
+	}
+}
+
+func TestEmbedStreamBatchError(t *testing.T) {
+	m, err := NewFakeModel(FakeTokenizer{}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.session = &failingSession{fakeSession: fakeSession{embedDim: 8}, fail: map[int]bool{2: true}}
+
+	results := streamAll(m, []string{"red apple", "green pear", "blue plum", "ripe fig", "sour lime"}, 2)
+	if len(results) != 5 {
+		t.Fatalf("got %d results, want 5", len(results))
+	}
+	for i, result := range results {
+		failed := i == 2 || i == 3
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		if failed && !errors.Is(result.Err, errRunFailed) {
+			t.Errorf("result %d: err = %v, want the error of its batch", i, result.Err)
+		}
+		if failed && result.Embedding != nil {
+			t.Errorf("result %d: got an embedding from a failed batch", i)
+		}
+		if !failed && (result.Err != nil || len(result.Embedding) != 8) {
+			t.Errorf("result %d: got %v, %v, want an embedding", i, result.Embedding, result.Err)
+		}
+	}
+}
+
+func TestEmbedStreamPartialBatch(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	m, err := NewFakeModel(FakeTokenizer{}, 4, WithMaxTokensPerBatch(4), WithBatchSoftTimeout(45*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each text is 4 tokens, so gets its own run, and the deadline passes
+	// after the second run of every batch.
+	m.session = &tickingSession{clock: clock, tick: 30 * time.Millisecond}
+	m.now = clock.Now
+
+	results := streamAll(m, []string{"red apple", "green pear", "blue plum", "ripe fig"}, 3)
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	var embedded, unprocessed []int
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		var partial *PartialBatchError
+		switch {
+		case result.Err == nil && len(result.Embedding) == 4:
+			embedded = append(embedded, i)
+		case errors.As(result.Err, &partial) && result.Embedding == nil:
+			unprocessed = append(unprocessed, i)
+		default:
+			t.Errorf("result %d: got %v, %v", i, result.Embedding, result.Err)
+		}
+	}
+	// The first batch of three loses one input to the deadline; the final
+	// batch of one finishes in time.
+	if len(embedded) != 3 || len(unprocessed) != 1 || unprocessed[0] > 2 {
+		t.Errorf("embedded %v, unprocessed %v, want one of the first three unprocessed", embedded, unprocessed)
+	}
+}
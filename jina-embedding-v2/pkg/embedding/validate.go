@@ -0,0 +1,147 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Backend is anything that embeds a single text: a *Model, or an adapter
+// around another runtime such as the CoreML or Python services.
+type Backend interface {
+	Embed(text string) ([]float32, error)
+}
+
+// DefaultProbe is the text ValidateAll embeds when ValidateOptions.Probe is
+// empty.
+const DefaultProbe = "This is an apple"
+
+// ValidateOptions configures ValidateAll.
+type ValidateOptions struct {
+	// Probe is the text embedded by every backend. Empty means DefaultProbe.
+	Probe string
+	// Dim is the embedding dimension every backend must return. Zero only
+	// requires the backends to agree with each other.
+	Dim int
+	// MinSimilarity is the lowest cosine similarity allowed between the
+	// probe embeddings of any two backends. Zero disables the check.
+	MinSimilarity float32
+}
+
+// BackendStatus is the outcome of probing one backend.
+type BackendStatus struct {
+	Name    string
+	Dim     int
+	Latency time.Duration
+	// Err is nil when the backend embedded the probe with the expected
+	// dimension.
+	Err error
+}
+
+// Ready reports whether the backend passed.
+func (s BackendStatus) Ready() bool {
+	return s.Err == nil
+}
+
+// BackendSimilarity is the cosine similarity of two backends' probe
+// embeddings.
+type BackendSimilarity struct {
+	A, B       string
+	Similarity float32
+}
+
+// ValidationReport is the result of ValidateAll.
+type ValidationReport struct {
+	// Backends holds one status per backend, sorted by name.
+	Backends []BackendStatus
+	// Similarities holds one entry per pair of ready backends with the
+	// same dimension.
+	Similarities []BackendSimilarity
+}
+
+// ValidateAll embeds a probe text through every backend concurrently, which
+// also warms them up, and checks that each succeeds with the expected
+// dimension and that all of them agree to within opts.MinSimilarity. It is
+// meant as a single startup health gate for services with several backends.
+//
+// The report is always returned. The error joins every failure, each naming
+// its backend, and is nil only if all backends are ready and agree. If ctx is
+// done before a backend answers, that backend fails with ctx's error; its
+// Embed call is left to finish in the background.
+func ValidateAll(ctx context.Context, backends map[string]Backend, opts ValidateOptions) (ValidationReport, error) {
+	probe := opts.Probe
+	if probe == "" {
+		probe = DefaultProbe
+	}
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type result struct {
+		embedding []float32
+		err       error
+		latency   time.Duration
+	}
+	results := make([]chan result, len(names))
+	for i, name := range names {
+		results[i] = make(chan result, 1)
+		go func(backend Backend, out chan<- result) {
+			start := time.Now()
+			embedding, err := backend.Embed(probe)
+			out <- result{embedding, err, time.Since(start)}
+		}(backends[name], results[i])
+	}
+
+	var report ValidationReport
+	var errs []error
+	embeddings := make([][]float32, len(names))
+	for i, name := range names {
+		status := BackendStatus{Name: name}
+		select {
+		case r := <-results[i]:
+			status.Latency = r.latency
+			status.Dim = len(r.embedding)
+			switch {
+			case r.err != nil:
+				status.Err = r.err
+			case status.Dim == 0:
+				status.Err = errors.New("returned an empty embedding")
+			case opts.Dim > 0 && status.Dim != opts.Dim:
+				status.Err = fmt.Errorf("returned %d dimensions, want %d", status.Dim, opts.Dim)
+			default:
+				embeddings[i] = r.embedding
+			}
+		case <-ctx.Done():
+			status.Err = ctx.Err()
+		}
+		if status.Err != nil {
+			errs = append(errs, fmt.Errorf("backend %s: %w", name, status.Err))
+		}
+		report.Backends = append(report.Backends, status)
+	}
+
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			a, b := embeddings[i], embeddings[j]
+			if a == nil || b == nil {
+				continue
+			}
+			if len(a) != len(b) {
+				errs = append(errs, fmt.Errorf("backends %s and %s disagree on dimension: %d vs %d", names[i], names[j], len(a), len(b)))
+				continue
+			}
+			sim := CosineSimilarity(a, b)
+			report.Similarities = append(report.Similarities, BackendSimilarity{A: names[i], B: names[j], Similarity: sim})
+			if opts.MinSimilarity > 0 && sim < opts.MinSimilarity {
+				errs = append(errs, fmt.Errorf("backends %s and %s disagree: similarity %.4f, want at least %.4f", names[i], names[j], sim, opts.MinSimilarity))
+			}
+		}
+	}
+
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedBackend returns embedding, or err if set, after delay.
+type fixedBackend struct {
+	embedding []float32
+	err       error
+	delay     time.Duration
+}
+
+func (b fixedBackend) Embed(text string) ([]float32, error) {
+	time.Sleep(b.delay)
+	return b.embedding, b.err
+}
+
+func TestValidateAll(t *testing.T) {
+	unavailable := errors.New("coreml-cli-v2 not found")
+	backends := map[string]Backend{
+		"onnx":   fixedBackend{embedding: []float32{1, 0, 0}},
+		"python": fixedBackend{embedding: []float32{0.99, 0.1, 0}},
+		"coreml": fixedBackend{err: unavailable},
+	}
+
+	report, err := ValidateAll(context.Background(), backends, ValidateOptions{Dim: 3, MinSimilarity: 0.98})
+	if !errors.Is(err, unavailable) || !strings.Contains(err.Error(), "backend coreml") {
+		t.Fatalf("expected the coreml failure, got %v", err)
+	}
+
+	ready := map[string]bool{}
+	for _, status := range report.Backends {
+		ready[status.Name] = status.Ready()
+	}
+	if want := map[string]bool{"coreml": false, "onnx": true, "python": true}; !reflect.DeepEqual(ready, want) {
+		t.Errorf("readiness = %v, want %v", ready, want)
+	}
+	if report.Backends[0].Name != "coreml" || !errors.Is(report.Backends[0].Err, unavailable) {
+		t.Errorf("coreml status = %+v", report.Backends[0])
+	}
+
+	if len(report.Similarities) != 1 {
+		t.Fatalf("similarities = %+v, want one onnx/python pair", report.Similarities)
+	}
+	if s := report.Similarities[0]; s.A != "onnx" || s.B != "python" || s.Similarity < 0.98 {
+		t.Errorf("similarity = %+v", s)
+	}
+}
+
+func TestValidateAllChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		backends map[string]Backend
+		opts     ValidateOptions
+		wantErr  string
+	}{
+		{
+			name:     "all ready",
+			backends: map[string]Backend{"a": fixedBackend{embedding: []float32{1, 0}}, "b": fixedBackend{embedding: []float32{1, 0}}},
+			opts:     ValidateOptions{Dim: 2, MinSimilarity: 0.99},
+		},
+		{
+			name:     "wrong dimension",
+			backends: map[string]Backend{"a": fixedBackend{embedding: []float32{1, 0}}},
+			opts:     ValidateOptions{Dim: 768},
+			wantErr:  "backend a: returned 2 dimensions, want 768",
+		},
+		{
+			name:     "dimensions disagree",
+			backends: map[string]Backend{"a": fixedBackend{embedding: []float32{1, 0}}, "b": fixedBackend{embedding: []float32{1, 0, 0}}},
+			wantErr:  "backends a and b disagree on dimension",
+		},
+		{
+			name:     "embeddings disagree",
+			backends: map[string]Backend{"a": fixedBackend{embedding: []float32{1, 0}}, "b": fixedBackend{embedding: []float32{0, 1}}},
+			opts:     ValidateOptions{MinSimilarity: 0.9},
+			wantErr:  "backends a and b disagree: similarity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateAll(context.Background(), tt.backends, tt.opts)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAllContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	backends := map[string]Backend{"slow": fixedBackend{embedding: []float32{1}, delay: time.Second}}
+	report, err := ValidateAll(ctx, backends, ValidateOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	if report.Backends[0].Ready() {
+		t.Error("slow backend reported ready")
+	}
+}
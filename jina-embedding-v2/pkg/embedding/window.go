@@ -0,0 +1,111 @@
+package embedding
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+)
+
+// SessionEmbedder keeps the embeddings of the last few messages of a
+// conversation and combines them into a single session embedding, weighted
+// toward the most recent messages. Each message is embedded once, when it is
+// appended. A SessionEmbedder is safe for concurrent use.
+type SessionEmbedder struct {
+	model interface {
+		Embed(text string) ([]float32, error)
+	}
+	windowSize int
+	decay      float64
+
+	mu         sync.Mutex
+	texts      []string
+	embeddings [][]float32
+	combined   []float32
+}
+
+// NewSessionEmbedder returns a SessionEmbedder over m that keeps the last
+// windowSize messages. The newest message has weight 1 and each older one
+// decay times the weight of the message after it, so decay must be in
+// (0, 1]; 1 weights the window evenly.
+func NewSessionEmbedder(m *Model, windowSize int, decay float64) (*SessionEmbedder, error) {
+	if windowSize < 1 {
+		return nil, fmt.Errorf("window size must be at least 1, got %d", windowSize)
+	}
+	if decay <= 0 || decay > 1 {
+		return nil, fmt.Errorf("decay must be in (0, 1], got %v", decay)
+	}
+	return &SessionEmbedder{model: m, windowSize: windowSize, decay: decay}, nil
+}
+
+// Append embeds text and adds it to the window, evicting the oldest message
+// if the window is full.
+func (s *SessionEmbedder) Append(text string) error {
+	embedding, err := s.model.Embed(text)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.texts = append(s.texts, text)
+	s.embeddings = append(s.embeddings, embedding)
+	if len(s.texts) > s.windowSize {
+		s.texts = s.texts[1:]
+		s.embeddings = s.embeddings[1:]
+	}
+	s.combined = nil
+	return nil
+}
+
+// Messages returns the texts currently in the window, oldest first.
+func (s *SessionEmbedder) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.texts...)
+}
+
+// Embedding returns the L2-normalized, recency-weighted mean of the window's
+// embeddings, or nil if no message has been appended. The result is cached
+// until the window changes; callers get their own copy.
+func (s *SessionEmbedder) Embedding() []float32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.combined != nil || len(s.embeddings) == 0 {
+		return slices.Clone(s.combined)
+	}
+
+	dim := len(s.embeddings[0])
+	sum := make([]float64, dim)
+	weight := 1.0
+	for i := len(s.embeddings) - 1; i >= 0; i-- {
+		for d, v := range s.embeddings[i] {
+			sum[d] += weight * float64(v)
+		}
+		weight *= s.decay
+	}
+
+	var norm float64
+	for _, v := range sum {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	combined := make([]float32, dim)
+	for d, v := range sum {
+		if norm > 0 {
+			combined[d] = float32(v / norm)
+		}
+	}
+	s.combined = combined
+	return slices.Clone(combined)
+}
+
+// Reset empties the window.
+func (s *SessionEmbedder) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.texts, s.embeddings, s.combined = nil, nil, nil
+}
@@ -0,0 +1,76 @@
+package embedding
+
+import (
+	"reflect"
+	"testing"
+)
+
+// axisEmbedder embeds each known text as a unit vector along its own axis.
+type axisEmbedder map[string]int
+
+func (a axisEmbedder) Embed(text string) ([]float32, error) {
+	v := make([]float32, len(a))
+	v[a[text]] = 1
+	return v, nil
+}
+
+func TestSessionEmbedderRecencyAndEviction(t *testing.T) {
+	s := &SessionEmbedder{
+		model:      axisEmbedder{"a": 0, "b": 1, "c": 2, "d": 3},
+		windowSize: 3,
+		decay:      0.5,
+	}
+
+	if s.Embedding() != nil {
+		t.Error("expected nil embedding for an empty session")
+	}
+
+	for _, text := range []string{"a", "b", "c"} {
+		if err := s.Append(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Weights 0.25, 0.5, 1 for a, b, c.
+	emb := s.Embedding()
+	if !(emb[2] > emb[1] && emb[1] > emb[0] && emb[0] > 0) {
+		t.Errorf("embedding %v is not weighted toward recent messages", emb)
+	}
+	if got, want := emb[2]/emb[1], float32(2); got != want {
+		t.Errorf("newest/previous weight ratio = %v, want %v", got, want)
+	}
+
+	if err := s.Append("d"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b", "c", "d"}; !reflect.DeepEqual(s.Messages(), want) {
+		t.Errorf("Messages() = %v, want %v", s.Messages(), want)
+	}
+	emb = s.Embedding()
+	if emb[0] != 0 {
+		t.Errorf("evicted message still contributes: %v", emb)
+	}
+	if !(emb[3] > emb[2] && emb[2] > emb[1]) {
+		t.Errorf("embedding %v is not weighted toward recent messages", emb)
+	}
+
+	// Changing a result must not affect later calls.
+	emb[3] = 42
+	if again := s.Embedding(); again[3] == 42 {
+		t.Error("Embedding returned the cached slice itself")
+	}
+
+	s.Reset()
+	if s.Embedding() != nil || len(s.Messages()) != 0 {
+		t.Error("Reset did not empty the session")
+	}
+}
+
+func TestNewSessionEmbedderValidates(t *testing.T) {
+	if _, err := NewSessionEmbedder(nil, 0, 0.5); err == nil {
+		t.Error("expected an error for a zero window")
+	}
+	if _, err := NewSessionEmbedder(nil, 3, 1.5); err == nil {
+		t.Error("expected an error for decay > 1")
+	}
+}
@@ -0,0 +1,5 @@
+// Package embeddingpb contains the generated protobuf and gRPC code for the
+// embedding service.
+package embeddingpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative embedding.proto
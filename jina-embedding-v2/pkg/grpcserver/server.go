@@ -0,0 +1,110 @@
+// Package grpcserver exposes an embedding.Model as the gRPC
+// embedding.v1.EmbeddingService defined in embeddingpb/embedding.proto.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/grpcserver/embeddingpb"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// embedder is the subset of *embedding.Model the service uses.
+type embedder interface {
+	EmbedBatchContext(ctx context.Context, texts []string) ([][]float32, int, error)
+	EmbedBatchWithTaskContext(ctx context.Context, texts []string, taskType string) ([][]float32, int, error)
+}
+
+type service struct {
+	embeddingpb.UnimplementedEmbeddingServiceServer
+	model embedder
+}
+
+// Register registers an EmbeddingService backed by m on s:
+//
+//	s := grpc.NewServer()
+//	grpcserver.Register(s, model)
+//	s.Serve(lis)
+//
+// Requests are abandoned once their context is cancelled or its deadline
+// passes. Errors carry the codes matching the HTTP server's statuses:
+// InvalidArgument for inputs the model cannot take, and Unavailable for a
+// batch soft timeout or a closed model.
+func Register(s grpc.ServiceRegistrar, m *embedding.Model) {
+	embeddingpb.RegisterEmbeddingServiceServer(s, &service{model: m})
+}
+
+func (s *service) Embed(ctx context.Context, req *embeddingpb.EmbedRequest) (*embeddingpb.EmbedReply, error) {
+	return s.embed(ctx, req)
+}
+
+func (s *service) EmbedStream(stream grpc.BidiStreamingServer[embeddingpb.EmbedRequest, embeddingpb.EmbedReply]) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		reply, err := s.embed(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *service) embed(ctx context.Context, req *embeddingpb.EmbedRequest) (*embeddingpb.EmbedReply, error) {
+	if len(req.GetTexts()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "texts must not be empty")
+	}
+
+	var embeddings [][]float32
+	var err error
+	if req.GetTask() == "" {
+		embeddings, _, err = s.model.EmbedBatchContext(ctx, req.GetTexts())
+	} else {
+		embeddings, _, err = s.model.EmbedBatchWithTaskContext(ctx, req.GetTexts(), req.GetTask())
+	}
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	reply := &embeddingpb.EmbedReply{Embeddings: make([]*embeddingpb.Embedding, len(embeddings))}
+	for i, values := range embeddings {
+		reply.Embeddings[i] = &embeddingpb.Embedding{Values: values}
+	}
+	return reply, nil
+}
+
+// errorCode returns the status code for an error from the model, matching
+// the HTTP statuses pkg/server uses.
+func errorCode(err error) codes.Code {
+	var tooLong *embedding.InputTooLongError
+	var partial *embedding.PartialBatchError
+	switch {
+	case errors.As(err, &tooLong),
+		errors.Is(err, embedding.ErrEmptyInput),
+		errors.Is(err, embedding.ErrInvalidTask),
+		errors.Is(err, tokenizer.ErrUnknownTask):
+		return codes.InvalidArgument
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.As(err, &partial), errors.Is(err, embedding.ErrClosed):
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,118 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/grpcserver/embeddingpb"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeEmbedder embeds each text as [len(text), task index] and knows the
+// tasks "a" and "b". It fails with err if set, and with ctx.Err() once ctx
+// is done.
+type fakeEmbedder struct {
+	err error
+}
+
+func (f fakeEmbedder) EmbedBatchContext(ctx context.Context, texts []string) ([][]float32, int, error) {
+	return f.embed(ctx, texts, 0)
+}
+
+func (f fakeEmbedder) EmbedBatchWithTaskContext(ctx context.Context, texts []string, taskType string) ([][]float32, int, error) {
+	switch taskType {
+	case "a":
+		return f.embed(ctx, texts, 1)
+	case "b":
+		return f.embed(ctx, texts, 2)
+	}
+	return nil, 0, fmt.Errorf("failed to resolve task %q: %w", taskType, tokenizer.ErrUnknownTask)
+}
+
+func (f fakeEmbedder) embed(ctx context.Context, texts []string, task float32) ([][]float32, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{float32(len(text)), task}
+	}
+	return out, 0, nil
+}
+
+func TestEmbed(t *testing.T) {
+	s := &service{model: fakeEmbedder{}}
+
+	tests := []struct {
+		name string
+		task string
+		want float32
+	}{
+		{"default task", "", 0},
+		{"named task", "b", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := s.Embed(context.Background(), &embeddingpb.EmbedRequest{Texts: []string{"ab", "cde"}, Task: tt.task})
+			if err != nil {
+				t.Fatalf("Embed: %v", err)
+			}
+			if len(reply.GetEmbeddings()) != 2 {
+				t.Fatalf("got %d embeddings, want 2", len(reply.GetEmbeddings()))
+			}
+			for i, wantLen := range []float32{2, 3} {
+				values := reply.GetEmbeddings()[i].GetValues()
+				if values[0] != wantLen || values[1] != tt.want {
+					t.Errorf("embedding %d = %v, want [%v %v]", i, values, wantLen, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbedErrors(t *testing.T) {
+	texts := []string{"a"}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Unix(0, 0))
+	defer cancel()
+
+	tests := []struct {
+		name     string
+		embedder fakeEmbedder
+		ctx      context.Context
+		req      *embeddingpb.EmbedRequest
+		want     codes.Code
+	}{
+		{"no texts", fakeEmbedder{}, context.Background(), &embeddingpb.EmbedRequest{}, codes.InvalidArgument},
+		{"unknown task", fakeEmbedder{}, context.Background(), &embeddingpb.EmbedRequest{Texts: texts, Task: "nope"}, codes.InvalidArgument},
+		{"empty input", fakeEmbedder{err: fmt.Errorf("input 0: %w", embedding.ErrEmptyInput)}, context.Background(), &embeddingpb.EmbedRequest{Texts: texts}, codes.InvalidArgument},
+		{"invalid task", fakeEmbedder{err: fmt.Errorf("%w: 9", embedding.ErrInvalidTask)}, context.Background(), &embeddingpb.EmbedRequest{Texts: texts}, codes.InvalidArgument},
+		{"input too long", fakeEmbedder{err: &embedding.InputTooLongError{Tokens: 9, MaxLength: 4}}, context.Background(), &embeddingpb.EmbedRequest{Texts: texts}, codes.InvalidArgument},
+		{"soft timeout", fakeEmbedder{err: &embedding.PartialBatchError{Unprocessed: []int{0}}}, context.Background(), &embeddingpb.EmbedRequest{Texts: texts}, codes.Unavailable},
+		{"model closed", fakeEmbedder{err: embedding.ErrClosed}, context.Background(), &embeddingpb.EmbedRequest{Texts: texts}, codes.Unavailable},
+		{"model error", fakeEmbedder{err: errors.New("boom")}, context.Background(), &embeddingpb.EmbedRequest{Texts: texts}, codes.Internal},
+		{"cancelled", fakeEmbedder{}, cancelled, &embeddingpb.EmbedRequest{Texts: texts}, codes.Canceled},
+		{"deadline exceeded", fakeEmbedder{}, expired, &embeddingpb.EmbedRequest{Texts: texts, Task: "a"}, codes.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &service{model: tt.embedder}
+			_, err := s.Embed(tt.ctx, tt.req)
+			if status.Code(err) != tt.want {
+				t.Errorf("code = %v, want %v (err: %v)", status.Code(err), tt.want, err)
+			}
+		})
+	}
+}
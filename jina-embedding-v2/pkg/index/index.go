@@ -0,0 +1,185 @@
+// Package index is a brute-force vector index held in memory, for corpora
+// small enough that running Weaviate isn't worth it.
+package index
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Hit is one search result.
+type Hit struct {
+	ID string
+	// Score is the cosine similarity between the query and the vector.
+	Score float32
+}
+
+// InMemoryIndex stores L2-normalized vectors by ID and searches them by
+// cosine similarity. It is safe for concurrent use.
+type InMemoryIndex struct {
+	mu      sync.RWMutex
+	dim     int
+	ids     []string
+	vectors [][]float32
+	pos     map[string]int
+}
+
+// New returns an empty index. Its dimension is set by the first Add.
+func New() *InMemoryIndex {
+	return &InMemoryIndex{pos: make(map[string]int)}
+}
+
+// Len returns the number of stored vectors.
+func (ix *InMemoryIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.ids)
+}
+
+// Add stores a normalized copy of vec under id, replacing any vector
+// already stored there. All vectors must have the same dimension.
+func (ix *InMemoryIndex) Add(id string, vec []float32) error {
+	if len(vec) == 0 {
+		return fmt.Errorf("index: empty vector for %q", id)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.dim != 0 && len(vec) != ix.dim {
+		return fmt.Errorf("index: vector for %q has dimension %d, index has %d", id, len(vec), ix.dim)
+	}
+	ix.dim = len(vec)
+
+	normalized := normalize(vec)
+	if i, ok := ix.pos[id]; ok {
+		ix.vectors[i] = normalized
+		return nil
+	}
+	ix.pos[id] = len(ix.ids)
+	ix.ids = append(ix.ids, id)
+	ix.vectors = append(ix.vectors, normalized)
+	return nil
+}
+
+// Search returns the k stored vectors most similar to query, best first.
+// Ties are broken by insertion order. A query whose dimension doesn't match
+// the index returns no hits.
+func (ix *InMemoryIndex) Search(query []float32, k int) []Hit {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if k <= 0 || len(query) != ix.dim || len(ix.ids) == 0 {
+		return nil
+	}
+
+	q := normalize(query)
+	hits := make([]Hit, len(ix.ids))
+	for i, vec := range ix.vectors {
+		hits[i] = Hit{ID: ix.ids[i], Score: dot(q, vec)}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	if k < len(hits) {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// snapshot is the gob-encoded form of an index.
+type snapshot struct {
+	IDs     []string
+	Vectors [][]float32
+}
+
+// Save writes the index to path as gob, replacing the file atomically.
+func (ix *InMemoryIndex) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := ix.encode(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+	return nil
+}
+
+// Load reads an index written by Save.
+func Load(path string) (*InMemoryIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("index: %w", err)
+	}
+	defer f.Close()
+
+	return decode(f)
+}
+
+func (ix *InMemoryIndex) encode(w io.Writer) error {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if err := gob.NewEncoder(w).Encode(snapshot{IDs: ix.ids, Vectors: ix.vectors}); err != nil {
+		return fmt.Errorf("index: encode: %w", err)
+	}
+	return nil
+}
+
+func decode(r io.Reader) (*InMemoryIndex, error) {
+	var snap snapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("index: decode: %w", err)
+	}
+	if len(snap.IDs) != len(snap.Vectors) {
+		return nil, fmt.Errorf("index: decode: %d ids for %d vectors", len(snap.IDs), len(snap.Vectors))
+	}
+
+	ix := New()
+	for i, id := range snap.IDs {
+		if err := ix.Add(id, snap.Vectors[i]); err != nil {
+			return nil, err
+		}
+	}
+	return ix, nil
+}
+
+// normalize returns vec scaled to unit length. A zero vector stays zero.
+func normalize(vec []float32) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+
+	out := make([]float32, len(vec))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		out[i] = float32(float64(v) / norm)
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
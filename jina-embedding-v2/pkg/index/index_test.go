@@ -0,0 +1,117 @@
+package index
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestIndex(t *testing.T) *InMemoryIndex {
+	t.Helper()
+
+	ix := New()
+	docs := map[string][]float32{
+		"east":  {1, 0},
+		"north": {0, 2},
+		"ne":    {3, 3},
+		"west":  {-1, 0},
+	}
+	for _, id := range []string{"east", "north", "ne", "west"} {
+		if err := ix.Add(id, docs[id]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ix
+}
+
+func hitIDs(hits []Hit) []string {
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	ix := newTestIndex(t)
+
+	tests := []struct {
+		name  string
+		query []float32
+		k     int
+		want  []string
+	}{
+		{"nearest first", []float32{10, 1}, 2, []string{"east", "ne"}},
+		{"k larger than index", []float32{0, 1}, 10, []string{"north", "ne", "east", "west"}},
+		{"zero k", []float32{1, 0}, 0, nil},
+		{"wrong dimension", []float32{1, 0, 0}, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hitIDs(ix.Search(tt.query, tt.k))
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchScores(t *testing.T) {
+	hits := newTestIndex(t).Search([]float32{2, 0}, 4)
+
+	want := map[string]float64{"east": 1, "ne": math.Sqrt2 / 2, "north": 0, "west": -1}
+	for _, hit := range hits {
+		if math.Abs(float64(hit.Score)-want[hit.ID]) > 1e-6 {
+			t.Errorf("score of %s = %v, want %v", hit.ID, hit.Score, want[hit.ID])
+		}
+	}
+}
+
+func TestAddReplacesAndChecksDimension(t *testing.T) {
+	ix := newTestIndex(t)
+
+	if err := ix.Add("west", []float32{0, -1}); err != nil {
+		t.Fatal(err)
+	}
+	if ix.Len() != 4 {
+		t.Errorf("Len() = %d after replacing a vector, want 4", ix.Len())
+	}
+	if hits := ix.Search([]float32{0, -5}, 1); len(hits) != 1 || hits[0].ID != "west" {
+		t.Errorf("Search after replace = %v, want west first", hits)
+	}
+
+	if err := ix.Add("up", []float32{0, 0, 1}); err == nil {
+		t.Error("expected an error for a vector of the wrong dimension")
+	}
+	if err := ix.Add("empty", nil); err == nil {
+		t.Error("expected an error for an empty vector")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	ix := newTestIndex(t)
+	path := filepath.Join(t.TempDir(), "index.gob")
+
+	if err := ix.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := []float32{1, 2}
+	if got, want := loaded.Search(query, 4), ix.Search(query, 4); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded index returns %v, want %v", got, want)
+	}
+
+	if _, err := decode(bytes.NewReader([]byte("not gob"))); err == nil {
+		t.Error("expected an error decoding garbage")
+	}
+}
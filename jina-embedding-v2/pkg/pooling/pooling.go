@@ -0,0 +1,89 @@
+// Package pooling combines the per-token hidden states of a transformer
+// into one vector per input. Hidden states are a flattened
+// [batchSize, seqLen, embedDim] slice and masks a flattened
+// [batchSize, seqLen] one, as onnxruntime returns and takes them.
+package pooling
+
+import "math"
+
+// Mean averages the hidden states of the attended tokens of each row,
+// weighting each by its mask value. A row with no attended tokens pools to
+// zeros.
+func Mean(modelOutput []float32, attentionMask []int64, batchSize, seqLen, embedDim int) []float32 {
+	return WeightedMean(modelOutput, nil, attentionMask, nil, batchSize, seqLen, embedDim)
+}
+
+// WeightedMean is Mean with the hidden state of token s of row b also
+// weighted by weights[inputIds[b*seqLen+s]]; tokens not in weights have
+// weight 1, and inputIds is not read when weights is empty. The sum is
+// divided by the total weight of the row. It walks the [seqLen, embedDim]
+// output of a row token by token so reads stay contiguous.
+func WeightedMean(modelOutput []float32, inputIds, attentionMask []int64, weights map[int64]float32, batchSize, seqLen, embedDim int) []float32 {
+	result := make([]float32, batchSize*embedDim)
+
+	for b := 0; b < batchSize; b++ {
+		row := result[b*embedDim : (b+1)*embedDim]
+
+		var sumWeight float32
+		for s := 0; s < seqLen; s++ {
+			weight := float32(attentionMask[b*seqLen+s])
+			if len(weights) > 0 {
+				if w, ok := weights[inputIds[b*seqLen+s]]; ok {
+					weight *= w
+				}
+			}
+			if weight == 0 {
+				continue
+			}
+			sumWeight += weight
+
+			hidden := modelOutput[(b*seqLen+s)*embedDim : (b*seqLen+s+1)*embedDim]
+			for i, v := range hidden {
+				row[i] += v * weight
+			}
+		}
+
+		if sumWeight < 1e-9 {
+			sumWeight = 1e-9
+		}
+		for i := range row {
+			row[i] /= sumWeight
+		}
+	}
+	return result
+}
+
+// CLS returns the hidden state at position 0, the <s> or [CLS] token, of
+// every row.
+func CLS(modelOutput []float32, batchSize, seqLen, embedDim int) []float32 {
+	result := make([]float32, batchSize*embedDim)
+	for b := 0; b < batchSize; b++ {
+		copy(result[b*embedDim:(b+1)*embedDim], modelOutput[b*seqLen*embedDim:])
+	}
+	return result
+}
+
+// L2Normalize returns a copy of the batchSize rows of embeddings, each
+// scaled to unit length. As in Mean, the divisor is clamped to 1e-9, so an
+// all-zero row, such as Mean returns for a fully masked one, stays all zero
+// instead of becoming NaN.
+func L2Normalize(embeddings []float32, batchSize, embedDim int) []float32 {
+	result := make([]float32, len(embeddings))
+
+	for b := 0; b < batchSize; b++ {
+		var norm float32
+		for i := 0; i < embedDim; i++ {
+			val := embeddings[b*embedDim+i]
+			norm += val * val
+		}
+		norm = float32(math.Sqrt(float64(norm)))
+		if norm < 1e-9 {
+			norm = 1e-9
+		}
+
+		for i := 0; i < embedDim; i++ {
+			result[b*embedDim+i] = embeddings[b*embedDim+i] / norm
+		}
+	}
+	return result
+}
@@ -0,0 +1,197 @@
+package pooling
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMean(t *testing.T) {
+	tests := []struct {
+		name                        string
+		output                      []float32
+		mask                        []int64
+		batchSize, seqLen, embedDim int
+		want                        []float32
+	}{
+		{
+			name:      "all tokens attended",
+			output:    []float32{1, 2, 3, 4, 5, 6},
+			mask:      []int64{1, 1, 1},
+			batchSize: 1, seqLen: 3, embedDim: 2,
+			want: []float32{3, 4},
+		},
+		{
+			name:      "fully masked row pools to zeros",
+			output:    []float32{1, 2, 3, 4},
+			mask:      []int64{0, 0},
+			batchSize: 1, seqLen: 2, embedDim: 2,
+			want: []float32{0, 0},
+		},
+		{
+			name: "batch of two with different mask lengths",
+			// Row 0 attends three tokens, row 1 only its first two.
+			output: []float32{
+				1, 10, 2, 20, 3, 30,
+				4, 40, 6, 60, 100, 1000,
+			},
+			mask:      []int64{1, 1, 1, 1, 1, 0},
+			batchSize: 2, seqLen: 3, embedDim: 2,
+			want: []float32{2, 20, 5, 50},
+		},
+		{
+			name:      "fully masked row next to an attended one",
+			output:    []float32{1, 2, 3, 4},
+			mask:      []int64{0, 1},
+			batchSize: 2, seqLen: 1, embedDim: 2,
+			want: []float32{0, 0, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mean(tt.output, tt.mask, tt.batchSize, tt.seqLen, tt.embedDim)
+			assertClose(t, got, tt.want)
+		})
+	}
+}
+
+func TestWeightedMean(t *testing.T) {
+	// One row of four tokens with 1-d hidden states 1, 2, 3, 4; the last
+	// token is padding.
+	output := []float32{1, 2, 3, 4}
+	ids := []int64{0, 7, 8, 1}
+	mask := []int64{1, 1, 1, 0}
+
+	tests := []struct {
+		name    string
+		weights map[int64]float32
+		want    float32
+	}{
+		{"no weights is mean pooling", nil, 2},
+		{"down-weighted token", map[int64]float32{7: 0.5}, (1 + 2*0.5 + 3) / 2.5},
+		{"zero weight drops a token", map[int64]float32{8: 0}, 1.5},
+		{"padding stays excluded", map[int64]float32{1: 10}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WeightedMean(output, ids, mask, tt.weights, 1, 4, 1)
+			assertClose(t, got, []float32{tt.want})
+		})
+	}
+}
+
+func TestCLS(t *testing.T) {
+	// Two rows of three tokens: row b, token s has hidden state [10b+s].
+	output := []float32{0, 1, 2, 10, 11, 12}
+	assertClose(t, CLS(output, 2, 3, 1), []float32{0, 10})
+}
+
+func TestL2Normalize(t *testing.T) {
+	tests := []struct {
+		name                string
+		embeddings          []float32
+		batchSize, embedDim int
+		want                []float32
+	}{
+		{"single row", []float32{3, 4}, 1, 2, []float32{0.6, 0.8}},
+		{"already unit length", []float32{0, 1, 0}, 1, 3, []float32{0, 1, 0}},
+		{"rows scale independently", []float32{3, 4, 0, -2}, 2, 2, []float32{0.6, 0.8, 0, -1}},
+		{"zero vector stays zero", []float32{0, 0, 0}, 1, 3, []float32{0, 0, 0}},
+		{"zero row next to a non-zero one", []float32{0, 0, 3, 4}, 2, 2, []float32{0, 0, 0.6, 0.8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append([]float32(nil), tt.embeddings...)
+			got := L2Normalize(in, tt.batchSize, tt.embedDim)
+			assertClose(t, got, tt.want)
+			assertClose(t, in, tt.embeddings)
+		})
+	}
+}
+
+func assertClose(t *testing.T, got, want []float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if math.IsNaN(float64(got[i])) || math.Abs(float64(got[i]-want[i])) > 1e-6 {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+// meanDimOuter is the previous Mean, which looped over the embedding
+// dimension outermost and strided through modelOutput. It is kept to check
+// the results match and to benchmark against.
+func meanDimOuter(modelOutput []float32, attentionMask []int64, batchSize, seqLen, embedDim int) []float32 {
+	result := make([]float32, batchSize*embedDim)
+
+	for b := 0; b < batchSize; b++ {
+		var sumMask float32
+		for i := 0; i < embedDim; i++ {
+			var sumEmbedding float32
+			for s := 0; s < seqLen; s++ {
+				maskVal := float32(attentionMask[b*seqLen+s])
+				embeddingVal := modelOutput[b*seqLen*embedDim+s*embedDim+i]
+				sumEmbedding += embeddingVal * maskVal
+				if i == 0 {
+					sumMask += maskVal
+				}
+			}
+			if sumMask < 1e-9 {
+				sumMask = 1e-9
+			}
+			result[b*embedDim+i] = sumEmbedding / sumMask
+		}
+	}
+	return result
+}
+
+// poolingInput returns deterministic hidden states and a mask whose rows
+// are padded to different lengths.
+func poolingInput(batchSize, seqLen, embedDim int) ([]float32, []int64) {
+	output := make([]float32, batchSize*seqLen*embedDim)
+	for i := range output {
+		output[i] = float32(i%97)/97 - 0.5
+	}
+	mask := make([]int64, batchSize*seqLen)
+	for b := 0; b < batchSize; b++ {
+		for s := 0; s < seqLen-b%seqLen; s++ {
+			mask[b*seqLen+s] = 1
+		}
+	}
+	return output, mask
+}
+
+func TestMeanMatchesDimOuter(t *testing.T) {
+	output, mask := poolingInput(3, 17, 8)
+	got := Mean(output, mask, 3, 17, 8)
+	want := meanDimOuter(output, mask, 3, 17, 8)
+	for i := range want {
+		if d := got[i] - want[i]; d > 1e-5 || d < -1e-5 {
+			t.Fatalf("Mean[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func BenchmarkMean(b *testing.B) {
+	const batchSize, seqLen, embedDim = 8, 512, 768
+	output, mask := poolingInput(batchSize, seqLen, embedDim)
+
+	for _, impl := range []struct {
+		name string
+		pool func([]float32, []int64, int, int, int) []float32
+	}{
+		{"seq-outer", Mean},
+		{"dim-outer", meanDimOuter},
+	} {
+		b.Run(impl.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				impl.pool(output, mask, batchSize, seqLen, embedDim)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+// Package pyclient embeds text through the Python ONNX server in py/main.py,
+// which listens on a local TCP port and answers one JSON request per
+// connection.
+package pyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the port py/main.py listens on.
+const DefaultPort = "8888"
+
+type InferenceRequest struct {
+	Command string `json:"command"`
+	Text    string `json:"text"`
+}
+
+type InferenceResponse struct {
+	Embedding     []float64 `json:"embedding"`
+	Shape         []int     `json:"shape"`
+	InferenceTime float64   `json:"inference_time"`
+	Error         string    `json:"error"`
+}
+
+// PyClient sends requests to a Python server on localhost. A client made by
+// NewPyClientWithAutostart may own the server process, which Close stops.
+type PyClient struct {
+	addr string
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+// NewPyClient returns a client for a server already listening on port. No
+// connection is made until the first request.
+func NewPyClient(port string) *PyClient {
+	return &PyClient{addr: net.JoinHostPort("localhost", port)}
+}
+
+// ServerOptions says how to start the Python server. Zero fields take the
+// defaults, which run `uv run main.py` with the server's output going to
+// this process's stdout and stderr.
+type ServerOptions struct {
+	// Command is the program to run; the default is "uv".
+	Command string
+	// Args are the program's arguments. The default, used only when
+	// Command is empty too, is "run main.py".
+	Args []string
+	// Dir is the working directory, normally the py directory.
+	Dir string
+	// Stdout and Stderr receive the server's output; nil means os.Stdout
+	// and os.Stderr. Pass io.Discard to silence it.
+	Stdout, Stderr io.Writer
+	// StartTimeout bounds the wait for a started server to accept
+	// connections; zero means DefaultStartTimeout.
+	StartTimeout time.Duration
+}
+
+// withDefaults fills in the zero fields of o.
+func (o ServerOptions) withDefaults() ServerOptions {
+	if o.Command == "" {
+		o.Command = "uv"
+		if o.Args == nil {
+			o.Args = []string{"run", "main.py"}
+		}
+	}
+	if o.Stdout == nil {
+		o.Stdout = os.Stdout
+	}
+	if o.Stderr == nil {
+		o.Stderr = os.Stderr
+	}
+	return o
+}
+
+// NewPyClientWithAutostart returns a client for the server on DefaultPort,
+// first starting `uv run main.py` in pyDir if no server is listening. When
+// several programs start at once only one spawns the server. Close stops
+// the server if this client started it.
+func NewPyClientWithAutostart(pyDir string) (*PyClient, error) {
+	if _, err := os.Stat(pyDir); err != nil {
+		return nil, fmt.Errorf("py directory not found at %s: %w", pyDir, err)
+	}
+	return NewPyClientWithServer(ServerOptions{Dir: pyDir})
+}
+
+// NewPyClientWithServer is NewPyClientWithAutostart with the server started
+// as opts describes.
+func NewPyClientWithServer(opts ServerOptions) (*PyClient, error) {
+	c := NewPyClient(DefaultPort)
+	cmd, err := newServerLauncher(opts, DefaultPort).ensure()
+	if err != nil {
+		return nil, err
+	}
+	c.cmd = cmd
+	return c, nil
+}
+
+// StartedServer reports whether this client started the server process.
+func (c *PyClient) StartedServer() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cmd != nil
+}
+
+// Infer sends one inference request and returns the server's response,
+// timing included. A response with Error set is returned as an error.
+func (c *PyClient) Infer(text string) (*InferenceResponse, error) {
+	var response InferenceResponse
+	if err := c.request(InferenceRequest{Command: "infer", Text: text}, &response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("inference error: %s", response.Error)
+	}
+	return &response, nil
+}
+
+// Embed returns the embedding of text.
+func (c *PyClient) Embed(text string) ([]float32, error) {
+	response, err := c.Infer(text)
+	if err != nil {
+		return nil, err
+	}
+
+	embedding := make([]float32, len(response.Embedding))
+	for i, v := range response.Embedding {
+		embedding[i] = float32(v)
+	}
+	return embedding, nil
+}
+
+// BatchEmbed embeds texts one request at a time, as the server has no batch
+// command, stopping at the first error.
+func (c *PyClient) BatchEmbed(texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embedding, err := c.Embed(text)
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", i, err)
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+// Shutdown asks the server to exit, whoever started it. If this client
+// started it, Shutdown waits for the process and kills it if it hasn't
+// exited after five seconds.
+func (c *PyClient) Shutdown() error {
+	c.mu.Lock()
+	cmd := c.cmd
+	c.cmd = nil
+	c.mu.Unlock()
+
+	if cmd != nil {
+		gracefulShutdown(c.addr, cmd)
+		return nil
+	}
+	return sendShutdownRequest(c.addr)
+}
+
+// Close stops the server if this client started it, and otherwise leaves
+// it running for other clients.
+func (c *PyClient) Close() error {
+	if !c.StartedServer() {
+		return nil
+	}
+	return c.Shutdown()
+}
+
+// request sends req on a new connection and decodes the reply into resp.
+// The server closes the connection after replying.
+func (c *PyClient) request(req InferenceRequest, resp any) error {
+	conn, err := net.Dial("tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	requestData, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(requestData); err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(conn).Decode(resp); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	return nil
+}
+
+func isServerRunning(addr string) bool {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func sendShutdownRequest(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	requestData, err := json.Marshal(InferenceRequest{Command: "shutdown"})
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(requestData); err != nil {
+		return err
+	}
+
+	// Read the acknowledgement but don't wait long; the server may close
+	// the connection without one.
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buffer := make([]byte, 1024)
+	conn.Read(buffer)
+	return nil
+}
+
+func gracefulShutdown(addr string, serverCmd *exec.Cmd) {
+	if serverCmd == nil {
+		return
+	}
+
+	// Ask the server to exit; if the request fails it is killed below.
+	_ = sendShutdownRequest(addr)
+
+	// Wait a bit for graceful shutdown
+	done := make(chan error, 1)
+	go func() {
+		done <- serverCmd.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		serverCmd.Process.Kill()
+		serverCmd.Wait()
+	}
+}
+
+func startServer(opts ServerOptions) (*exec.Cmd, error) {
+	opts = opts.withDefaults()
+
+	cmd := exec.Command(opts.Command, opts.Args...)
+	cmd.Dir = opts.Dir
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+
+	err := cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
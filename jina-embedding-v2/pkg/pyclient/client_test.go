@@ -0,0 +1,180 @@
+package pyclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer speaks the py/main.py protocol: one JSON request per
+// connection, answered and closed. "infer" embeds a text as [len(text), 1]
+// and text "fail" gets an error response.
+type fakeServer struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req InferenceRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.commands = append(s.commands, req.Command)
+	s.mu.Unlock()
+
+	var resp any
+	switch {
+	case req.Command == "shutdown":
+		resp = map[string]string{"status": "shutting down"}
+	case req.Text == "fail":
+		resp = InferenceResponse{Error: "Model not loaded"}
+	default:
+		resp = InferenceResponse{
+			Embedding:     []float64{float64(len(req.Text)), 1},
+			Shape:         []int{1, 2},
+			InferenceTime: 0.01,
+		}
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *fakeServer) port() string {
+	_, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return port
+}
+
+func TestPyClientEmbed(t *testing.T) {
+	client := NewPyClient(newFakeServer(t).port())
+
+	got, err := client.Embed("apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float32{5, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Embed = %v, want %v", got, want)
+	}
+
+	batch, err := client.BatchEmbed([]string{"a", "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float32{{1, 1}, {3, 1}}; !reflect.DeepEqual(batch, want) {
+		t.Errorf("BatchEmbed = %v, want %v", batch, want)
+	}
+
+	if _, err := client.BatchEmbed([]string{"a", "fail"}); err == nil || !strings.Contains(err.Error(), "Model not loaded") {
+		t.Errorf("BatchEmbed error = %v, want the server's error", err)
+	}
+}
+
+func TestPyClientShutdownAndClose(t *testing.T) {
+	server := newFakeServer(t)
+	client := NewPyClient(server.port())
+
+	// The client didn't start the server, so Close leaves it alone.
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if want := []string{"shutdown"}; !reflect.DeepEqual(server.commands, want) {
+		t.Errorf("server received %v, want %v", server.commands, want)
+	}
+}
+
+func TestPyClientNoServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	if _, err := NewPyClient(port).Embed("apple"); err == nil {
+		t.Error("expected an error with no server listening")
+	}
+}
+
+func TestServerOptionsDefaults(t *testing.T) {
+	got := ServerOptions{Dir: "py"}.withDefaults()
+	if got.Command != "uv" || !reflect.DeepEqual(got.Args, []string{"run", "main.py"}) || got.Dir != "py" {
+		t.Errorf("defaults = %+v, want uv run main.py in py", got)
+	}
+	if got.Stdout != os.Stdout || got.Stderr != os.Stderr {
+		t.Error("output should default to this process's stdout and stderr")
+	}
+
+	// A custom command does not inherit the uv arguments.
+	got = ServerOptions{Command: "python3"}.withDefaults()
+	if got.Args != nil {
+		t.Errorf("args for a custom command = %v, want none", got.Args)
+	}
+}
+
+func TestStartServerOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	cmd, err := startServer(ServerOptions{
+		Command: "/bin/sh",
+		Args:    []string{"-c", "pwd; echo oops >&2"},
+		Dir:     dir,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantDir, _ := filepath.EvalSymlinks(dir)
+	if got, _ := filepath.EvalSymlinks(strings.TrimSpace(stdout.String())); got != wantDir {
+		t.Errorf("server ran in %q, want %q", got, wantDir)
+	}
+	if stderr.String() != "oops\n" {
+		t.Errorf("stderr = %q, want %q", stderr.String(), "oops\n")
+	}
+}
@@ -0,0 +1,121 @@
+package pyclient
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// serverLauncher starts the Python server unless one is already listening.
+// Instances on the same machine serialise on a lock file, so when several
+// start at once only the first spawns the server and the others wait for it
+// to become ready.
+type serverLauncher struct {
+	addr     string
+	lockPath string
+	running  func() bool
+	start    func() (*exec.Cmd, error)
+	timeout  time.Duration
+	// Readiness is polled every interval, doubling up to maxInterval.
+	interval    time.Duration
+	maxInterval time.Duration
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+// DefaultStartTimeout is how long the launcher waits for a server it
+// started to accept connections.
+const DefaultStartTimeout = 30 * time.Second
+
+// StartTimeoutError is returned when a started server does not become ready
+// within the start timeout.
+type StartTimeoutError struct {
+	Addr    string
+	Timeout time.Duration
+}
+
+func (e *StartTimeoutError) Error() string {
+	return fmt.Sprintf("server at %s failed to start within %v", e.Addr, e.Timeout)
+}
+
+func newServerLauncher(opts ServerOptions, port string) *serverLauncher {
+	addr := net.JoinHostPort("localhost", port)
+	timeout := opts.StartTimeout
+	if timeout <= 0 {
+		timeout = DefaultStartTimeout
+	}
+	return &serverLauncher{
+		addr:        addr,
+		lockPath:    filepath.Join(os.TempDir(), "onnx-py-server-"+port+".lock"),
+		running:     func() bool { return isServerRunning(addr) },
+		start:       func() (*exec.Cmd, error) { return startServer(opts) },
+		timeout:     timeout,
+		interval:    50 * time.Millisecond,
+		maxInterval: 1 * time.Second,
+	}
+}
+
+// ensure makes sure a server is running. It returns the server process if
+// this instance started it, or nil if another one already had.
+func (l *serverLauncher) ensure() (*exec.Cmd, error) {
+	if l.running() {
+		return nil, nil
+	}
+
+	unlock, err := lockFile(l.lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %v", l.lockPath, err)
+	}
+	defer unlock()
+
+	// Another instance may have started the server while we waited for the
+	// lock; it releases the lock only once the server is ready.
+	if l.running() {
+		return nil, nil
+	}
+
+	cmd, err := l.start()
+	if err != nil {
+		return nil, fmt.Errorf("error starting server: %v", err)
+	}
+	l.mu.Lock()
+	l.cmd = cmd
+	l.mu.Unlock()
+
+	if l.waitReady() {
+		return cmd, nil
+	}
+
+	gracefulShutdown(l.addr, cmd)
+	return nil, &StartTimeoutError{Addr: l.addr, Timeout: l.timeout}
+}
+
+// waitReady polls l.running with exponential backoff until it reports true
+// or l.timeout passes.
+func (l *serverLauncher) waitReady() bool {
+	deadline := time.Now().Add(l.timeout)
+	wait := l.interval
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false
+		}
+		time.Sleep(min(wait, remaining))
+		if l.running() {
+			return true
+		}
+		wait = min(2*wait, l.maxInterval)
+	}
+}
+
+// started returns the server process this launcher started, if any.
+func (l *serverLauncher) started() *exec.Cmd {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cmd
+}
@@ -0,0 +1,114 @@
+package pyclient
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentLaunchersStartOneServer(t *testing.T) {
+	var up atomic.Bool
+	var spawned atomic.Int32
+
+	newLauncher := func() *serverLauncher {
+		return &serverLauncher{
+			lockPath: filepath.Join(t.TempDir(), "server.lock"),
+			running:  up.Load,
+			start: func() (*exec.Cmd, error) {
+				spawned.Add(1)
+				// Simulate the model taking a while to load.
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					up.Store(true)
+				}()
+				return &exec.Cmd{}, nil
+			},
+			timeout:     5 * time.Second,
+			interval:    5 * time.Millisecond,
+			maxInterval: 20 * time.Millisecond,
+		}
+	}
+
+	a, b := newLauncher(), newLauncher()
+	b.lockPath = a.lockPath
+
+	var wg sync.WaitGroup
+	cmds := make([]*exec.Cmd, 2)
+	for i, l := range []*serverLauncher{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd, err := l.ensure()
+			if err != nil {
+				t.Errorf("launcher %d: %v", i, err)
+			}
+			cmds[i] = cmd
+		}()
+	}
+	wg.Wait()
+
+	if n := spawned.Load(); n != 1 {
+		t.Fatalf("spawned %d servers, want 1", n)
+	}
+	if (cmds[0] == nil) == (cmds[1] == nil) {
+		t.Errorf("exactly one launcher should own the server, got %v and %v", cmds[0], cmds[1])
+	}
+}
+
+func TestWaitReadyBacksOff(t *testing.T) {
+	var polls []time.Time
+	l := &serverLauncher{
+		running: func() bool {
+			polls = append(polls, time.Now())
+			return len(polls) == 5
+		},
+		timeout:     5 * time.Second,
+		interval:    2 * time.Millisecond,
+		maxInterval: 8 * time.Millisecond,
+	}
+
+	if !l.waitReady() {
+		t.Fatal("waitReady gave up before the server was ready")
+	}
+	if len(polls) != 5 {
+		t.Fatalf("polled %d times, want 5", len(polls))
+	}
+	// The waits are 2, 4, 8, 8ms: the last gap is capped at maxInterval.
+	if gap := polls[4].Sub(polls[3]); gap < 8*time.Millisecond {
+		t.Errorf("last gap = %v, want at least 8ms", gap)
+	}
+}
+
+func TestStartTimeout(t *testing.T) {
+	l := &serverLauncher{
+		addr:     "localhost:0",
+		lockPath: filepath.Join(t.TempDir(), "server.lock"),
+		running:  func() bool { return false },
+		start: func() (*exec.Cmd, error) {
+			// Any short-lived process will do; this test binary runs no tests.
+			cmd := exec.Command(os.Args[0], "-test.run=^$")
+			return cmd, cmd.Start()
+		},
+		timeout:     30 * time.Millisecond,
+		interval:    5 * time.Millisecond,
+		maxInterval: 10 * time.Millisecond,
+	}
+
+	begin := time.Now()
+	cmd, err := l.ensure()
+	var timeoutErr *StartTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("ensure() = %v, %v; want a *StartTimeoutError", cmd, err)
+	}
+	if timeoutErr.Timeout != l.timeout || timeoutErr.Addr != l.addr {
+		t.Errorf("error = %+v", timeoutErr)
+	}
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("ensure took %v with a 30ms timeout", elapsed)
+	}
+}
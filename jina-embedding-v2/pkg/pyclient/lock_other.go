@@ -0,0 +1,9 @@
+//go:build !unix
+
+package pyclient
+
+// lockFile is a no-op where flock is unavailable, so concurrent first starts
+// are not serialised there.
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
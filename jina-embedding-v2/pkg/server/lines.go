@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+)
+
+// DefaultLineBatchSize is the number of lines ServeLines embeds per
+// EmbedBatchContext call when batchSize is zero or less.
+const DefaultLineBatchSize = 32
+
+type lineEmbedding struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// ServeLines embeds every line of r, in batches of batchSize lines, and
+// writes one {"index": n, "embedding": [...]} object per line to w, in input
+// order, until r is exhausted. Lines may be arbitrarily long; a trailing
+// "\r" is removed and an empty line still produces an embedding, so output
+// line n always belongs to input line n.
+//
+// At most one batch is held in memory. Each batch is flushed to w before the
+// next is read, so a slow consumer of w slows down reading from r rather
+// than letting output pile up. Lines longer than m.MaxLength() are an error
+// unless m was created with embedding.WithTruncation.
+func ServeLines(r io.Reader, w io.Writer, m *embedding.Model, batchSize int) error {
+	return serveLines(r, w, m, batchSize)
+}
+
+func serveLines(r io.Reader, w io.Writer, e embedder, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = DefaultLineBatchSize
+	}
+
+	reader := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+
+	index := 0
+	batch := make([]string, 0, batchSize)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read line %d: %w", index+len(batch)+1, err)
+		}
+		eof := err != nil
+		// ReadString returns "" at EOF after a final newline: that is not a
+		// line of its own.
+		if !eof || line != "" {
+			batch = append(batch, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		}
+
+		if len(batch) == batchSize || (eof && len(batch) > 0) {
+			if err := embedLines(e, enc, index, batch); err != nil {
+				return err
+			}
+			if err := out.Flush(); err != nil {
+				return fmt.Errorf("failed to write embeddings: %w", err)
+			}
+			index += len(batch)
+			batch = batch[:0]
+		}
+
+		if eof {
+			return nil
+		}
+	}
+}
+
+// embedLines embeds batch, whose first line has index first, and encodes
+// one lineEmbedding per line.
+func embedLines(e embedder, enc *json.Encoder, first int, batch []string) error {
+	embeddings, _, err := e.EmbedBatchContext(context.Background(), batch)
+	var tooLong *embedding.InputTooLongError
+	if errors.As(err, &tooLong) {
+		return fmt.Errorf("line %d is %d tokens long, exceeding model_max_length of %d", first+tooLong.Index+1, tooLong.Tokens, tooLong.MaxLength)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to embed lines %d-%d: %w", first+1, first+len(batch), err)
+	}
+	for i, emb := range embeddings {
+		if err := enc.Encode(lineEmbedding{Index: first + i, Embedding: emb}); err != nil {
+			return fmt.Errorf("failed to write embeddings: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServeLines(t *testing.T) {
+	long := strings.Repeat("x", 200_000) // longer than bufio.Scanner's default limit
+	input := "a\r\nbb\n\nccc\n" + long + "\ndddd"
+	want := []int{1, 2, 0, 3, len(long), 4}
+
+	for _, batchSize := range []int{1, 2, 0} {
+		var out strings.Builder
+		if err := serveLines(strings.NewReader(input), &out, fakeEmbedder{}, batchSize); err != nil {
+			t.Fatalf("batch size %d: %v", batchSize, err)
+		}
+
+		scanner := bufio.NewScanner(strings.NewReader(out.String()))
+		n := 0
+		for ; scanner.Scan(); n++ {
+			var got lineEmbedding
+			if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+				t.Fatalf("batch size %d: invalid NDJSON line %q: %v", batchSize, scanner.Text(), err)
+			}
+			if n >= len(want) {
+				continue
+			}
+			if got.Index != n || len(got.Embedding) != 1 || int(got.Embedding[0]) != want[n] {
+				t.Errorf("batch size %d: line %d = %+v, want index %d with embedding [%d]", batchSize, n, got, n, want[n])
+			}
+		}
+		if n != len(want) {
+			t.Errorf("batch size %d: got %d output lines, want %d", batchSize, n, len(want))
+		}
+	}
+}
+
+func TestServeLinesTrailingNewline(t *testing.T) {
+	var out strings.Builder
+	if err := serveLines(strings.NewReader("a\nb\n"), &out, fakeEmbedder{}, 8); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "\n"); n != 2 {
+		t.Errorf("got %d output lines for 2 input lines", n)
+	}
+}
+
+func TestServeLinesErrors(t *testing.T) {
+	var out strings.Builder
+	err := serveLines(strings.NewReader("ab\nabcdef\n"), &out, fakeEmbedder{maxLength: 4}, 8)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error naming line 2, got %v", err)
+	}
+
+	boom := errors.New("boom")
+	err = serveLines(strings.NewReader("ab\n"), &out, fakeEmbedder{err: boom}, 8)
+	if !errors.Is(err, boom) {
+		t.Errorf("expected the embedder error, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// openAIRequest mirrors the body of OpenAI's POST /v1/embeddings. Input is
+// either a single string or an array of strings.
+type openAIRequest struct {
+	Input json.RawMessage `json:"input"`
+	Model string          `json:"model"`
+}
+
+type openAIEmbedding struct {
+	Object    string    `json:"object"`
+	Embedding []float32 `json:"embedding"`
+	Index     int       `json:"index"`
+}
+
+type openAIUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+type openAIResponse struct {
+	Object string            `json:"object"`
+	Data   []openAIEmbedding `json:"data"`
+	Model  string            `json:"model"`
+	Usage  openAIUsage       `json:"usage"`
+}
+
+// parseOpenAIInput accepts the string and []string forms of "input".
+func parseOpenAIInput(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+	if len(many) == 0 {
+		return nil, fmt.Errorf("input must not be empty")
+	}
+	return many, nil
+}
+
+// handleOpenAIEmbeddings serves POST /v1/embeddings in the shape of OpenAI's
+// embeddings API, so existing OpenAI clients can point at this server. The
+// requested model name is echoed back; it selects the model when serving a
+// Registry and is ignored otherwise.
+func handleOpenAIEmbeddings(w http.ResponseWriter, r *http.Request, lookup modelLookup) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req openAIRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	texts, err := parseOpenAIInput(req.Input)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	e, ok := lookupModel(w, lookup, req.Model)
+	if !ok {
+		return
+	}
+
+	embeddings, tokenCount, err := e.EmbedBatchContext(r.Context(), texts)
+	if err != nil {
+		writeEmbedError(w, err)
+		return
+	}
+
+	resp := openAIResponse{
+		Object: "list",
+		Data:   make([]openAIEmbedding, len(embeddings)),
+		Model:  req.Model,
+		Usage:  openAIUsage{PromptTokens: tokenCount, TotalTokens: tokenCount},
+	}
+	for i, emb := range embeddings {
+		resp.Data[i] = openAIEmbedding{Object: "embedding", Embedding: emb, Index: i}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
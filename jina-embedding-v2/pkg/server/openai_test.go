@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIEmbeddings(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantData   int
+		wantTokens int
+	}{
+		{"string input", `{"input":"hello","model":"jina"}`, 1, 5},
+		{"array input", `{"input":["a","bcd"],"model":"jina"}`, 2, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			newHandler(fakeEmbedder{}).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+
+			var got openAIResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Object != "list" || got.Model != "jina" {
+				t.Errorf("object = %q, model = %q", got.Object, got.Model)
+			}
+			if len(got.Data) != tt.wantData {
+				t.Fatalf("got %d embeddings, want %d", len(got.Data), tt.wantData)
+			}
+			for i, d := range got.Data {
+				if d.Index != i || d.Object != "embedding" || len(d.Embedding) == 0 {
+					t.Errorf("data[%d] = %+v", i, d)
+				}
+			}
+			if got.Usage.PromptTokens != tt.wantTokens || got.Usage.TotalTokens != tt.wantTokens {
+				t.Errorf("usage = %+v, want %d tokens", got.Usage, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestOpenAIEmbeddingsBadInput(t *testing.T) {
+	for _, body := range []string{`{}`, `{"input":[]}`, `{"input":42}`, `{"input":[1,2]}`} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		newHandler(fakeEmbedder{}).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("body %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
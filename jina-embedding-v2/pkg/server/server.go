@@ -0,0 +1,193 @@
+// Package server exposes an embedding.Model over HTTP.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
+)
+
+// embedder is the subset of *embedding.Model the handlers use.
+type embedder interface {
+	EmbedBatchContext(ctx context.Context, texts []string) ([][]float32, int, error)
+}
+
+// maxBodyBytes bounds the size of a request body.
+const maxBodyBytes = 16 << 20
+
+type embedRequest struct {
+	Texts []string `json:"texts"`
+	// Model selects the model when serving a Registry.
+	Model string `json:"model,omitempty"`
+}
+
+type embedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	TokenCount int         `json:"token_count"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Serve listens on addr and serves m until the listener fails:
+//
+//	POST /embed    {"texts": [...]} -> {"embeddings": [[...]], "token_count": n}
+//	POST /v1/embeddings  OpenAI-compatible embeddings API
+//	GET  /healthz  200 OK
+//
+// Requests are embedded with m.EmbedBatchContext, which stops once the
+// client goes away; the model serialises inference itself, so concurrent
+// requests are safe. Inputs longer than m.MaxLength() are rejected with 400
+// unless m was created with embedding.WithTruncation, and bodies over 16 MiB
+// with 413.
+func Serve(addr string, m *embedding.Model) error {
+	return http.ListenAndServe(addr, newHandler(m))
+}
+
+// Handler returns the HTTP handler Serve uses, for mounting under an
+// existing server.
+func Handler(m *embedding.Model) http.Handler {
+	return newHandler(m)
+}
+
+// ServeRegistry is Serve for several models: each request is embedded by
+// the model of r named in its "model" field. Requests naming no model, or
+// one r does not hold, are rejected with 404.
+func ServeRegistry(addr string, r *embedding.Registry) error {
+	return http.ListenAndServe(addr, RegistryHandler(r))
+}
+
+// RegistryHandler returns the HTTP handler ServeRegistry uses, for mounting
+// under an existing server.
+func RegistryHandler(r *embedding.Registry) http.Handler {
+	return newRoutingHandler(func(name string) (embedder, bool) {
+		m, ok := r.Get(name)
+		if !ok {
+			return nil, false
+		}
+		return m, true
+	})
+}
+
+// modelLookup returns the embedder serving the model a request names.
+type modelLookup func(name string) (embedder, bool)
+
+// newHandler serves e whatever model a request names.
+func newHandler(e embedder) http.Handler {
+	return newRoutingHandler(func(string) (embedder, bool) { return e, true })
+}
+
+func newRoutingHandler(lookup modelLookup) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/embed", func(w http.ResponseWriter, r *http.Request) {
+		handleEmbed(w, r, lookup)
+	})
+	mux.HandleFunc("/v1/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		handleOpenAIEmbeddings(w, r, lookup)
+	})
+	mux.HandleFunc("/healthz", handleHealthz)
+	return mux
+}
+
+// lookupModel writes a 404 and returns false unless lookup knows name.
+func lookupModel(w http.ResponseWriter, lookup modelLookup, name string) (embedder, bool) {
+	e, ok := lookup(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown model %q", name))
+	}
+	return e, ok
+}
+
+func handleEmbed(w http.ResponseWriter, r *http.Request, lookup modelLookup) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req embedRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if len(req.Texts) == 0 {
+		writeError(w, http.StatusBadRequest, "texts must not be empty")
+		return
+	}
+	e, ok := lookupModel(w, lookup, req.Model)
+	if !ok {
+		return
+	}
+
+	embeddings, tokenCount, err := e.EmbedBatchContext(r.Context(), req.Texts)
+	if err != nil {
+		writeEmbedError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, embedResponse{Embeddings: embeddings, TokenCount: tokenCount})
+}
+
+// decodeBody decodes the JSON body of r, at most maxBodyBytes of it, into v.
+// On failure it writes a 413 or 400 and returns false.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+	} else {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+	}
+	return false
+}
+
+// writeEmbedError writes the response for an error from EmbedBatchContext:
+// 400 for inputs the model cannot take, 503 when the request could not be
+// finished now but may succeed if retried, and 500 otherwise.
+func writeEmbedError(w http.ResponseWriter, err error) {
+	var tooLong *embedding.InputTooLongError
+	var partial *embedding.PartialBatchError
+	switch {
+	case errors.As(err, &tooLong),
+		errors.Is(err, embedding.ErrEmptyInput),
+		errors.Is(err, embedding.ErrInvalidTask),
+		errors.Is(err, tokenizer.ErrUnknownTask):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &partial):
+		writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("timed out with %d inputs not embedded; retry with fewer inputs", len(partial.Unprocessed)))
+	case errors.Is(err, embedding.ErrClosed),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
+)
+
+// fakeEmbedder embeds each text as [len(text)] and counts one token per byte.
+// Texts longer than maxLength are rejected like embedding.Model does, or,
+// with truncate set, counted as maxLength tokens like a model created with
+// embedding.WithTruncation.
+type fakeEmbedder struct {
+	err       error
+	maxLength int
+	truncate  bool
+	ctx       *context.Context
+}
+
+func (f fakeEmbedder) EmbedBatchContext(ctx context.Context, texts []string) ([][]float32, int, error) {
+	if f.ctx != nil {
+		*f.ctx = ctx
+	}
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	out := make([][]float32, len(texts))
+	tokens := 0
+	for i, text := range texts {
+		n := len(text)
+		if f.maxLength > 0 && n > f.maxLength {
+			if !f.truncate {
+				return nil, 0, &embedding.InputTooLongError{Index: i, Tokens: n, MaxLength: f.maxLength}
+			}
+			n = f.maxLength
+		}
+		out[i] = []float32{float32(len(text))}
+		tokens += n
+	}
+	return out, tokens, nil
+}
+
+func TestEmbed(t *testing.T) {
+	srv := httptest.NewServer(newHandler(fakeEmbedder{}))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/embed", "application/json", strings.NewReader(`{"texts":["ab","cde"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var got embedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float32{{2}, {3}}; !reflect.DeepEqual(got.Embeddings, want) {
+		t.Errorf("embeddings = %v, want %v", got.Embeddings, want)
+	}
+	if got.TokenCount != 5 {
+		t.Errorf("token_count = %d, want 5", got.TokenCount)
+	}
+}
+
+func TestEmbedErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		embedder fakeEmbedder
+		method   string
+		body     string
+		want     int
+	}{
+		{"wrong method", fakeEmbedder{}, http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid json", fakeEmbedder{}, http.MethodPost, "{", http.StatusBadRequest},
+		{"no texts", fakeEmbedder{}, http.MethodPost, `{"texts":[]}`, http.StatusBadRequest},
+		{"body too large", fakeEmbedder{}, http.MethodPost, `{"texts":["` + strings.Repeat("a", maxBodyBytes) + `"]}`, http.StatusRequestEntityTooLarge},
+		{"model error", fakeEmbedder{err: errors.New("boom")}, http.MethodPost, `{"texts":["a"]}`, http.StatusInternalServerError},
+		{"empty input", fakeEmbedder{err: fmt.Errorf("input 0: %w", embedding.ErrEmptyInput)}, http.MethodPost, `{"texts":[""]}`, http.StatusBadRequest},
+		{"invalid task", fakeEmbedder{err: fmt.Errorf("%w: 9", embedding.ErrInvalidTask)}, http.MethodPost, `{"texts":["a"]}`, http.StatusBadRequest},
+		{"unknown task", fakeEmbedder{err: fmt.Errorf("resolve: %w", tokenizer.ErrUnknownTask)}, http.MethodPost, `{"texts":["a"]}`, http.StatusBadRequest},
+		{"soft timeout", fakeEmbedder{err: &embedding.PartialBatchError{Unprocessed: []int{1}}}, http.MethodPost, `{"texts":["a","b"]}`, http.StatusServiceUnavailable},
+		{"model closed", fakeEmbedder{err: embedding.ErrClosed}, http.MethodPost, `{"texts":["a"]}`, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/embed", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			newHandler(tt.embedder).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbedPassesRequestContext(t *testing.T) {
+	var got context.Context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, path := range []string{"/embed", "/v1/embeddings"} {
+		got = nil
+		body := `{"texts":["a"],"input":"a"}`
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		newHandler(fakeEmbedder{ctx: &got}).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200: %s", path, rec.Code, rec.Body)
+		}
+		if got != ctx {
+			t.Errorf("%s: embedder did not get the request context", path)
+		}
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	newHandler(fakeEmbedder{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+}
+
+func TestEmbedOverMaxLength(t *testing.T) {
+	tests := []struct {
+		name       string
+		embedder   fakeEmbedder
+		wantStatus int
+		wantTokens int
+	}{
+		{"reject", fakeEmbedder{maxLength: 4}, http.StatusBadRequest, 0},
+		{"truncate", fakeEmbedder{maxLength: 4, truncate: true}, http.StatusOK, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"texts":["ab","abcdefgh"]}`
+			req := httptest.NewRequest(http.MethodPost, "/embed", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			newHandler(tt.embedder).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				var got errorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(got.Error, "8 tokens") || !strings.Contains(got.Error, "of 4") {
+					t.Errorf("error %q should report actual and allowed token counts", got.Error)
+				}
+				return
+			}
+
+			var got embedResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.TokenCount != tt.wantTokens {
+				t.Errorf("token_count = %d, want %d", got.TokenCount, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestRegistryHandler(t *testing.T) {
+	var registry embedding.Registry
+	defer registry.CloseAll()
+	for name, dim := range map[string]int{"small": 4, "base": 8} {
+		m, err := embedding.NewFakeModel(embedding.FakeTokenizer{}, dim)
+		if err != nil {
+			t.Fatal(err)
+		}
+		registry.Register(name, m)
+	}
+	handler := RegistryHandler(&registry)
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantDim    int
+	}{
+		{"embed routes by model", "/embed", `{"texts":["a cat"],"model":"small"}`, http.StatusOK, 4},
+		{"openai routes by model", "/v1/embeddings", `{"input":"a cat","model":"base"}`, http.StatusOK, 8},
+		{"unknown model", "/embed", `{"texts":["a cat"],"model":"large"}`, http.StatusNotFound, 0},
+		{"no model", "/v1/embeddings", `{"input":"a cat"}`, http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got struct {
+				Embeddings [][]float32 `json:"embeddings"`
+				Data       []struct {
+					Embedding []float32 `json:"embedding"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			emb := got.Embeddings
+			for _, d := range got.Data {
+				emb = append(emb, d.Embedding)
+			}
+			if len(emb) != 1 || len(emb[0]) != tt.wantDim {
+				t.Errorf("got embeddings %v, want one of dimension %d", emb, tt.wantDim)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package tokenizer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// LoadFromArchive loads tokenizer.json and config.json from a .tar.gz, .tgz or
+// .zip bundle without extracting it to disk. The files may sit at any depth in
+// the archive; the first entry with a matching base name is used.
+func (t *SentencePieceTokenizer) LoadFromArchive(archivePath string) error {
+	var files map[string][]byte
+	var err error
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		files, err = readZipFiles(archivePath)
+	} else {
+		files, err = readTarGzFiles(archivePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	tokenizerData, ok := files["tokenizer.json"]
+	if !ok {
+		return fmt.Errorf("tokenizer.json not found in archive %s", archivePath)
+	}
+
+	configData, ok := files["config.json"]
+	if !ok {
+		return fmt.Errorf("config.json not found in archive %s", archivePath)
+	}
+
+	t.name = archivePath
+	return t.load(tokenizerData, configData)
+}
+
+func isArchiveMember(name string) bool {
+	base := path.Base(name)
+	return base == "tokenizer.json" || base == "config.json"
+}
+
+func readTarGzFiles(archivePath string) (map[string][]byte, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip stream: %v", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %v", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg || !isArchiveMember(hdr.Name) {
+			continue
+		}
+		base := path.Base(hdr.Name)
+		if _, seen := files[base]; seen {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive: %v", hdr.Name, err)
+		}
+		files[base] = data
+	}
+
+	return files, nil
+}
+
+func readZipFiles(archivePath string) (map[string][]byte, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string][]byte)
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() || !isArchiveMember(zf.Name) {
+			continue
+		}
+		base := path.Base(zf.Name)
+		if _, seen := files[base]; seen {
+			continue
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in archive: %v", zf.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive: %v", zf.Name, err)
+		}
+		files[base] = data
+	}
+
+	return files, nil
+}
@@ -0,0 +1,113 @@
+package tokenizer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTarGz(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "tokenizer.tar.gz")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func writeZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "tokenizer.zip")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestLoadFromArchiveTarGz(t *testing.T) {
+	archivePath := writeTarGz(t, map[string]string{
+		"jina/tokenizer.json": testTokenizerJSON,
+		"jina/config.json":    testConfigJSON,
+	})
+
+	tok := NewSentencePieceTokenizer()
+	if err := tok.LoadFromArchive(archivePath); err != nil {
+		t.Fatalf("LoadFromArchive: %v", err)
+	}
+
+	want := newTestTokenizer(t)
+	if !reflect.DeepEqual(tok.vocab, want.vocab) {
+		t.Errorf("vocab mismatch: got %v, want %v", tok.vocab, want.vocab)
+	}
+	if !reflect.DeepEqual(tok.config, want.config) {
+		t.Errorf("config mismatch: got %v, want %v", tok.config, want.config)
+	}
+}
+
+func TestLoadFromArchiveZip(t *testing.T) {
+	archivePath := writeZip(t, map[string]string{
+		"tokenizer.json": testTokenizerJSON,
+		"config.json":    testConfigJSON,
+	})
+
+	tok := NewSentencePieceTokenizer()
+	if err := tok.LoadFromArchive(archivePath); err != nil {
+		t.Fatalf("LoadFromArchive: %v", err)
+	}
+	if len(tok.vocab) == 0 {
+		t.Error("expected vocab to be loaded")
+	}
+}
+
+func TestLoadFromArchiveMissingFile(t *testing.T) {
+	archivePath := writeTarGz(t, map[string]string{
+		"tokenizer.json": testTokenizerJSON,
+	})
+
+	err := NewSentencePieceTokenizer().LoadFromArchive(archivePath)
+	if err == nil || !strings.Contains(err.Error(), "config.json not found") {
+		t.Fatalf("expected missing config.json error, got %v", err)
+	}
+}
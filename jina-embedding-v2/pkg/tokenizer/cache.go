@@ -0,0 +1,106 @@
+package tokenizer
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is a bounded LRU cache of Encode results. A single Cache can be
+// shared by any number of tokenizers; entries are keyed by the model the
+// tokenizer was loaded from, so tokenizers of the same model reuse each
+// other's results while different models never collide.
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[cacheKey]*list.Element
+	order    *list.List
+}
+
+type cacheKey struct {
+	model string
+	text  string
+}
+
+type cacheEntry struct {
+	key           cacheKey
+	inputIds      []int64
+	attentionMask []int64
+}
+
+// NewCache returns a cache holding at most capacity encodings.
+func NewCache(capacity int) *Cache {
+	return &Cache{
+		capacity: capacity,
+		entries:  make(map[cacheKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Len returns the number of cached encodings.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *Cache) get(model, text string) ([]int64, []int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[cacheKey{model, text}]
+	if !ok {
+		return nil, nil, false
+	}
+	c.order.MoveToFront(elem)
+
+	entry := elem.Value.(*cacheEntry)
+	return append([]int64(nil), entry.inputIds...), append([]int64(nil), entry.attentionMask...), true
+}
+
+func (c *Cache) put(model, text string, inputIds, attentionMask []int64) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey{model, text}
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{
+		key:           key,
+		inputIds:      append([]int64(nil), inputIds...),
+		attentionMask: append([]int64(nil), attentionMask...),
+	})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// removeModel drops every encoding of model.
+func (c *Cache) removeModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		if key.model == model {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// SetCache makes Encode consult and populate c. Pass nil to disable caching.
+// The tokenizer must have been loaded first, since the cache key includes the
+// model it was loaded from.
+func (t *SentencePieceTokenizer) SetCache(c *Cache) {
+	t.cache = c
+}
@@ -0,0 +1,84 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCacheSharedAcrossTokenizers(t *testing.T) {
+	cache := NewCache(16)
+
+	first := newTestTokenizer(t)
+	first.name = "jinaai/jina-embeddings-v2-base-en"
+	first.SetCache(cache)
+
+	second := newTestTokenizer(t)
+	second.name = "jinaai/jina-embeddings-v2-base-en"
+	second.SetCache(cache)
+
+	ids, mask := first.Encode("this is an apple")
+	if cache.Len() != 1 {
+		t.Fatalf("cache has %d entries after first encode, want 1", cache.Len())
+	}
+
+	// Make the second tokenizer unable to tokenize on its own; a correct
+	// result can then only come from the shared cache.
+	second.vocab = map[string]int{}
+	gotIds, gotMask := second.Encode("this is an apple")
+	if !reflect.DeepEqual(gotIds, ids) || !reflect.DeepEqual(gotMask, mask) {
+		t.Errorf("second tokenizer did not use the shared entry: got %v, want %v", gotIds, ids)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("cache has %d entries, want 1", cache.Len())
+	}
+}
+
+func TestCacheKeyedByModel(t *testing.T) {
+	cache := NewCache(16)
+
+	first := newTestTokenizer(t)
+	first.name = "model-a"
+	first.SetCache(cache)
+
+	second := newTestTokenizer(t)
+	second.name = "model-b"
+	second.SetCache(cache)
+
+	first.Encode("this is an apple")
+	second.Encode("this is an apple")
+
+	if cache.Len() != 2 {
+		t.Errorf("cache has %d entries, want 2", cache.Len())
+	}
+}
+
+func TestCacheHitForMixedCaseText(t *testing.T) {
+	cache := NewCache(16)
+	tok := newTestTokenizer(t)
+	tok.SetCache(cache)
+
+	want, _ := tok.Encode("This is an Apple")
+	tok.vocab = map[string]int{}
+	got, _ := tok.Encode("This is an Apple")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("second Encode missed the cache: got %v, want %v", got, want)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewCache(2)
+	cache.put("m", "a", []int64{1}, []int64{1})
+	cache.put("m", "b", []int64{2}, []int64{1})
+	cache.get("m", "a")
+	cache.put("m", "c", []int64{3}, []int64{1})
+
+	if _, _, ok := cache.get("m", "b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if _, _, ok := cache.get("m", "a"); !ok {
+		t.Error("expected recently used entry to be kept")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("cache has %d entries, want 2", cache.Len())
+	}
+}
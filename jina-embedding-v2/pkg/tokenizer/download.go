@@ -0,0 +1,239 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheDirEnv names the environment variable that sets the directory
+// LoadFromHuggingFace caches downloads in. HFHomeEnv is consulted next, with
+// downloads kept in its "tokenizers" subdirectory.
+const (
+	CacheDirEnv = "TOKENIZER_CACHE"
+	HFHomeEnv   = "HF_HOME"
+)
+
+// HFTokenEnv names the environment variable holding the HuggingFace access
+// token sent with downloads, needed for gated and private repositories.
+const HFTokenEnv = "HF_TOKEN"
+
+// EndpointEnv names the environment variable that replaces
+// https://huggingface.co as the download host, e.g. with a mirror such as
+// https://hf-mirror.com. It has the same meaning for the huggingface_hub
+// Python library.
+const EndpointEnv = "HF_ENDPOINT"
+
+const defaultEndpoint = "https://huggingface.co"
+
+// Downloads are retried after 429 and 5xx responses and network errors,
+// up to downloadAttempts tries in all. The wait starts at
+// downloadBackoff and doubles, unless the server sends Retry-After, and is
+// capped at maxDownloadBackoff.
+const (
+	downloadAttempts   = 5
+	downloadBackoff    = 500 * time.Millisecond
+	maxDownloadBackoff = 30 * time.Second
+)
+
+// WithHFToken sets the HuggingFace access token LoadFromHuggingFace sends
+// as a bearer token. It overrides $HF_TOKEN.
+func WithHFToken(token string) Option {
+	return func(t *SentencePieceTokenizer) {
+		t.hfToken = token
+	}
+}
+
+// authToken returns the token from WithHFToken, else $HF_TOKEN.
+func (t *SentencePieceTokenizer) authToken() string {
+	if t.hfToken != "" {
+		return t.hfToken
+	}
+	return os.Getenv(HFTokenEnv)
+}
+
+// WithCacheDir sets the directory LoadFromHuggingFace caches tokenizer.json
+// and config.json in, one subdirectory per model. It overrides the
+// environment; see cacheRoot.
+func WithCacheDir(dir string) Option {
+	return func(t *SentencePieceTokenizer) {
+		t.cacheDir = dir
+	}
+}
+
+// cacheRoot returns the download cache directory: WithCacheDir, then
+// $TOKENIZER_CACHE, then $HF_HOME/tokenizers, then a directory under
+// os.TempDir().
+func (t *SentencePieceTokenizer) cacheRoot() string {
+	if t.cacheDir != "" {
+		return t.cacheDir
+	}
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv(HFHomeEnv); dir != "" {
+		return filepath.Join(dir, "tokenizers")
+	}
+	return filepath.Join(os.TempDir(), "real_tokenizer_cache")
+}
+
+// WithEndpoint sets the host LoadFromHuggingFace downloads from. It
+// overrides $HF_ENDPOINT.
+func WithEndpoint(url string) Option {
+	return func(t *SentencePieceTokenizer) {
+		t.endpointURL = url
+	}
+}
+
+// endpoint returns WithEndpoint, else $HF_ENDPOINT, else huggingface.co,
+// without a trailing slash.
+func (t *SentencePieceTokenizer) endpoint() string {
+	url := t.endpointURL
+	if url == "" {
+		url = os.Getenv(EndpointEnv)
+	}
+	if url == "" {
+		url = defaultEndpoint
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+// errRetryable wraps download failures worth retrying.
+type errRetryable struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *errRetryable) Error() string { return e.err.Error() }
+func (e *errRetryable) Unwrap() error { return e.err }
+
+// downloadFile fetches url into path, retrying transient failures with
+// exponential backoff.
+func (t *SentencePieceTokenizer) downloadFile(url, path string) error {
+	backoff := t.retryBackoff
+	if backoff == 0 {
+		backoff = downloadBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := t.fetch(url, path)
+		var retryable *errRetryable
+		if err == nil || !errors.As(err, &retryable) || attempt == downloadAttempts {
+			return err
+		}
+
+		wait := backoff
+		if retryable.retryAfter > 0 {
+			wait = retryable.retryAfter
+		}
+		wait = min(wait, maxDownloadBackoff)
+		t.logger.Warn("download failed, retrying", "url", url, "attempt", attempt, "wait", wait, "err", err)
+		time.Sleep(wait)
+		backoff *= 2
+	}
+}
+
+// fetch makes one attempt at downloading url into path.
+func (t *SentencePieceTokenizer) fetch(url, path string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if token := t.authToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return &errRetryable{err: err}
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.logger.Warn("failed to close response body", "url", url, "err", err)
+		}
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		if t.authToken() == "" {
+			return fmt.Errorf("failed to download file: status %d; the repository may be gated, set %s", resp.StatusCode, HFTokenEnv)
+		}
+		return fmt.Errorf("failed to download file: status %d; check that the token has access to the repository", resp.StatusCode)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return &errRetryable{
+			err:        fmt.Errorf("failed to download file: status %d", resp.StatusCode),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("failed to download file: status %d", resp.StatusCode)
+	}
+
+	// Write to a temporary file and rename it into place once complete,
+	// so an interrupted download never leaves a partial file in the cache.
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+
+	_, err = io.Copy(out, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return &errRetryable{err: err}
+	}
+
+	if isJSON(path) {
+		data, err := os.ReadFile(out.Name())
+		if err != nil {
+			return err
+		}
+		if !json.Valid(data) {
+			return &errRetryable{err: fmt.Errorf("failed to download file: %s is not valid JSON", url)}
+		}
+	}
+
+	return os.Rename(out.Name(), path)
+}
+
+// cached reports whether path holds a usable download. A JSON file that
+// doesn't parse, e.g. one truncated by a version of downloadFile that wrote
+// in place, is reported as missing so it is downloaded again.
+func (t *SentencePieceTokenizer) cached(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if isJSON(path) && !json.Valid(data) {
+		t.logger.Warn("cached file is not valid JSON, downloading it again", "path", path)
+		return false
+	}
+	return true
+}
+
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or
+// as an HTTP date. It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,447 @@
+package tokenizer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheRoot(t *testing.T) {
+	tests := []struct {
+		name          string
+		option, cache string
+		hfHome        string
+		want          string
+	}{
+		{"default", "", "", "", filepath.Join(os.TempDir(), "real_tokenizer_cache")},
+		{"hf home", "", "", "/data/hf", filepath.Join("/data/hf", "tokenizers")},
+		{"tokenizer cache", "", "/cache", "/data/hf", "/cache"},
+		{"option", "/opt/tok", "/cache", "/data/hf", "/opt/tok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(CacheDirEnv, tt.cache)
+			t.Setenv(HFHomeEnv, tt.hfHome)
+
+			tok := NewSentencePieceTokenizer(WithCacheDir(tt.option))
+			if got := tok.cacheRoot(); got != tt.want {
+				t.Errorf("cacheRoot() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFromHuggingFaceUsesCache(t *testing.T) {
+	dir := t.TempDir()
+	modelDir := filepath.Join(dir, "jinaai", "test-model")
+	if err := os.MkdirAll(modelDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"tokenizer.json": testTokenizerJSON, "config.json": testConfigJSON} {
+		if err := os.WriteFile(filepath.Join(modelDir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A pre-populated cache is used without touching the network.
+	tok := NewSentencePieceTokenizer(WithCacheDir(dir))
+	if err := tok.LoadFromHuggingFace("jinaai/test-model"); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := tok.Encode("this is an apple"); !reflect.DeepEqual(ids, []int64{0, 4, 5, 6, 7, 2}) {
+		t.Errorf("Encode = %v", ids)
+	}
+}
+
+func TestDownloadFileAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer hf_secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "{}")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		option  string
+		env     string
+		wantErr string
+	}{
+		{"no token", "", "", HFTokenEnv},
+		{"env token", "", "hf_secret", ""},
+		{"option overrides env", "hf_secret", "hf_other", ""},
+		{"wrong token", "hf_wrong", "", "has access"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(HFTokenEnv, tt.env)
+			tok := NewSentencePieceTokenizer(WithHFToken(tt.option))
+
+			err := tok.downloadFile(srv.URL+"/tokenizer.json", filepath.Join(t.TempDir(), "tokenizer.json"))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDownloadFileRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		status    int
+		wantErr   bool
+		wantCalls int32
+	}{
+		{"recovers from 503", 2, http.StatusServiceUnavailable, false, 3},
+		{"recovers from 429", 1, http.StatusTooManyRequests, false, 2},
+		{"gives up", downloadAttempts, http.StatusBadGateway, true, downloadAttempts},
+		{"404 is not retried", 1, http.StatusNotFound, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(calls.Add(1)) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				io.WriteString(w, "{}")
+			}))
+			defer srv.Close()
+
+			tok := NewSentencePieceTokenizer()
+			tok.retryBackoff = time.Millisecond
+
+			path := filepath.Join(t.TempDir(), "config.json")
+			err := tok.downloadFile(srv.URL+"/config.json", path)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", calls.Load(), tt.wantCalls)
+			}
+			if !tt.wantErr {
+				if data, _ := os.ReadFile(path); string(data) != "{}" {
+					t.Errorf("downloaded %q", data)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 4, 16, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	t.Setenv(EndpointEnv, "")
+	if got := NewSentencePieceTokenizer().endpoint(); got != "https://huggingface.co" {
+		t.Errorf("default endpoint = %q", got)
+	}
+
+	t.Setenv(EndpointEnv, "https://hf-mirror.com/")
+	if got := NewSentencePieceTokenizer().endpoint(); got != "https://hf-mirror.com" {
+		t.Errorf("$%s endpoint = %q", EndpointEnv, got)
+	}
+	if got := NewSentencePieceTokenizer(WithEndpoint("http://localhost:9000")).endpoint(); got != "http://localhost:9000" {
+		t.Errorf("WithEndpoint endpoint = %q", got)
+	}
+}
+
+func TestLoadFromHuggingFaceMirror(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jinaai/test-model/resolve/main/tokenizer.json":
+			io.WriteString(w, testTokenizerJSON)
+		case "/jinaai/test-model/resolve/main/config.json":
+			io.WriteString(w, testConfigJSON)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(EndpointEnv, srv.URL)
+
+	tok := NewSentencePieceTokenizer(WithCacheDir(t.TempDir()))
+	if err := tok.LoadFromHuggingFace("jinaai/test-model"); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := tok.Encode("this is an apple"); !reflect.DeepEqual(ids, []int64{0, 4, 5, 6, 7, 2}) {
+		t.Errorf("Encode = %v", ids)
+	}
+}
+
+func TestDownloadFileIsAtomic(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Promise more than is sent, so the client sees a cut-off body.
+		w.Header().Set("Content-Length", "100")
+		io.WriteString(w, `{"model": {"vocab": [`)
+	}))
+	defer srv.Close()
+
+	tok := NewSentencePieceTokenizer()
+	tok.retryBackoff = time.Millisecond
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokenizer.json")
+	if err := tok.downloadFile(srv.URL+"/tokenizer.json", path); err == nil {
+		t.Fatal("expected an error for a truncated download")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("interrupted download left files behind: %v", entries)
+	}
+}
+
+func TestDownloadFileRejectsInvalidJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html>rate limited</html>")
+	}))
+	defer srv.Close()
+
+	tok := NewSentencePieceTokenizer()
+	tok.retryBackoff = time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := tok.downloadFile(srv.URL+"/config.json", path); err == nil {
+		t.Fatal("expected an error for a non-JSON response")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("invalid download was cached: %v", err)
+	}
+}
+
+func TestLoadFromHuggingFaceReplacesCorruptCache(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.WriteString(w, testTokenizerJSON)
+	}))
+	defer srv.Close()
+	t.Setenv(EndpointEnv, srv.URL)
+
+	dir := t.TempDir()
+	modelDir := filepath.Join(dir, "jinaai", "test-model")
+	if err := os.MkdirAll(modelDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A partial tokenizer.json left by an interrupted download.
+	if err := os.WriteFile(filepath.Join(modelDir, "tokenizer.json"), []byte(`{"model": {`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(modelDir, "config.json"), []byte(testConfigJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tok := NewSentencePieceTokenizer(WithCacheDir(dir))
+	if err := tok.LoadFromHuggingFace("jinaai/test-model"); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("downloaded %d files, want only the corrupt tokenizer.json", calls.Load())
+	}
+}
+
+// writeCachedModel puts tokenizer.json and config.json for model into the
+// download cache at dir, as a previous LoadFromHuggingFace would have.
+func writeCachedModel(t *testing.T, dir, model, tokenizerJSON, configJSON string) {
+	t.Helper()
+	modelDir := filepath.Join(dir, model)
+	if err := os.MkdirAll(modelDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"tokenizer.json": tokenizerJSON, "config.json": configJSON} {
+		if err := os.WriteFile(filepath.Join(modelDir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// otherTokenizerJSON is a second model for reload tests: it knows only
+// "▁apple", at a different id, and has no pad token.
+const otherTokenizerJSON = `{
+	"model": {
+		"type": "Unigram",
+		"vocab": [["<s>", 0.0], ["</s>", 0.0], ["<unk>", 0.0], ["▁apple", -1.0]]
+	},
+	"pre_tokenizer": {"type": "Metaspace", "replacement": "▁", "add_prefix_space": true},
+	"added_tokens": [
+		{"id": 0, "content": "<s>", "special": true},
+		{"id": 1, "content": "</s>", "special": true},
+		{"id": 2, "content": "<unk>", "special": true}
+	]
+}`
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	writeCachedModel(t, dir, "jinaai/first", testTokenizerJSON, testConfigJSON)
+	writeCachedModel(t, dir, "jinaai/second", otherTokenizerJSON, `{}`)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	tok := NewSentencePieceTokenizer(WithCacheDir(dir), WithEndpoint(srv.URL))
+	tok.SetCache(NewCache(10))
+	if err := tok.LoadFromHuggingFace("jinaai/first"); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := tok.Encode("this apple")
+
+	want := NewSentencePieceTokenizer(WithCacheDir(dir))
+	if err := want.LoadFromHuggingFace("jinaai/second"); err != nil {
+		t.Fatal(err)
+	}
+	wantIds, _ := want.Encode("this apple")
+
+	if err := tok.Reload("jinaai/second"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := tok.Encode("this apple")
+	if !reflect.DeepEqual(got, wantIds) || reflect.DeepEqual(got, first) {
+		t.Errorf("after Reload, Encode = %v, want %v (first model gave %v)", got, wantIds, first)
+	}
+	// Nothing of the first model is left behind.
+	if _, ok := tok.vocab["▁this"]; ok {
+		t.Error("vocabulary of the first model survived Reload")
+	}
+	if tok.PadID() != -1 || len(tok.Tasks()) != 0 {
+		t.Errorf("PadID() = %d, Tasks() = %v; want the second model's", tok.PadID(), tok.Tasks())
+	}
+
+	// A failed Reload leaves the current model in place.
+	if err := tok.Reload("jinaai/missing"); err == nil {
+		t.Fatal("Reload of a missing model succeeded")
+	}
+	if again, _ := tok.Encode("this apple"); !reflect.DeepEqual(again, wantIds) {
+		t.Errorf("after a failed Reload, Encode = %v, want %v", again, wantIds)
+	}
+}
+
+func TestReloadSameModelDropsCachedEncodings(t *testing.T) {
+	dir := t.TempDir()
+	writeCachedModel(t, dir, "jinaai/model", testTokenizerJSON, testConfigJSON)
+
+	tok := NewSentencePieceTokenizer(WithCacheDir(dir))
+	cache := NewCache(10)
+	tok.SetCache(cache)
+	if err := tok.LoadFromHuggingFace("jinaai/model"); err != nil {
+		t.Fatal(err)
+	}
+	tok.Encode("this apple")
+
+	// The files change under the same name, as after an upstream update.
+	writeCachedModel(t, dir, "jinaai/model", otherTokenizerJSON, `{}`)
+	if err := tok.Reload("jinaai/model"); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("cache holds %d encodings of the old files", cache.Len())
+	}
+	if ids, _ := tok.Encode("apple"); !reflect.DeepEqual(ids, []int64{0, 3, 1}) {
+		t.Errorf("Encode(apple) = %v, want [0 3 1]", ids)
+	}
+}
+
+// TestConcurrentEncodeAndReload hammers the read methods from many
+// goroutines while the model is reloaded back and forth. It is meant for
+// go test -race; without it, it only checks nothing panics and every
+// encoding is one of the two models'.
+func TestConcurrentEncodeAndReload(t *testing.T) {
+	dir := t.TempDir()
+	writeCachedModel(t, dir, "jinaai/first", testTokenizerJSON, testConfigJSON)
+	writeCachedModel(t, dir, "jinaai/second", otherTokenizerJSON, `{}`)
+
+	tok := NewSentencePieceTokenizer(WithCacheDir(dir))
+	tok.SetCache(NewCache(4))
+	if err := tok.LoadFromHuggingFace("jinaai/first"); err != nil {
+		t.Fatal(err)
+	}
+	const text = "this is an apple"
+	firstIds, _ := tok.Encode(text)
+	other := NewSentencePieceTokenizer(WithCacheDir(dir))
+	if err := other.LoadFromHuggingFace("jinaai/second"); err != nil {
+		t.Fatal(err)
+	}
+	secondIds, _ := other.Encode(text)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				ids, _ := tok.Encode(text)
+				if !reflect.DeepEqual(ids, firstIds) && !reflect.DeepEqual(ids, secondIds) {
+					t.Errorf("Encode = %v, a mix of the two models", ids)
+					return
+				}
+				tok.CountTokens(text)
+				tok.EncodeWithOffsets(text)
+				tok.DecodeIds(ids)
+				tok.GetTaskID("retrieval.query")
+				tok.Tasks()
+				tok.PadID()
+				tok.ModelMaxLength()
+				tok.EncodeForTask("retrieval.query", text)
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		model := "jinaai/second"
+		if i%2 == 1 {
+			model = "jinaai/first"
+		}
+		if err := tok.Reload(model); err != nil {
+			t.Error(err)
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
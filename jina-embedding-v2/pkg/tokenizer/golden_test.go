@@ -0,0 +1,49 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// goldenFile holds token ids produced by the HuggingFace tokenizer for the
+// tokenizer.json in testdata/jina-embeddings-v2-base-en, written by
+// py/golden_tokenizer.py.
+type goldenFile struct {
+	Model string `json:"model"`
+	Cases []struct {
+		Text string  `json:"text"`
+		IDs  []int64 `json:"ids"`
+	} `json:"cases"`
+}
+
+func TestEncodeGolden(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "tokenizer_golden.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var golden goldenFile
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("invalid golden file: %v", err)
+	}
+
+	dir := filepath.Join("testdata", "jina-embeddings-v2-base-en")
+	tok := NewSentencePieceTokenizer()
+	if err := tok.LoadFromLocal(filepath.Join(dir, "tokenizer.json"), filepath.Join(dir, "config.json")); err != nil {
+		t.Fatalf("failed to load the tokenizer of %s: %v", golden.Model, err)
+	}
+
+	for _, c := range golden.Cases {
+		t.Run(c.Text, func(t *testing.T) {
+			if c.IDs == nil {
+				t.Fatal("no golden ids; run make golden-tokenizer")
+			}
+			got, _ := tok.Encode(c.Text)
+			if !reflect.DeepEqual(got, c.IDs) {
+				t.Errorf("Encode(%q)\n got %v\nwant %v", c.Text, got, c.IDs)
+			}
+		})
+	}
+}
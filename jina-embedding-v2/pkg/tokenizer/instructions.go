@@ -0,0 +1,119 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// TextPlaceholder marks where the input goes in an instruction template. A
+// template without it is used as a prefix.
+const TextPlaceholder = "{text}"
+
+// defaultInstructions are the jina-embeddings-v3 retrieval instructions.
+// Tasks without an entry are encoded as is.
+var defaultInstructions = map[string]string{
+	"retrieval.query":   "Represent the query for retrieving evidence documents: " + TextPlaceholder,
+	"retrieval.passage": "Represent the document for retrieval: " + TextPlaceholder,
+}
+
+// WithInstructionFile loads task instruction templates from a JSON file
+// mapping task names to templates, e.g.
+//
+//	{"retrieval.query": "query: {text}", "classification": "{text}"}
+//
+// The file is read when the tokenizer is loaded, and its entries override
+// both the built-in defaults and any task_instructions in config.json. A
+// missing or malformed file makes loading fail.
+func WithInstructionFile(path string) Option {
+	return func(t *SentencePieceTokenizer) {
+		t.instructionFile = path
+	}
+}
+
+// LoadInstructions reads instruction templates in the WithInstructionFile
+// format from r and merges them over the current ones.
+func (t *SentencePieceTokenizer) LoadInstructions(r io.Reader) error {
+	var templates map[string]string
+	if err := json.NewDecoder(r).Decode(&templates); err != nil {
+		return fmt.Errorf("failed to parse instruction templates: %v", err)
+	}
+	for task, template := range templates {
+		if strings.Count(template, TextPlaceholder) > 1 {
+			return fmt.Errorf("instruction template for %q has more than one %s", task, TextPlaceholder)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	instructions := t.taskInstructions()
+	for task, template := range templates {
+		instructions[task] = template
+	}
+	t.instructions = instructions
+	return nil
+}
+
+// loadInstructions sets up the templates for a freshly loaded model:
+// defaults, then config.json's task_instructions, then the instruction file.
+func (t *SentencePieceTokenizer) loadInstructions(config *ModelConfig) error {
+	t.instructions = nil
+	instructions := t.taskInstructions()
+	for task, instruction := range config.TaskInstructions {
+		instructions[task] = instruction
+	}
+	t.instructions = instructions
+
+	if t.instructionFile == "" {
+		return nil
+	}
+	f, err := os.Open(t.instructionFile)
+	if err != nil {
+		return fmt.Errorf("failed to open instruction file: %v", err)
+	}
+	defer f.Close()
+	return t.LoadInstructions(f)
+}
+
+// taskInstructions returns a copy of the current templates, or of the
+// defaults before any have been loaded.
+func (t *SentencePieceTokenizer) taskInstructions() map[string]string {
+	source := t.instructions
+	if source == nil {
+		source = defaultInstructions
+	}
+	instructions := make(map[string]string, len(source))
+	for task, template := range source {
+		instructions[task] = template
+	}
+	return instructions
+}
+
+// ApplyInstruction returns text wrapped in the instruction template for
+// taskType, or text unchanged if the task has none.
+func (t *SentencePieceTokenizer) ApplyInstruction(taskType, text string) string {
+	t.mu.RLock()
+	instructions := t.instructions
+	t.mu.RUnlock()
+
+	if instructions == nil {
+		instructions = defaultInstructions
+	}
+	template, ok := instructions[taskType]
+	if !ok {
+		return text
+	}
+	if !strings.Contains(template, TextPlaceholder) {
+		return template + text
+	}
+	return strings.Replace(template, TextPlaceholder, text, 1)
+}
+
+// EncodeForTask is Encode applied to text wrapped in the instruction
+// template for taskType.
+func (t *SentencePieceTokenizer) EncodeForTask(taskType, text string) ([]int64, []int64) {
+	return t.Encode(t.ApplyInstruction(taskType, text))
+}
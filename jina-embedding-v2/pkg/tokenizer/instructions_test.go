@@ -0,0 +1,74 @@
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInstructionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instructions.json")
+	templates := `{"retrieval.query": "this is {text}", "classification": "an "}`
+	if err := os.WriteFile(path, []byte(templates), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tok := NewSentencePieceTokenizer(WithInstructionFile(path))
+	config := `{"task_instructions": {"retrieval.passage": "passage: ", "classification": "ignored: "}}`
+	if err := tok.load([]byte(testTokenizerJSON), []byte(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		task, want string
+	}{
+		{"retrieval.query", "this is apple"},    // file overrides the default
+		{"retrieval.passage", "passage: apple"}, // config overrides the default
+		{"classification", "an apple"},          // file overrides config; no placeholder means prefix
+		{"text-matching", "apple"},              // no template
+	}
+	for _, tt := range tests {
+		if got := tok.ApplyInstruction(tt.task, "apple"); got != tt.want {
+			t.Errorf("ApplyInstruction(%q) = %q, want %q", tt.task, got, tt.want)
+		}
+	}
+
+	want, _ := tok.Encode("this is apple")
+	if got, _ := tok.EncodeForTask("retrieval.query", "apple"); !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeForTask = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultInstructions(t *testing.T) {
+	tok := newTestTokenizer(t)
+	got := tok.ApplyInstruction("retrieval.query", "apple")
+	if want := "Represent the query for retrieving evidence documents: apple"; got != want {
+		t.Errorf("ApplyInstruction = %q, want %q", got, want)
+	}
+}
+
+func TestInstructionFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	malformed := filepath.Join(dir, "malformed.json")
+	twice := filepath.Join(dir, "twice.json")
+	if err := os.WriteFile(malformed, []byte(`{"retrieval.query": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(twice, []byte(`{"retrieval.query": "{text} {text}"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing.json"), malformed, twice} {
+		tok := NewSentencePieceTokenizer(WithInstructionFile(path))
+		if err := tok.load([]byte(testTokenizerJSON), []byte(testConfigJSON)); err == nil {
+			t.Errorf("loading with %s: expected an error", filepath.Base(path))
+		}
+	}
+
+	tok := newTestTokenizer(t)
+	if err := tok.LoadInstructions(strings.NewReader(`[]`)); err == nil {
+		t.Error("LoadInstructions: expected an error for a non-object")
+	}
+}
@@ -0,0 +1,33 @@
+package tokenizer
+
+// Logger receives the tokenizer's diagnostic output. Its methods take a
+// message followed by alternating keys and values, so a *slog.Logger can be
+// passed as is.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// nopLogger discards everything. It is the default Logger.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+
+// Option configures a SentencePieceTokenizer at construction time.
+type Option func(*SentencePieceTokenizer)
+
+// WithLogger sends download and load progress to l at info level and
+// problems at warn level. With the tokenizer's Debug field set, the ids
+// produced by every Encode call are logged at debug level. Nothing is logged
+// by default.
+func WithLogger(l Logger) Option {
+	return func(t *SentencePieceTokenizer) {
+		if l == nil {
+			l = nopLogger{}
+		}
+		t.logger = l
+	}
+}
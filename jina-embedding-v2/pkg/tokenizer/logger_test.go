@@ -0,0 +1,85 @@
+package tokenizer
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// recordingLogger keeps the messages it is given, by level.
+type recordingLogger struct {
+	debug, info, warn []string
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) { l.debug = append(l.debug, msg) }
+func (l *recordingLogger) Info(msg string, args ...any)  { l.info = append(l.info, msg) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.warn = append(l.warn, msg) }
+
+func TestWithLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	tok := NewSentencePieceTokenizer(WithLogger(logger))
+	if err := tok.load([]byte(testTokenizerJSON), []byte(testConfigJSON)); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.info) != 1 || logger.info[0] != "loaded tokenizer" {
+		t.Errorf("info messages = %q, want one load message", logger.info)
+	}
+
+	tok.Encode("this is an apple")
+	if len(logger.debug) != 0 {
+		t.Errorf("Encode logged %q with Debug unset", logger.debug)
+	}
+
+	tok.Debug = true
+	tok.Encode("this is an apple")
+	tok.Encode("an apple")
+	if len(logger.debug) != 2 {
+		t.Errorf("got %d debug messages for 2 Encode calls", len(logger.debug))
+	}
+}
+
+func TestEncodeDoesNotAllocateForLogging(t *testing.T) {
+	tok := newTestTokenizer(t)
+	withoutLogger := testing.AllocsPerRun(100, func() { tok.Encode("this is an apple") })
+
+	tok = NewSentencePieceTokenizer(WithLogger(&recordingLogger{}))
+	if err := tok.load([]byte(testTokenizerJSON), []byte(testConfigJSON)); err != nil {
+		t.Fatal(err)
+	}
+	withLogger := testing.AllocsPerRun(100, func() { tok.Encode("this is an apple") })
+
+	if withLogger != withoutLogger {
+		t.Errorf("Encode made %v allocations with a logger and Debug unset, %v without", withLogger, withoutLogger)
+	}
+}
+
+func TestSlogLoggerKeepsEncodeAtDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	tok := NewSentencePieceTokenizer(WithLogger(logger))
+	if err := tok.load([]byte(testTokenizerJSON), []byte(testConfigJSON)); err != nil {
+		t.Fatal(err)
+	}
+	tok.Debug = true
+	tok.Encode("this is an apple")
+
+	out := buf.String()
+	if !strings.Contains(out, "vocab_size=8") {
+		t.Errorf("expected the load message at info level, got %q", out)
+	}
+	if strings.Contains(out, "encoded") {
+		t.Errorf("Encode logged above debug level: %q", out)
+	}
+}
+
+func TestDefaultLoggerIsSilent(t *testing.T) {
+	tok := NewSentencePieceTokenizer(WithLogger(nil))
+	if _, ok := tok.logger.(nopLogger); !ok {
+		t.Errorf("WithLogger(nil) installed %T, want nopLogger", tok.logger)
+	}
+	if _, ok := NewSentencePieceTokenizer().logger.(nopLogger); !ok {
+		t.Error("default logger is not nopLogger")
+	}
+}
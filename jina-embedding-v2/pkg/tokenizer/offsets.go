@@ -0,0 +1,121 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// EncodeWithOffsets is Encode plus, for every token, the [start, end) range
+// of runes of text it was produced from, for mapping tokens back to spans of
+// the input. Special tokens get [0, 0]. A piece that is only the word marker
+// covers no characters and gets an empty range at the word's start.
+// Characters removed by CleanText or surrounding whitespace belong to no
+// token, so offsets of neighbouring tokens can leave gaps.
+//
+// The ids are the same as Encode's; the cache is not used.
+func (t *SentencePieceTokenizer) EncodeWithOffsets(text string) (ids []int64, mask []int64, offsets [][2]int) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	normalized, origin := t.normalizeWithOrigin(text)
+
+	tokens := []string{t.bosToken}
+	offsets = [][2]int{{0, 0}}
+
+	// runeAt converts byte offsets in normalized to rune indexes; spans are
+	// visited in order, so it only ever scans forward.
+	byteOffset, runeIndex := 0, 0
+	runeAt := func(b int) int {
+		runeIndex += utf8.RuneCountInString(normalized[byteOffset:b])
+		byteOffset = b
+		return runeIndex
+	}
+
+	for _, span := range t.preTokenizeSpans(normalized) {
+		start, end := runeAt(span.start), runeAt(span.end)
+
+		// The marker is the first rune of a marked pre-token but has no
+		// character in the text.
+		cursor := start
+		if span.marked {
+			cursor--
+		}
+		for _, p := range t.greedyPieces(span.token) {
+			from := max(cursor, start)
+			cursor += p.runes
+			to := min(cursor, end)
+
+			tokens = append(tokens, p.token)
+			if from >= to {
+				offsets = append(offsets, [2]int{origin[from], origin[from]})
+			} else {
+				offsets = append(offsets, [2]int{origin[from], origin[to-1] + 1})
+			}
+		}
+	}
+
+	tokens = append(tokens, t.eosToken)
+	offsets = append(offsets, [2]int{0, 0})
+
+	ids = t.tokenToIds(tokens)
+	mask = make([]int64, len(ids))
+	for i := range mask {
+		mask[i] = 1
+	}
+	return ids, mask, offsets
+}
+
+// normalizeWithOrigin returns the text Encode tokenizes, after CleanText if
+// set and normalize, and for every rune of it the index of the rune of text
+// it came from. origin has one extra entry, the rune count of text, so a
+// range ending at the end of the normalized text can be looked up.
+func (t *SentencePieceTokenizer) normalizeWithOrigin(text string) (string, []int) {
+	var runes []rune
+	var origin []int
+
+	i := 0
+	if t.CleanText {
+		// Mirrors cleanText: whitespace runs become one space, and only
+		// between kept characters.
+		pendingSpace := -1
+		for _, r := range text {
+			switch {
+			case unicode.IsSpace(r):
+				if len(runes) > 0 && pendingSpace < 0 {
+					pendingSpace = i
+				}
+			case r == utf8.RuneError || unicode.In(r, unicode.Cc, unicode.Cf, unicode.Co):
+			default:
+				if pendingSpace >= 0 {
+					runes = append(runes, ' ')
+					origin = append(origin, pendingSpace)
+					pendingSpace = -1
+				}
+				runes = append(runes, r)
+				origin = append(origin, i)
+			}
+			i++
+		}
+	} else {
+		for _, r := range text {
+			runes = append(runes, r)
+			origin = append(origin, i)
+			i++
+		}
+	}
+
+	if t.modelType == modelWordPiece {
+		runes, origin = t.normalizeBert(runes, origin)
+	}
+
+	// normalize trims surrounding whitespace.
+	start, end := 0, len(runes)
+	for start < end && unicode.IsSpace(runes[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(runes[end-1]) {
+		end--
+	}
+
+	return string(runes[start:end]), append(origin[start:end:end], i)
+}
@@ -0,0 +1,104 @@
+package tokenizer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEncodeWithOffsets(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		cleanText bool
+		want      [][2]int
+	}{
+		{"words", "this is an apple", false, [][2]int{{0, 0}, {0, 4}, {5, 7}, {8, 10}, {11, 16}, {0, 0}}},
+		{"surrounding spaces", "  this  is ", false, [][2]int{{0, 0}, {2, 6}, {8, 10}, {0, 0}}},
+		// "▁" is not in the vocabulary, so the marker is an unknown token of
+		// its own.
+		{"multibyte", "é is", false, [][2]int{{0, 0}, {0, 0}, {0, 1}, {2, 4}, {0, 0}}},
+		{"clean text", "\tthis\u200b\n\nis", true, [][2]int{{0, 0}, {1, 5}, {8, 10}, {0, 0}}},
+		{"joined by clean text", "app\u200ble", true, [][2]int{{0, 0}, {0, 6}, {0, 0}}},
+		{"empty", "", false, [][2]int{{0, 0}, {0, 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := newTestTokenizer(t)
+			tok.CleanText = tt.cleanText
+
+			ids, mask, offsets := tok.EncodeWithOffsets(tt.text)
+			if !reflect.DeepEqual(offsets, tt.want) {
+				t.Errorf("offsets = %v, want %v", offsets, tt.want)
+			}
+			if wantIDs, wantMask := tok.Encode(tt.text); !reflect.DeepEqual(ids, wantIDs) || !reflect.DeepEqual(mask, wantMask) {
+				t.Errorf("ids, mask = %v, %v; Encode gives %v, %v", ids, mask, wantIDs, wantMask)
+			}
+		})
+	}
+}
+
+func TestEncodeWithOffsetsPieces(t *testing.T) {
+	tok := newTestTokenizer(t)
+	for i, piece := range []string{"▁", "ap", "ple", "▁is"} {
+		id := 100 + i
+		tok.vocab[piece] = id
+		tok.vocabReverse[id] = piece
+	}
+
+	// "apple" after a space is "▁apple"; removing that piece forces the
+	// marker, "ap" and "ple" to be separate tokens.
+	delete(tok.vocab, "▁apple")
+
+	ids, _, offsets := tok.EncodeWithOffsets("is apple")
+	want := [][2]int{{0, 0}, {0, 2}, {3, 3}, {3, 5}, {5, 8}, {0, 0}}
+	if !reflect.DeepEqual(offsets, want) {
+		t.Errorf("EncodeWithOffsets = %v, %v; want offsets %v", ids, offsets, want)
+	}
+}
+
+func FuzzEncodeWithOffsets(f *testing.F) {
+	for _, seed := range []string{
+		"This is an apple",
+		"  机器学习 很有趣 ",
+		"Launch 🚀 now!",
+		"naïve\u200b café\t\tdéjà vu",
+		"",
+	} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	f.Fuzz(func(t *testing.T, text string, cleanText bool) {
+		if !utf8.ValidString(text) {
+			t.Skip("tokenizer input is UTF-8 text")
+		}
+
+		tok := newRuneTokenizer(t, text)
+		tok.CleanText = cleanText
+
+		ids, _, offsets := tok.EncodeWithOffsets(text)
+		if want, _ := tok.Encode(text); !reflect.DeepEqual(ids, want) {
+			t.Fatalf("ids %v differ from Encode %v", ids, want)
+		}
+		if len(offsets) != len(ids) {
+			t.Fatalf("%d offsets for %d ids", len(offsets), len(ids))
+		}
+
+		runes := []rune(text)
+		for i, off := range offsets {
+			if off[0] < 0 || off[0] > off[1] || off[1] > len(runes) {
+				t.Fatalf("offset %d = %v out of range for %d runes", i, off, len(runes))
+			}
+			// Without CleanText every piece is a substring of the input.
+			token := tok.vocabReverse[int(ids[i])]
+			if !cleanText && off[0] < off[1] && token != tok.unkToken {
+				if got := string(runes[off[0]:off[1]]); got != strings.TrimPrefix(token, wordPrefix) {
+					t.Fatalf("token %q has offsets %v covering %q", token, off, got)
+				}
+			}
+		}
+	})
+}
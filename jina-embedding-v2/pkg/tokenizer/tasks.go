@@ -0,0 +1,60 @@
+package tokenizer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// KnownTasks lists the task names ResolveTask accepts: the LoRA adapters
+// of jina-embeddings-v3, plus "clustering", which is served by the
+// "separation" adapter.
+var KnownTasks = []string{
+	"retrieval.query",
+	"retrieval.passage",
+	"classification",
+	"clustering",
+	"text-matching",
+	"separation",
+}
+
+// taskAdapters maps friendly task names to the lora_adaptations entry
+// that serves them, where the two differ.
+var taskAdapters = map[string]string{
+	"clustering": "separation",
+}
+
+// ResolveTask returns the LoRA adapter index for a task name from
+// KnownTasks. The index is the adapter's position in the model's
+// lora_adaptations, so a config listing adapters in another order still
+// gets the right one. Names outside KnownTasks, and tasks the model has no
+// adapter for, are an ErrUnknownTask.
+func (t *SentencePieceTokenizer) ResolveTask(name string) (int64, error) {
+	if !slices.Contains(KnownTasks, name) {
+		return 0, fmt.Errorf("%w: %q, want one of %s", ErrUnknownTask, name, strings.Join(KnownTasks, ", "))
+	}
+	adapter := name
+	if a, ok := taskAdapters[name]; ok {
+		adapter = a
+	}
+	return t.GetTaskID(adapter)
+}
+
+// checkLoraAdaptations validates config.json's lora_adaptations at load
+// time. A name listed twice would make its task id ambiguous, so it is an
+// error; adapters ResolveTask does not know are only logged, since
+// GetTaskID still reaches them by name.
+func (t *SentencePieceTokenizer) checkLoraAdaptations(adaptations []string) error {
+	for i, adapter := range adaptations {
+		if adapter == "" {
+			return fmt.Errorf("lora_adaptations[%d] is empty", i)
+		}
+		if slices.Contains(adaptations[:i], adapter) {
+			return fmt.Errorf("lora_adaptations lists %q twice", adapter)
+		}
+		if !slices.Contains(KnownTasks, adapter) {
+			t.logger.Warn("unknown LoRA adapter in config", "adapter", adapter, "index", i)
+		}
+	}
+	return nil
+}
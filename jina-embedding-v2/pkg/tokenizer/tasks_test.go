@@ -0,0 +1,65 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveTask(t *testing.T) {
+	// Adapters deliberately not in jina-embeddings-v3's order.
+	config := `{"lora_adaptations": ["text-matching", "separation", "retrieval.passage", "retrieval.query"]}`
+	tok := NewSentencePieceTokenizer()
+	if err := tok.load([]byte(testTokenizerJSON), []byte(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		want    int64
+		wantErr error
+	}{
+		{"text-matching", 0, nil},
+		{"retrieval.query", 3, nil},
+		{"retrieval.passage", 2, nil},
+		{"separation", 1, nil},
+		{"clustering", 1, nil},
+		{"classification", 0, ErrUnknownTask},
+		{"summarization", 0, ErrUnknownTask},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tok.ResolveTask(tt.name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResolveTask(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ResolveTask(%q) = %d, want %d", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadChecksLoraAdaptations(t *testing.T) {
+	for _, config := range []string{
+		`{"lora_adaptations": ["text-matching", "separation", "text-matching"]}`,
+		`{"lora_adaptations": ["text-matching", ""]}`,
+	} {
+		if err := NewSentencePieceTokenizer().load([]byte(testTokenizerJSON), []byte(config)); err == nil {
+			t.Errorf("load accepted %s", config)
+		}
+	}
+
+	logger := &recordingLogger{}
+	tok := NewSentencePieceTokenizer(WithLogger(logger))
+	err := tok.load([]byte(testTokenizerJSON), []byte(`{"lora_adaptations": ["text-matching", "code"]}`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(logger.warn) != 1 {
+		t.Errorf("got warnings %v, want one for the unknown adapter", logger.warn)
+	}
+	if id, err := tok.GetTaskID("code"); err != nil || id != 1 {
+		t.Errorf("GetTaskID(\"code\") = %d, %v; want 1", id, err)
+	}
+}
@@ -2,19 +2,65 @@ package tokenizer
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Model types of tokenizer.json that Encode implements.
+const (
+	// modelUnigram is a SentencePiece vocabulary, like XLM-RoBERTa's in
+	// jina-embeddings-v3.
+	modelUnigram = "Unigram"
+	// modelWordPiece is a BERT vocabulary, like jina-embeddings-v2's.
+	modelWordPiece = "WordPiece"
+)
+
+// ErrUnknownTask is returned by GetTaskID and ResolveTask for task types
+// missing from the model's lora_adaptations.
+var ErrUnknownTask = errors.New("unknown task type")
+
 type ModelConfig struct {
-	LoraAdaptations []string `json:"lora_adaptations"`
+	LoraAdaptations       []string `json:"lora_adaptations"`
+	MaxPositionEmbeddings int      `json:"max_position_embeddings"`
+	// TaskInstructions maps task names to instruction prefixes, as in the
+	// jina-embeddings-v3 config.
+	TaskInstructions map[string]string `json:"task_instructions"`
 }
 
+// SentencePieceTokenizer is safe for concurrent use once loaded: encoding,
+// decoding and the other lookups may run from many goroutines at once, and
+// Reload may swap the model under them. The Load methods and the exported
+// fields are for setting the tokenizer up and must not be used
+// concurrently with anything else.
 type SentencePieceTokenizer struct {
+	// Debug makes Encode log the ids of every encoding at debug level. It
+	// is off by default: building the log record costs an allocation per
+	// call even when the logger discards it.
+	Debug bool
+
+	// CleanText makes Encode clean its input first, like the HuggingFace
+	// BERT normalizer's clean_text: control and format characters such as
+	// zero-width spaces are removed, and every run of whitespace, line
+	// breaks included, becomes a single space.
+	CleanText bool
+
+	// mu guards the loaded model below against Reload: encoding holds it
+	// for reading, Reload for writing while it swaps in the new model.
+	mu sync.RWMutex
+
+	// modelType is modelUnigram or modelWordPiece. It selects how Encode
+	// normalizes, splits and segments text; wordPiece holds the settings
+	// of a WordPiece model.
+	modelType string
+	wordPiece wordPieceConfig
+
 	vocab         map[string]int
 	vocabReverse  map[int]string
 	specialTokens map[string]int
@@ -22,31 +68,68 @@ type SentencePieceTokenizer struct {
 	bosToken      string
 	eosToken      string
 	unkToken      string
+	padToken      string
+	maxLength     int
+
+	// addPrefixSpace is the pre-tokenizer's add_prefix_space flag: whether
+	// a word at the very start of the text is marked with wordPrefix as if
+	// a space preceded it.
+	addPrefixSpace bool
+
+	name   string
+	cache  *Cache
+	logger Logger
+
+	// cacheDir overrides the download cache directory; see cacheRoot.
+	cacheDir string
+	// hfToken overrides $HF_TOKEN; see authToken.
+	hfToken string
+	// endpointURL overrides $HF_ENDPOINT; see endpoint.
+	endpointURL string
+	// retryBackoff overrides downloadBackoff, for tests.
+	retryBackoff time.Duration
+
+	// instructions maps task names to instruction templates; nil means
+	// defaultInstructions.
+	instructions    map[string]string
+	instructionFile string
 }
 
 type TokenizerJSON struct {
 	Version string `json:"version"`
 	Model   struct {
-		Type       string      `json:"type"`
-		Vocab      interface{} `json:"vocab"`
-		UnkId      int         `json:"unk_id"`
-		Dropout    *float64    `json:"dropout"`
-		Continuing interface{} `json:"continuing_subword_prefix"`
-		EndOfWord  bool        `json:"end_of_word_suffix"`
-		FuseUnk    bool        `json:"fuse_unk"`
+		Type                 string      `json:"type"`
+		Vocab                interface{} `json:"vocab"`
+		UnkId                *int        `json:"unk_id"`
+		UnkToken             string      `json:"unk_token"`
+		Dropout              *float64    `json:"dropout"`
+		Continuing           string      `json:"continuing_subword_prefix"`
+		MaxInputCharsPerWord int         `json:"max_input_chars_per_word"`
+		EndOfWord            bool        `json:"end_of_word_suffix"`
+		FuseUnk              bool        `json:"fuse_unk"`
 	} `json:"model"`
 	Normalizer struct {
-		Type string `json:"type"`
+		Type               string `json:"type"`
+		CleanText          *bool  `json:"clean_text"`
+		HandleChineseChars *bool  `json:"handle_chinese_chars"`
+		StripAccents       *bool  `json:"strip_accents"`
+		Lowercase          *bool  `json:"lowercase"`
 	} `json:"normalizer"`
 	PreTokenizer struct {
-		Type       string `json:"type"`
-		AddPrefix  bool   `json:"add_prefix_space"`
-		TrimOffset bool   `json:"trim_offsets"`
+		PreTokenizerJSON
+		PreTokenizers []PreTokenizerJSON `json:"pretokenizers"`
 	} `json:"pre_tokenizer"`
 	PostProcessor struct {
-		Type string   `json:"type"`
-		Sep  []string `json:"sep"`
-		Cls  []string `json:"cls"`
+		Type string        `json:"type"`
+		Sep  []interface{} `json:"sep"`
+		Cls  []interface{} `json:"cls"`
+		// Single and SpecialTokens describe a TemplateProcessing
+		// post-processor, which fast BERT tokenizers use.
+		Single        []TemplatePieceJSON `json:"single"`
+		SpecialTokens map[string]struct {
+			IDs    []int    `json:"ids"`
+			Tokens []string `json:"tokens"`
+		} `json:"special_tokens"`
 	} `json:"post_processor"`
 	Decoder struct {
 		Type string `json:"type"`
@@ -56,17 +139,57 @@ type TokenizerJSON struct {
 		Content string `json:"content"`
 		Special bool   `json:"special"`
 	} `json:"added_tokens"`
+	Truncation *struct {
+		MaxLength int `json:"max_length"`
+	} `json:"truncation"`
+	Padding *struct {
+		PadID    int    `json:"pad_id"`
+		PadToken string `json:"pad_token"`
+	} `json:"padding"`
+}
+
+// TemplatePieceJSON is one entry of a TemplateProcessing template: either a
+// special token, named by its key in special_tokens, or the input sequence.
+type TemplatePieceJSON struct {
+	SpecialToken *struct {
+		ID string `json:"id"`
+	} `json:"SpecialToken"`
+	Sequence *struct {
+		ID string `json:"id"`
+	} `json:"Sequence"`
+}
+
+// PreTokenizerJSON is one pre-tokenizer of tokenizer.json, either the
+// top-level one or an entry of a Sequence.
+type PreTokenizerJSON struct {
+	Type          string `json:"type"`
+	AddPrefix     bool   `json:"add_prefix_space"`
+	PrependScheme string `json:"prepend_scheme"`
+	TrimOffset    bool   `json:"trim_offsets"`
+}
+
+// addsPrefixSpace reports whether p puts a word marker before the first
+// word. Newer tokenizers versions replace add_prefix_space with
+// prepend_scheme.
+func (p PreTokenizerJSON) addsPrefixSpace() bool {
+	return p.AddPrefix || p.PrependScheme == "always" || p.PrependScheme == "first"
 }
 
-func NewSentencePieceTokenizer() *SentencePieceTokenizer {
-	return &SentencePieceTokenizer{
+func NewSentencePieceTokenizer(opts ...Option) *SentencePieceTokenizer {
+	t := &SentencePieceTokenizer{
+		modelType:     modelUnigram,
 		vocab:         make(map[string]int),
 		vocabReverse:  make(map[int]string),
 		specialTokens: make(map[string]int),
 		bosToken:      "<s>",
 		eosToken:      "</s>",
 		unkToken:      "<unk>",
+		logger:        nopLogger{},
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 func (t *SentencePieceTokenizer) LoadFromLocal(tokenizerPath, configPath string) error {
@@ -83,73 +206,26 @@ func (t *SentencePieceTokenizer) LoadFromLocal(tokenizerPath, configPath string)
 		return fmt.Errorf("failed to read tokenizer.json: %v", err)
 	}
 
-	var tokenizerJSON TokenizerJSON
-	err = json.Unmarshal(tokenizerData, &tokenizerJSON)
-	if err != nil {
-		return fmt.Errorf("failed to parse tokenizer.json: %v", err)
-	}
-
 	configData, err := os.ReadFile(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to read config.json: %v", err)
 	}
 
-	var modelConfig ModelConfig
-	err = json.Unmarshal(configData, &modelConfig)
-	if err != nil {
-		return fmt.Errorf("failed to parse config.json: %v", err)
-	}
-
-	t.config = &modelConfig
-
-	switch vocab := tokenizerJSON.Model.Vocab.(type) {
-	case map[string]interface{}:
-		for token, id := range vocab {
-			if idInt, ok := id.(float64); ok {
-				t.vocab[token] = int(idInt)
-				t.vocabReverse[int(idInt)] = token
-			}
-		}
-	case []interface{}:
-		for i, vocabItem := range vocab {
-			if vocabArray, ok := vocabItem.([]interface{}); ok && len(vocabArray) >= 2 {
-				if token, ok := vocabArray[0].(string); ok {
-					t.vocab[token] = i
-					t.vocabReverse[i] = token
-				}
-			}
-		}
-	}
-
-	for _, token := range tokenizerJSON.AddedTokens {
-		t.specialTokens[token.Content] = token.ID
-		switch token.Content {
-		case "<s>":
-			t.bosToken = token.Content
-		case "</s>":
-			t.eosToken = token.Content
-		case "<unk>":
-			t.unkToken = token.Content
-		}
-	}
-
-	fmt.Printf("Loaded tokenizer with vocab size: %d\n", len(t.vocab))
-	fmt.Printf("Special tokens: %v\n", t.specialTokens)
-
-	return nil
+	t.name = tokenizerPath
+	return t.load(tokenizerData, configData)
 }
 
 func (t *SentencePieceTokenizer) LoadFromHuggingFace(modelName string) error {
-	baseURL := fmt.Sprintf("https://huggingface.co/%s/resolve/main", modelName)
+	baseURL := fmt.Sprintf("%s/%s/resolve/main", t.endpoint(), modelName)
 
-	cacheDir := filepath.Join(os.TempDir(), "real_tokenizer_cache", modelName)
+	cacheDir := filepath.Join(t.cacheRoot(), modelName)
 	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create cache directory: %v", err)
 	}
 
 	tokenizerPath := filepath.Join(cacheDir, "tokenizer.json")
-	if _, err := os.Stat(tokenizerPath); os.IsNotExist(err) {
-		fmt.Printf("Downloading tokenizer.json...\n")
+	if !t.cached(tokenizerPath) {
+		t.logger.Info("downloading tokenizer.json", "model", modelName)
 		err := t.downloadFile(baseURL+"/tokenizer.json", tokenizerPath)
 		if err != nil {
 			return fmt.Errorf("failed to download tokenizer.json: %v", err)
@@ -157,8 +233,8 @@ func (t *SentencePieceTokenizer) LoadFromHuggingFace(modelName string) error {
 	}
 
 	configPath := filepath.Join(cacheDir, "config.json")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		fmt.Printf("Downloading config.json...\n")
+	if !t.cached(configPath) {
+		t.logger.Info("downloading config.json", "model", modelName)
 		err := t.downloadFile(baseURL+"/config.json", configPath)
 		if err != nil {
 			return fmt.Errorf("failed to download config.json: %v", err)
@@ -170,15 +246,96 @@ func (t *SentencePieceTokenizer) LoadFromHuggingFace(modelName string) error {
 		return fmt.Errorf("failed to read tokenizer.json: %v", err)
 	}
 
+	configData, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config.json: %v", err)
+	}
+
+	t.name = modelName
+	return t.load(tokenizerData, configData)
+}
+
+// Reload replaces the loaded model with modelName's tokenizer.json and
+// config.json from HuggingFace, for servers that swap models without
+// recreating the tokenizer. Unlike calling LoadFromHuggingFace again, which
+// merges into the existing vocabulary, nothing of the old model is kept.
+// The new files are downloaded and parsed before anything changes, so on
+// error the tokenizer still has its old model, and encoding calls running
+// concurrently see either the old model or the new one, never a mix.
+// Options and the encode cache are kept; cached encodings of the old model
+// are dropped.
+func (t *SentencePieceTokenizer) Reload(modelName string) error {
+	fresh := t.withSameOptions()
+	if err := fresh.LoadFromHuggingFace(modelName); err != nil {
+		return err
+	}
+
+	t.replaceModel(fresh)
+	return nil
+}
+
+// replaceModel swaps in the model loaded into fresh, dropping cached
+// encodings of the old one.
+func (t *SentencePieceTokenizer) replaceModel(fresh *SentencePieceTokenizer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	oldName := t.name
+	t.modelType = fresh.modelType
+	t.wordPiece = fresh.wordPiece
+	t.vocab = fresh.vocab
+	t.vocabReverse = fresh.vocabReverse
+	t.specialTokens = fresh.specialTokens
+	t.config = fresh.config
+	t.bosToken = fresh.bosToken
+	t.eosToken = fresh.eosToken
+	t.unkToken = fresh.unkToken
+	t.padToken = fresh.padToken
+	t.maxLength = fresh.maxLength
+	t.addPrefixSpace = fresh.addPrefixSpace
+	t.instructions = fresh.instructions
+	t.name = fresh.name
+
+	if t.cache != nil {
+		t.cache.removeModel(oldName)
+		t.cache.removeModel(t.name)
+	}
+}
+
+// withSameOptions returns an unloaded tokenizer configured like t.
+func (t *SentencePieceTokenizer) withSameOptions() *SentencePieceTokenizer {
+	fresh := NewSentencePieceTokenizer()
+	fresh.Debug = t.Debug
+	fresh.CleanText = t.CleanText
+	fresh.logger = t.logger
+	fresh.cacheDir = t.cacheDir
+	fresh.hfToken = t.hfToken
+	fresh.endpointURL = t.endpointURL
+	fresh.retryBackoff = t.retryBackoff
+	fresh.instructionFile = t.instructionFile
+	return fresh
+}
+
+// load parses the contents of tokenizer.json and config.json.
+func (t *SentencePieceTokenizer) load(tokenizerData, configData []byte) error {
 	var tokenizerJSON TokenizerJSON
-	err = json.Unmarshal(tokenizerData, &tokenizerJSON)
+	err := json.Unmarshal(tokenizerData, &tokenizerJSON)
 	if err != nil {
 		return fmt.Errorf("failed to parse tokenizer.json: %v", err)
 	}
 
-	configData, err := os.ReadFile(configPath)
-	if err != nil {
-		return fmt.Errorf("failed to read config.json: %v", err)
+	switch tokenizerJSON.Model.Type {
+	case "", modelUnigram:
+		t.modelType = modelUnigram
+	case modelWordPiece:
+		t.modelType = modelWordPiece
+		t.wordPiece = newWordPieceConfig(&tokenizerJSON)
+		t.bosToken, t.eosToken, t.unkToken = "[CLS]", "[SEP]", "[UNK]"
+		if tokenizerJSON.Model.UnkToken != "" {
+			t.unkToken = tokenizerJSON.Model.UnkToken
+		}
+	default:
+		return fmt.Errorf("unsupported tokenizer.json model type %q", tokenizerJSON.Model.Type)
 	}
 
 	var modelConfig ModelConfig
@@ -187,7 +344,25 @@ func (t *SentencePieceTokenizer) LoadFromHuggingFace(modelName string) error {
 		return fmt.Errorf("failed to parse config.json: %v", err)
 	}
 
+	if err := t.checkLoraAdaptations(modelConfig.LoraAdaptations); err != nil {
+		return fmt.Errorf("invalid config.json: %w", err)
+	}
 	t.config = &modelConfig
+	if err := t.loadInstructions(&modelConfig); err != nil {
+		return err
+	}
+
+	t.addPrefixSpace = tokenizerJSON.PreTokenizer.addsPrefixSpace()
+	for _, p := range tokenizerJSON.PreTokenizer.PreTokenizers {
+		if p.addsPrefixSpace() {
+			t.addPrefixSpace = true
+		}
+	}
+
+	t.maxLength = modelConfig.MaxPositionEmbeddings
+	if tokenizerJSON.Truncation != nil && tokenizerJSON.Truncation.MaxLength > 0 {
+		t.maxLength = tokenizerJSON.Truncation.MaxLength
+	}
 
 	switch vocab := tokenizerJSON.Model.Vocab.(type) {
 	case map[string]interface{}:
@@ -215,70 +390,162 @@ func (t *SentencePieceTokenizer) LoadFromHuggingFace(modelName string) error {
 			t.bosToken = token.Content
 		case "</s>":
 			t.eosToken = token.Content
-		case "<unk>":
+		case "<unk>", "[UNK]":
 			t.unkToken = token.Content
+		case "<pad>", "[PAD]":
+			t.padToken = token.Content
+		}
+	}
+
+	// A Unigram model names its unknown piece by id.
+	if id := tokenizerJSON.Model.UnkId; t.modelType == modelUnigram && id != nil {
+		if token, ok := t.vocabReverse[*id]; ok {
+			t.unkToken = token
 		}
 	}
+	if err := t.checkUnkToken(); err != nil {
+		return fmt.Errorf("invalid tokenizer.json: %w", err)
+	}
+
+	// A padding section names the pad token the tokenizer was saved with.
+	if padding := tokenizerJSON.Padding; padding != nil && padding.PadToken != "" {
+		t.padToken = padding.PadToken
+		t.specialTokens[padding.PadToken] = padding.PadID
+	}
+
+	// A Roberta or BERT post-processor names the tokens that wrap every
+	// sequence as [token, id] pairs; prefer them over the added_tokens guess.
+	if token, id, ok := specialPair(tokenizerJSON.PostProcessor.Cls); ok {
+		t.bosToken = token
+		t.specialTokens[token] = id
+	}
+	if token, id, ok := specialPair(tokenizerJSON.PostProcessor.Sep); ok {
+		t.eosToken = token
+		t.specialTokens[token] = id
+	}
+	// A TemplateProcessing post-processor lists them around the sequence
+	// in its single-sequence template instead.
+	bos, eos := templateSpecials(&tokenizerJSON)
+	if bos != nil {
+		t.bosToken = bos.token
+		t.specialTokens[bos.token] = bos.id
+	}
+	if eos != nil {
+		t.eosToken = eos.token
+		t.specialTokens[eos.token] = eos.id
+	}
 
-	fmt.Printf("Loaded tokenizer with vocab size: %d\n", len(t.vocab))
-	fmt.Printf("Special tokens: %v\n", t.specialTokens)
+	t.logger.Info("loaded tokenizer", "vocab_size", len(t.vocab), "special_tokens", len(t.specialTokens))
 
 	return nil
 }
 
-func (t *SentencePieceTokenizer) downloadFile(url, filepath string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
+// checkUnkToken makes sure the unknown token has an id, so that text
+// missing from the vocabulary is not silently encoded as id 0.
+func (t *SentencePieceTokenizer) checkUnkToken() error {
+	if _, ok := t.specialTokens[t.unkToken]; ok {
+		return nil
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			fmt.Printf("Warning: failed to close response body: %v\n", err)
-		}
-	}()
+	id, ok := t.vocab[t.unkToken]
+	if !ok {
+		return fmt.Errorf("unknown token %q is not in the vocabulary", t.unkToken)
+	}
+	t.specialTokens[t.unkToken] = id
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file: status %d", resp.StatusCode)
+// specialPair parses a post-processor ["token", id] pair.
+func specialPair(pair []interface{}) (string, int, bool) {
+	if len(pair) != 2 {
+		return "", 0, false
+	}
+	token, ok := pair[0].(string)
+	if !ok {
+		return "", 0, false
 	}
+	id, ok := pair[1].(float64)
+	if !ok {
+		return "", 0, false
+	}
+	return token, int(id), true
+}
 
-	out, err := os.Create(filepath)
-	if err != nil {
-		return err
+// specialToken is a special token and its id.
+type specialToken struct {
+	token string
+	id    int
+}
+
+// templateSpecials returns the special tokens a TemplateProcessing
+// post-processor puts before and after a single sequence, or nil where the
+// template has none. Only templates adding one token on each side are
+// understood.
+func templateSpecials(tokenizerJSON *TokenizerJSON) (bos, eos *specialToken) {
+	postProcessor := tokenizerJSON.PostProcessor
+	if postProcessor.Type != "TemplateProcessing" {
+		return nil, nil
 	}
-	defer func() {
-		if err := out.Close(); err != nil {
-			fmt.Printf("Warning: failed to close file: %v\n", err)
-		}
-	}()
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	seenSequence := false
+	for _, p := range postProcessor.Single {
+		if p.Sequence != nil {
+			seenSequence = true
+			continue
+		}
+		if p.SpecialToken == nil {
+			continue
+		}
+		special, ok := postProcessor.SpecialTokens[p.SpecialToken.ID]
+		if !ok || len(special.IDs) != 1 || len(special.Tokens) != 1 {
+			continue
+		}
+		token := &specialToken{token: special.Tokens[0], id: special.IDs[0]}
+		if !seenSequence && bos == nil {
+			bos = token
+		} else if seenSequence && eos == nil {
+			eos = token
+		}
+	}
+	return bos, eos
 }
 
 func (t *SentencePieceTokenizer) tokenToIds(tokens []string) []int64 {
 	var ids []int64
 	for _, token := range tokens {
-		if id, exists := t.vocab[token]; exists {
+		if id, exists := t.specialTokens[token]; exists {
+			ids = append(ids, int64(id))
+		} else if id, exists := t.vocab[token]; exists {
 			ids = append(ids, int64(id))
 		} else {
-			if id, exists := t.specialTokens[token]; exists {
-				ids = append(ids, int64(id))
-			} else {
-				ids = append(ids, int64(t.specialTokens[t.unkToken]))
-			}
+			ids = append(ids, int64(t.specialTokens[t.unkToken]))
 		}
 	}
 	return ids
 }
 
+// Encode tokenizes text into input ids and an attention mask: the text is
+// normalized, split into words and symbols, each segmented into vocabulary
+// pieces, and the result wrapped in the model's begin and end tokens, <s>
+// and </s> for a Unigram model or [CLS] and [SEP] for a WordPiece one.
 func (t *SentencePieceTokenizer) Encode(text string) ([]int64, []int64) {
-	text = strings.ToLower(text)
-	words := strings.Fields(text)
+	if t.CleanText {
+		text = cleanText(text)
+	}
 
-	var tokens []string
-	tokens = append(tokens, "[CLS]")
-	tokens = append(tokens, words...)
-	tokens = append(tokens, "[SEP]")
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.cache != nil {
+		if inputIds, attentionMask, ok := t.cache.get(t.name, text); ok {
+			return inputIds, attentionMask
+		}
+	}
+
+	tokens := []string{t.bosToken}
+	for _, preToken := range t.preTokenize(t.normalize(text)) {
+		tokens = append(tokens, t.greedyTokenize(preToken)...)
+	}
+	tokens = append(tokens, t.eosToken)
 
 	inputIds := t.tokenToIds(tokens)
 
@@ -287,10 +554,86 @@ func (t *SentencePieceTokenizer) Encode(text string) ([]int64, []int64) {
 		attentionMask[i] = 1
 	}
 
+	if t.cache != nil {
+		t.cache.put(t.name, text, inputIds, attentionMask)
+	}
+
+	if t.Debug {
+		t.logger.Debug("encoded text", "tokens", len(inputIds), "ids", inputIds)
+	}
+
 	return inputIds, attentionMask
 }
 
+// cleanText removes control and format characters from text and collapses
+// whitespace runs into single spaces, trimming both ends.
+func cleanText(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	pendingSpace := false
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			pendingSpace = b.Len() > 0
+			continue
+		case r == utf8.RuneError || unicode.In(r, unicode.Cc, unicode.Cf, unicode.Co):
+			continue
+		}
+		if pendingSpace {
+			b.WriteByte(' ')
+			pendingSpace = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// EncodeWithLength is Encode plus the effective token count of the result.
+func (t *SentencePieceTokenizer) EncodeWithLength(text string) ([]int64, []int64, int) {
+	inputIds, attentionMask := t.Encode(text)
+	return inputIds, attentionMask, EffectiveLength(attentionMask)
+}
+
+// CountTokens returns len(ids) for Encode(text), special tokens included,
+// without building the id and mask slices. A cached encoding is used if
+// there is one, but the count is not cached.
+func (t *SentencePieceTokenizer) CountTokens(text string) int {
+	if t.CleanText {
+		text = cleanText(text)
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.cache != nil {
+		if inputIds, _, ok := t.cache.get(t.name, text); ok {
+			return len(inputIds)
+		}
+	}
+
+	count := 2 // bosToken and eosToken
+	for _, preToken := range t.preTokenize(t.normalize(text)) {
+		t.segment(preToken, func(piece) { count++ })
+	}
+	return count
+}
+
+// EffectiveLength returns the number of attended tokens in an attention mask,
+// i.e. the token count excluding padding.
+func EffectiveLength(mask []int64) int {
+	n := 0
+	for _, m := range mask {
+		if m == 1 {
+			n++
+		}
+	}
+	return n
+}
+
 func (t *SentencePieceTokenizer) GetTaskID(taskType string) (int64, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	if t.config == nil {
 		return 0, fmt.Errorf("config not loaded")
 	}
@@ -301,23 +644,70 @@ func (t *SentencePieceTokenizer) GetTaskID(taskType string) (int64, error) {
 		}
 	}
 
-	return 0, nil
+	return 0, fmt.Errorf("%w: %q not in %v", ErrUnknownTask, taskType, t.config.LoraAdaptations)
+}
+
+// PadID returns the id of the pad token: the one named in tokenizer.json's
+// padding section, otherwise the <pad> (or [PAD]) entry of added_tokens. It
+// is -1 when the tokenizer has no pad token.
+func (t *SentencePieceTokenizer) PadID() int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.padToken == "" {
+		return -1
+	}
+	if id, ok := t.specialTokens[t.padToken]; ok {
+		return int64(id)
+	}
+	return -1
 }
 
+// ModelMaxLength returns the longest input, in tokens, the model accepts:
+// the truncation max_length from tokenizer.json if set, otherwise
+// max_position_embeddings from config.json. It is 0 if neither is known.
+func (t *SentencePieceTokenizer) ModelMaxLength() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.maxLength
+}
+
+// Tasks returns the task types accepted by GetTaskID, in task ID order.
+func (t *SentencePieceTokenizer) Tasks() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.config == nil {
+		return nil
+	}
+	return append([]string(nil), t.config.LoraAdaptations...)
+}
+
+// DecodeIds converts token ids back to text, for debugging. The begin and
+// end tokens are dropped by id rather than by string, so input text that
+// happens to contain "<s>" survives decoding.
 func (t *SentencePieceTokenizer) DecodeIds(ids []int64) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var tokens []string
 	for _, id := range ids {
-		if token, exists := t.vocabReverse[int(id)]; exists {
-			tokens = append(tokens, token)
-		} else {
+		token, exists := t.vocabReverse[int(id)]
+		switch {
+		case !exists:
 			tokens = append(tokens, t.unkToken)
+		case token == t.bosToken || token == t.eosToken:
+		default:
+			tokens = append(tokens, token)
 		}
 	}
 
+	if t.modelType == modelWordPiece {
+		text := strings.Join(tokens, " ")
+		return strings.ReplaceAll(text, " "+t.wordPiece.SubwordPrefix, "")
+	}
 	text := strings.Join(tokens, "")
-	text = strings.ReplaceAll(text, "▁", " ")
-	text = strings.ReplaceAll(text, t.bosToken, "")
-	text = strings.ReplaceAll(text, t.eosToken, "")
-
+	text = strings.ReplaceAll(text, wordPrefix, " ")
 	return strings.TrimSpace(text)
-}
\ No newline at end of file
+}
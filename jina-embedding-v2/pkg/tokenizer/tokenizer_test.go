@@ -0,0 +1,326 @@
+package tokenizer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEffectiveLength(t *testing.T) {
+	tests := []struct {
+		name string
+		mask []int64
+		want int
+	}{
+		{"empty", nil, 0},
+		{"all ones", []int64{1, 1, 1, 1}, 4},
+		{"right padded", []int64{1, 1, 1, 0, 0}, 3},
+		{"all padding", []int64{0, 0, 0}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EffectiveLength(tt.mask); got != tt.want {
+				t.Errorf("EffectiveLength(%v) = %d, want %d", tt.mask, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeWithLength(t *testing.T) {
+	tok := newTestTokenizer(t)
+
+	ids, mask, n := tok.EncodeWithLength("this is an apple")
+	if want := []int64{0, 4, 5, 6, 7, 2}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ids = %v, want %v", ids, want)
+	}
+	if n != 6 {
+		t.Errorf("token count = %d, want 6", n)
+	}
+
+	// Right-padded to a batch length, as EmbedBatch does, the pad positions
+	// are masked out and do not count.
+	for len(mask) < 10 {
+		ids = append(ids, tok.PadID())
+		mask = append(mask, 0)
+	}
+	if got := EffectiveLength(mask); got != 6 {
+		t.Errorf("EffectiveLength of padded mask %v = %d, want 6", mask, got)
+	}
+}
+
+const testTokenizerJSON = `{
+	"version": "1.0",
+	"model": {
+		"type": "Unigram",
+		"unk_id": 3,
+		"vocab": [
+			["<s>", 0.0],
+			["<pad>", 0.0],
+			["</s>", 0.0],
+			["<unk>", 0.0],
+			["▁this", -1.0],
+			["▁is", -1.0],
+			["▁an", -1.0],
+			["▁apple", -1.0]
+		]
+	},
+	"pre_tokenizer": {"type": "Metaspace", "replacement": "▁", "add_prefix_space": true},
+	"added_tokens": [
+		{"id": 0, "content": "<s>", "special": true},
+		{"id": 1, "content": "<pad>", "special": true},
+		{"id": 2, "content": "</s>", "special": true},
+		{"id": 3, "content": "<unk>", "special": true}
+	]
+}`
+
+const testConfigJSON = `{
+	"lora_adaptations": ["retrieval.query", "retrieval.passage", "separation", "classification", "text-matching"]
+}`
+
+// unkOnlyJSON is the smallest tokenizer.json that loads: one with nothing
+// but an unknown token.
+const unkOnlyJSON = `{"added_tokens": [{"id": 0, "content": "<unk>", "special": true}]}`
+
+func newTestTokenizer(t *testing.T) *SentencePieceTokenizer {
+	t.Helper()
+
+	tok := NewSentencePieceTokenizer()
+	if err := tok.load([]byte(testTokenizerJSON), []byte(testConfigJSON)); err != nil {
+		t.Fatalf("failed to load test tokenizer: %v", err)
+	}
+	return tok
+}
+
+func TestGetTaskID(t *testing.T) {
+	tok := newTestTokenizer(t)
+
+	id, err := tok.GetTaskID("text-matching")
+	if err != nil {
+		t.Fatalf("GetTaskID: %v", err)
+	}
+	if id != 4 {
+		t.Errorf("text-matching task id = %d, want 4", id)
+	}
+
+	_, err = tok.GetTaskID("summarization")
+	if !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("expected ErrUnknownTask, got %v", err)
+	}
+}
+
+func TestTasks(t *testing.T) {
+	tasks := newTestTokenizer(t).Tasks()
+	want := []string{"retrieval.query", "retrieval.passage", "separation", "classification", "text-matching"}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("Tasks() = %v, want %v", tasks, want)
+	}
+}
+
+func TestModelMaxLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenizer string
+		config    string
+		want      int
+	}{
+		{"unknown", unkOnlyJSON, `{}`, 0},
+		{"config", unkOnlyJSON, `{"max_position_embeddings": 8192}`, 8192},
+		{"truncation wins", `{"truncation": {"max_length": 512}, "added_tokens": [{"id": 0, "content": "<unk>", "special": true}]}`, `{"max_position_embeddings": 8192}`, 512},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := NewSentencePieceTokenizer()
+			if err := tok.load([]byte(tt.tokenizer), []byte(tt.config)); err != nil {
+				t.Fatal(err)
+			}
+			if got := tok.ModelMaxLength(); got != tt.want {
+				t.Errorf("ModelMaxLength() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "this is an apple", "this is an apple"},
+		{"tabs and newlines", "this\tis\r\nan\n\napple", "this is an apple"},
+		{"repeated spaces", "  this   is an  apple  ", "this is an apple"},
+		{"zero-width space", "this is an\u200b apple\ufeff", "this is an apple"},
+		{"control characters", "this\x00 is\x07 an\x1b apple", "this is an apple"},
+		{"joined by zero-width space", "app\u200ble", "apple"},
+		{"only whitespace", " \t\r\n ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanText(tt.in); got != tt.want {
+				t.Errorf("cleanText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeCleanText(t *testing.T) {
+	tok := newTestTokenizer(t)
+	want, _ := tok.Encode("this is an apple")
+
+	messy := "this\tis\u200b\r\nan \x07 apple\n"
+	if got, _ := tok.Encode(messy); reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q to tokenize differently without CleanText", messy)
+	}
+
+	tok.CleanText = true
+	if got, _ := tok.Encode(messy); !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode(%q) = %v, want %v", messy, got, want)
+	}
+}
+
+func TestEncodeUsesConfiguredSpecialTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenizer string
+		bos, eos  string
+	}{
+		{"added tokens", testTokenizerJSON, "<s>", "</s>"},
+		{"post processor", `{
+			"model": {
+				"type": "Unigram",
+				"vocab": [["<unk>", 0.0], ["▁this", -1.0], ["<pad>", 0.0], ["[CLS]", 0.0], ["[SEP]", 0.0], ["▁apple", -1.0]]
+			},
+			"added_tokens": [
+				{"id": 0, "content": "<unk>", "special": true},
+				{"id": 2, "content": "<pad>", "special": true},
+				{"id": 3, "content": "[CLS]", "special": true},
+				{"id": 4, "content": "[SEP]", "special": true}
+			],
+			"post_processor": {"type": "RobertaProcessing", "cls": ["[CLS]", 3], "sep": ["[SEP]", 4]}
+		}`, "[CLS]", "[SEP]"},
+		{"template post processor", `{
+			"model": {
+				"type": "Unigram",
+				"vocab": [["<unk>", 0.0], ["▁this", -1.0], ["<s>", 0.0], ["</s>", 0.0], ["▁apple", -1.0]]
+			},
+			"added_tokens": [{"id": 0, "content": "<unk>", "special": true}],
+			"post_processor": {
+				"type": "TemplateProcessing",
+				"single": [
+					{"SpecialToken": {"id": "[CLS]", "type_id": 0}},
+					{"Sequence": {"id": "A", "type_id": 0}},
+					{"SpecialToken": {"id": "[SEP]", "type_id": 0}}
+				],
+				"special_tokens": {
+					"[CLS]": {"id": "[CLS]", "ids": [101], "tokens": ["[CLS]"]},
+					"[SEP]": {"id": "[SEP]", "ids": [102], "tokens": ["[SEP]"]}
+				}
+			}
+		}`, "[CLS]", "[SEP]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := NewSentencePieceTokenizer()
+			if err := tok.load([]byte(tt.tokenizer), []byte(`{}`)); err != nil {
+				t.Fatal(err)
+			}
+
+			ids, _ := tok.Encode("this apple")
+			if len(ids) < 2 {
+				t.Fatalf("Encode returned %v", ids)
+			}
+			if want := int64(tok.specialTokens[tt.bos]); ids[0] != want {
+				t.Errorf("first id = %d, want bos id %d (%s)", ids[0], want, tt.bos)
+			}
+			if want := int64(tok.specialTokens[tt.eos]); ids[len(ids)-1] != want {
+				t.Errorf("last id = %d, want eos id %d (%s)", ids[len(ids)-1], want, tt.eos)
+			}
+		})
+	}
+}
+
+func TestUnkToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenizer string
+		want      int
+	}{
+		{"added tokens", testTokenizerJSON, 3},
+		{"unigram unk_id", `{"model": {"type": "Unigram", "unk_id": 1, "vocab": [["<s>", 0.0], ["<oov>", 0.0]]}}`, 1},
+		{"wordpiece unk_token", `{"model": {"type": "WordPiece", "unk_token": "<oov>", "vocab": {"[CLS]": 0, "<oov>": 7}}}`, 7},
+		{"bert added tokens", `{"added_tokens": [{"id": 100, "content": "[UNK]", "special": true}]}`, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := NewSentencePieceTokenizer()
+			if err := tok.load([]byte(tt.tokenizer), []byte(`{}`)); err != nil {
+				t.Fatal(err)
+			}
+			if got := tok.specialTokens[tok.unkToken]; got != tt.want {
+				t.Errorf("unknown token %q has id %d, want %d", tok.unkToken, got, tt.want)
+			}
+		})
+	}
+
+	for _, tokenizerJSON := range []string{
+		`{}`,
+		`{"model": {"type": "WordPiece", "vocab": {"[PAD]": 0, "hello": 1}}}`,
+		`{"model": {"type": "Unigram", "vocab": [["<pad>", 0.0], ["▁hello", -1.0]]}}`,
+	} {
+		if err := NewSentencePieceTokenizer().load([]byte(tokenizerJSON), []byte(`{}`)); err == nil {
+			t.Errorf("load(%s): expected an error for a tokenizer without an unknown token", tokenizerJSON)
+		}
+	}
+	if err := NewSentencePieceTokenizer().load([]byte(`{"model": {"type": "BPE"}}`), []byte(`{}`)); err == nil {
+		t.Error("expected an error for an unsupported model type")
+	}
+}
+
+func TestPadID(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenizer string
+		want      int64
+	}{
+		{"added tokens", testTokenizerJSON, 1},
+		{"bert style", `{"added_tokens": [{"id": 0, "content": "[PAD]", "special": true}, {"id": 100, "content": "[UNK]", "special": true}]}`, 0},
+		{"padding section wins", `{
+			"added_tokens": [{"id": 1, "content": "<pad>", "special": true}, {"id": 3, "content": "<unk>", "special": true}, {"id": 5, "content": "<blank>", "special": true}],
+			"padding": {"pad_id": 5, "pad_token": "<blank>"}
+		}`, 5},
+		{"no pad token", `{"added_tokens": [{"id": 0, "content": "<s>", "special": true}, {"id": 3, "content": "<unk>", "special": true}]}`, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := NewSentencePieceTokenizer()
+			if err := tok.load([]byte(tt.tokenizer), []byte(`{}`)); err != nil {
+				t.Fatal(err)
+			}
+			if got := tok.PadID(); got != tt.want {
+				t.Errorf("PadID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	tok := newTestTokenizer(t)
+
+	for _, text := range []string{"", "this is an apple", "  unknown words, and symbols! ", "机器学习 🚀"} {
+		ids, _ := tok.Encode(text)
+		if got := tok.CountTokens(text); got != len(ids) {
+			t.Errorf("CountTokens(%q) = %d, want %d", text, got, len(ids))
+		}
+	}
+
+	text := "this is an apple"
+	count := testing.AllocsPerRun(100, func() { tok.CountTokens(text) })
+	encode := testing.AllocsPerRun(100, func() { tok.Encode(text) })
+	if count >= encode {
+		t.Errorf("CountTokens makes %v allocations, Encode %v", count, encode)
+	}
+}
@@ -0,0 +1,133 @@
+package tokenizer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// wordPrefix is the SentencePiece marker for a piece that starts a word.
+const wordPrefix = "▁"
+
+// preTokenizePattern splits text into words and single symbols. \w only
+// matches ASCII in Go, so words are spelled out with Unicode classes: letters
+// (including CJK), digits, combining marks and underscores. Anything else
+// that isn't whitespace, such as punctuation or emoji, is a symbol.
+var preTokenizePattern = regexp.MustCompile(`[\p{L}\p{M}\p{N}_]+|[^\p{L}\p{M}\p{N}_\s]`)
+
+// normalize trims surrounding whitespace. XLM-RoBERTa's tokenizer is cased,
+// so text is otherwise left alone; a WordPiece model applies its BERT
+// normalizer.
+func (t *SentencePieceTokenizer) normalize(text string) string {
+	if t.modelType == modelWordPiece {
+		runes, _ := t.normalizeBert([]rune(text), nil)
+		text = string(runes)
+	}
+	return strings.TrimSpace(text)
+}
+
+// preTokenize splits text into words, marked with wordPrefix, and symbols.
+// A word at the start of text is only marked when the tokenizer has
+// add_prefix_space set.
+func (t *SentencePieceTokenizer) preTokenize(text string) []string {
+	spans := t.preTokenizeSpans(text)
+
+	tokens := make([]string, len(spans))
+	for i, span := range spans {
+		tokens[i] = span.token
+	}
+	return tokens
+}
+
+// preTokenSpan is a pre-token and the bytes [start, end) of the text it
+// came from. marked reports whether token starts with an added wordPrefix.
+type preTokenSpan struct {
+	token      string
+	start, end int
+	marked     bool
+}
+
+// preTokenizeSpans is preTokenize with the position of each pre-token.
+func (t *SentencePieceTokenizer) preTokenizeSpans(text string) []preTokenSpan {
+	if t.modelType == modelWordPiece {
+		return bertPreTokenizeSpans(text)
+	}
+
+	matches := preTokenizePattern.FindAllStringIndex(text, -1)
+
+	spans := make([]preTokenSpan, 0, len(matches))
+	for _, loc := range matches {
+		span := preTokenSpan{token: text[loc[0]:loc[1]], start: loc[0], end: loc[1]}
+		if isAlphaNumeric(span.token) && (loc[0] > 0 || t.addPrefixSpace) {
+			span.token = wordPrefix + span.token
+			span.marked = true
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// isAlphaNumeric reports whether s contains a letter or digit.
+func isAlphaNumeric(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// greedyTokenize segments a pre-token into the longest vocabulary pieces,
+// left to right, as a simplified Unigram model. It works on runes so that
+// multibyte characters are never split; a rune that starts no piece becomes
+// the unknown token.
+func (t *SentencePieceTokenizer) greedyTokenize(token string) []string {
+	pieces := t.greedyPieces(token)
+
+	result := make([]string, len(pieces))
+	for i, p := range pieces {
+		result[i] = p.token
+	}
+	return result
+}
+
+// piece is a token produced by greedyPieces and the number of runes of the
+// pre-token it covers; an unknown token covers one rune.
+type piece struct {
+	token string
+	runes int
+}
+
+// greedyPieces is greedyTokenize with the length of every piece.
+func (t *SentencePieceTokenizer) greedyPieces(token string) []piece {
+	var result []piece
+	t.segment(token, func(p piece) { result = append(result, p) })
+	return result
+}
+
+// segment calls emit with each piece of token, in order.
+func (t *SentencePieceTokenizer) segment(token string, emit func(piece)) {
+	if t.modelType == modelWordPiece {
+		t.segmentWordPiece(token, emit)
+		return
+	}
+
+	runes := []rune(token)
+
+	for i := 0; i < len(runes); {
+		bestLength := 0
+		for j := i + 1; j <= len(runes); j++ {
+			if _, exists := t.vocab[string(runes[i:j])]; exists {
+				bestLength = j - i
+			}
+		}
+
+		if bestLength > 0 {
+			emit(piece{string(runes[i : i+bestLength]), bestLength})
+			i += bestLength
+		} else {
+			emit(piece{t.unkToken, 1})
+			i++
+		}
+	}
+}
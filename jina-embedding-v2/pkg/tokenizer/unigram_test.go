@@ -0,0 +1,177 @@
+package tokenizer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestEncodeUnigram(t *testing.T) {
+	tok := newTestTokenizer(t)
+
+	tests := []struct {
+		in   string
+		want []int64
+	}{
+		{"this is an apple", []int64{0, 4, 5, 6, 7, 2}},
+		{"  this is\tan apple\n", []int64{0, 4, 5, 6, 7, 2}},
+		// Cased, like XLM-RoBERTa: "This" is not "▁this".
+		{"This", []int64{0, 3, 3, 3, 3, 3, 2}},
+		{"", []int64{0, 2}},
+	}
+
+	for _, tt := range tests {
+		ids, mask := tok.Encode(tt.in)
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("Encode(%q) = %v, want %v", tt.in, ids, tt.want)
+		}
+		if len(mask) != len(ids) {
+			t.Errorf("Encode(%q): %d mask entries for %d ids", tt.in, len(mask), len(ids))
+		}
+	}
+}
+
+// newRuneTokenizer returns a test tokenizer whose vocabulary also holds "▁"
+// and every rune of text, so no part of text has to become UNK.
+func newRuneTokenizer(t *testing.T, text string) *SentencePieceTokenizer {
+	t.Helper()
+
+	tok := newTestTokenizer(t)
+	add := func(piece string) {
+		if _, ok := tok.vocab[piece]; ok {
+			return
+		}
+		id := len(tok.vocabReverse)
+		tok.vocab[piece] = id
+		tok.vocabReverse[id] = piece
+	}
+	add(wordPrefix)
+	for _, r := range text {
+		add(string(r))
+	}
+	return tok
+}
+
+// stripSpace removes whitespace and the word marker, which decoding does not
+// reproduce exactly.
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || string(r) == wordPrefix {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func TestGreedyTokenizeMultibyte(t *testing.T) {
+	tok := newTestTokenizer(t)
+	for i, piece := range []string{"▁机器", "学习", "很", "有趣", "🚀"} {
+		id := 100 + i
+		tok.vocab[piece] = id
+		tok.vocabReverse[id] = piece
+	}
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"▁机器学习很有趣", []string{"▁机器", "学习", "很", "有趣"}},
+		{"🚀", []string{"🚀"}},
+		// "界" is unknown; the rune after it must still be found.
+		{"很界很", []string{"很", "<unk>", "很"}},
+	}
+
+	for _, tt := range tests {
+		if got := tok.greedyTokenize(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("greedyTokenize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPreTokenizeUnicodeWords(t *testing.T) {
+	tok := newTestTokenizer(t)
+
+	got := tok.preTokenize("机器学习很有趣 🚀 café!")
+	want := []string{"▁机器学习很有趣", "🚀", "▁café", "!"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("preTokenize = %q, want %q", got, want)
+	}
+}
+
+func TestAddPrefixSpace(t *testing.T) {
+	tests := []struct {
+		name         string
+		preTokenizer string
+		wantFirst    string
+	}{
+		{"unset", `{"type": "Metaspace", "replacement": "▁"}`, "this"},
+		{"add_prefix_space", `{"type": "Metaspace", "add_prefix_space": true}`, "▁this"},
+		{"add_prefix_space false", `{"type": "Metaspace", "add_prefix_space": false}`, "this"},
+		{"prepend_scheme in a sequence", `{"type": "Sequence", "pretokenizers": [
+			{"type": "WhitespaceSplit"},
+			{"type": "Metaspace", "replacement": "▁", "prepend_scheme": "always"}
+		]}`, "▁this"},
+		{"prepend_scheme never", `{"type": "Metaspace", "prepend_scheme": "never"}`, "this"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizerJSON := strings.Replace(testTokenizerJSON,
+				`{"type": "Metaspace", "replacement": "▁", "add_prefix_space": true}`, tt.preTokenizer, 1)
+			tok := NewSentencePieceTokenizer()
+			if err := tok.load([]byte(tokenizerJSON), []byte(testConfigJSON)); err != nil {
+				t.Fatal(err)
+			}
+
+			got := tok.preTokenize("this is an apple")
+			want := []string{tt.wantFirst, "▁is", "▁an", "▁apple"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("preTokenize = %q, want %q", got, want)
+			}
+
+			// Without the marker the first word is not in the vocabulary.
+			ids, _ := tok.Encode("this is an apple")
+			if wantMarked := tt.wantFirst == "▁this"; (ids[1] == 4) != wantMarked {
+				t.Errorf("Encode = %v; first word id 4 expected: %v", ids, wantMarked)
+			}
+		})
+	}
+}
+
+func FuzzEncodeDecodeRoundTrip(f *testing.F) {
+	for _, seed := range []string{
+		"This is an apple",
+		"机器学习很有趣",
+		"Launch 🚀 now!",
+		"naïve café, déjà vu",
+		"<s> literal markers </s>",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		if !utf8.ValidString(text) {
+			t.Skip("tokenizer input is UTF-8 text")
+		}
+
+		tok := newRuneTokenizer(t, text)
+		ids, mask := tok.Encode(text)
+		if len(ids) != len(mask) {
+			t.Fatalf("%d ids but %d mask entries", len(ids), len(mask))
+		}
+		if n := tok.CountTokens(text); n != len(ids) {
+			t.Fatalf("CountTokens = %d, Encode gives %d ids", n, len(ids))
+		}
+
+		decoded := tok.DecodeIds(ids)
+		if !utf8.ValidString(decoded) {
+			t.Fatalf("decoded text %q is not valid UTF-8", decoded)
+		}
+		if got, want := stripSpace(decoded), stripSpace(text); got != want {
+			t.Fatalf("round trip lost content: Encode/DecodeIds(%q) = %q", text, decoded)
+		}
+	})
+}
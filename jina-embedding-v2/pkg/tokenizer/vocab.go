@@ -0,0 +1,134 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// vocabFormatVersion is written by ExportVocab and checked by ImportVocab.
+const vocabFormatVersion = 1
+
+// vocabFile is the ExportVocab format. The vocabulary is a list indexed by
+// token id, which is how Unigram tokenizers number their pieces; ids with no
+// token are empty strings.
+type vocabFile struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	// Model is the model type; files written before WordPiece support
+	// leave it out and are Unigram.
+	Model          string           `json:"model,omitempty"`
+	WordPiece      *wordPieceConfig `json:"word_piece,omitempty"`
+	Vocab          []string         `json:"vocab"`
+	SpecialTokens  map[string]int   `json:"special_tokens"`
+	BosToken       string           `json:"bos_token"`
+	EosToken       string           `json:"eos_token"`
+	UnkToken       string           `json:"unk_token"`
+	PadToken       string           `json:"pad_token,omitempty"`
+	MaxLength      int              `json:"max_length,omitempty"`
+	AddPrefixSpace bool             `json:"add_prefix_space"`
+	Config         ModelConfig      `json:"config"`
+}
+
+// ExportVocab writes everything the tokenizer parsed from tokenizer.json
+// and config.json as one JSON document: the vocabulary, special tokens,
+// pre-tokenizer settings and model config. ImportVocab loads it back into
+// a tokenizer that encodes exactly as this one does, so the file can be
+// checked in to pin tokenizer behavior regardless of upstream changes. The
+// output is deterministic.
+func (t *SentencePieceTokenizer) ExportVocab(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.config == nil {
+		return fmt.Errorf("tokenizer not loaded")
+	}
+
+	size := 0
+	for id := range t.vocabReverse {
+		size = max(size, id+1)
+	}
+	vocab := make([]string, size)
+	for id, token := range t.vocabReverse {
+		if id >= 0 {
+			vocab[id] = token
+		}
+	}
+
+	var wordPiece *wordPieceConfig
+	if t.modelType == modelWordPiece {
+		wordPiece = &t.wordPiece
+	}
+	return json.NewEncoder(w).Encode(vocabFile{
+		Version:        vocabFormatVersion,
+		Name:           t.name,
+		Model:          t.modelType,
+		WordPiece:      wordPiece,
+		Vocab:          vocab,
+		SpecialTokens:  t.specialTokens,
+		BosToken:       t.bosToken,
+		EosToken:       t.eosToken,
+		UnkToken:       t.unkToken,
+		PadToken:       t.padToken,
+		MaxLength:      t.maxLength,
+		AddPrefixSpace: t.addPrefixSpace,
+		Config:         *t.config,
+	})
+}
+
+// ImportVocab replaces the loaded model with one written by ExportVocab.
+// Like Reload, it parses the whole file before changing anything and is
+// safe to call while other goroutines encode. Instruction templates are set
+// up as when loading config.json, so WithInstructionFile still applies.
+func (t *SentencePieceTokenizer) ImportVocab(r io.Reader) error {
+	var file vocabFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("failed to parse vocabulary: %v", err)
+	}
+	if file.Version != vocabFormatVersion {
+		return fmt.Errorf("unsupported vocabulary format version %d, want %d", file.Version, vocabFormatVersion)
+	}
+
+	fresh := t.withSameOptions()
+	switch file.Model {
+	case "", modelUnigram:
+	case modelWordPiece:
+		if file.WordPiece == nil {
+			return fmt.Errorf("invalid vocab file: WordPiece model without word_piece settings")
+		}
+		fresh.modelType = modelWordPiece
+		fresh.wordPiece = *file.WordPiece
+	default:
+		return fmt.Errorf("unsupported vocab file model type %q", file.Model)
+	}
+	for id, token := range file.Vocab {
+		if token == "" {
+			continue
+		}
+		fresh.vocab[token] = id
+		fresh.vocabReverse[id] = token
+	}
+	for token, id := range file.SpecialTokens {
+		fresh.specialTokens[token] = id
+	}
+	fresh.bosToken = file.BosToken
+	fresh.eosToken = file.EosToken
+	fresh.unkToken = file.UnkToken
+	fresh.padToken = file.PadToken
+	fresh.maxLength = file.MaxLength
+	fresh.addPrefixSpace = file.AddPrefixSpace
+	if err := fresh.checkUnkToken(); err != nil {
+		return fmt.Errorf("invalid vocab file: %w", err)
+	}
+	if err := fresh.checkLoraAdaptations(file.Config.LoraAdaptations); err != nil {
+		return fmt.Errorf("invalid vocab file: %w", err)
+	}
+	fresh.config = &file.Config
+	fresh.name = file.Name
+	if err := fresh.loadInstructions(fresh.config); err != nil {
+		return err
+	}
+
+	t.replaceModel(fresh)
+	return nil
+}
@@ -0,0 +1,101 @@
+package tokenizer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportImportVocab(t *testing.T) {
+	tok := newTestTokenizer(t)
+
+	var exported bytes.Buffer
+	if err := tok.ExportVocab(&exported); err != nil {
+		t.Fatal(err)
+	}
+
+	var again bytes.Buffer
+	if err := tok.ExportVocab(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(exported.Bytes(), again.Bytes()) {
+		t.Error("ExportVocab is not deterministic")
+	}
+
+	imported := NewSentencePieceTokenizer()
+	if err := imported.ImportVocab(bytes.NewReader(exported.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{"", "this is an apple", "  unknown words, and symbols! ", "an apple is this"} {
+		wantIds, wantMask := tok.Encode(text)
+		gotIds, gotMask := imported.Encode(text)
+		if !reflect.DeepEqual(gotIds, wantIds) || !reflect.DeepEqual(gotMask, wantMask) {
+			t.Errorf("Encode(%q) = %v, want %v", text, gotIds, wantIds)
+		}
+		if got, want := imported.DecodeIds(wantIds), tok.DecodeIds(wantIds); got != want {
+			t.Errorf("DecodeIds = %q, want %q", got, want)
+		}
+	}
+	if imported.PadID() != tok.PadID() || imported.ModelMaxLength() != tok.ModelMaxLength() {
+		t.Errorf("PadID, ModelMaxLength = %d, %d; want %d, %d",
+			imported.PadID(), imported.ModelMaxLength(), tok.PadID(), tok.ModelMaxLength())
+	}
+	if !reflect.DeepEqual(imported.Tasks(), tok.Tasks()) {
+		t.Errorf("Tasks() = %v, want %v", imported.Tasks(), tok.Tasks())
+	}
+	if got, want := imported.ApplyInstruction("retrieval.query", "x"), tok.ApplyInstruction("retrieval.query", "x"); got != want {
+		t.Errorf("ApplyInstruction = %q, want %q", got, want)
+	}
+}
+
+func TestImportVocabReplacesModel(t *testing.T) {
+	tok := newTestTokenizer(t)
+
+	other := NewSentencePieceTokenizer()
+	if err := other.load([]byte(otherTokenizerJSON), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	var exported bytes.Buffer
+	if err := other.ExportVocab(&exported); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tok.ImportVocab(&exported); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tok.vocab["▁this"]; ok {
+		t.Error("vocabulary of the previous model survived ImportVocab")
+	}
+	if ids, _ := tok.Encode("apple"); !reflect.DeepEqual(ids, []int64{0, 3, 1}) {
+		t.Errorf("Encode(apple) = %v, want [0 3 1]", ids)
+	}
+}
+
+func TestImportVocabErrors(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{"not json", "vocab", "failed to parse"},
+		{"unknown version", `{"version": 99}`, "version 99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := newTestTokenizer(t)
+			err := tok.ImportVocab(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ImportVocab error = %v, want one mentioning %q", err, tt.want)
+			}
+			// A failed import keeps the loaded model.
+			if ids, _ := tok.Encode("this"); !reflect.DeepEqual(ids, []int64{0, 4, 2}) {
+				t.Errorf("Encode after failed import = %v", ids)
+			}
+		})
+	}
+
+	if err := NewSentencePieceTokenizer().ExportVocab(&bytes.Buffer{}); err == nil {
+		t.Error("ExportVocab of an unloaded tokenizer should fail")
+	}
+}
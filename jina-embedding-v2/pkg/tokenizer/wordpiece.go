@@ -0,0 +1,216 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// wordPieceConfig holds the settings of a WordPiece model, such as
+// jina-embeddings-v2's BERT vocabulary, and of the BertNormalizer in front
+// of it.
+type wordPieceConfig struct {
+	// SubwordPrefix marks a piece that continues a word, "##" for BERT.
+	SubwordPrefix string `json:"subword_prefix"`
+	// MaxWordChars is the longest word, in runes, that is segmented;
+	// longer ones become the unknown token.
+	MaxWordChars int `json:"max_word_chars"`
+
+	// The BertNormalizer flags of the same names.
+	CleanText    bool `json:"clean_text"`
+	ChineseChars bool `json:"handle_chinese_chars"`
+	StripAccents bool `json:"strip_accents"`
+	Lowercase    bool `json:"lowercase"`
+}
+
+// newWordPieceConfig reads the WordPiece settings of tokenizerJSON, using
+// the HuggingFace defaults for those it leaves out.
+func newWordPieceConfig(tokenizerJSON *TokenizerJSON) wordPieceConfig {
+	c := wordPieceConfig{SubwordPrefix: "##", MaxWordChars: 100}
+	if prefix := tokenizerJSON.Model.Continuing; prefix != "" {
+		c.SubwordPrefix = prefix
+	}
+	if n := tokenizerJSON.Model.MaxInputCharsPerWord; n > 0 {
+		c.MaxWordChars = n
+	}
+
+	normalizer := tokenizerJSON.Normalizer
+	switch normalizer.Type {
+	case "BertNormalizer":
+		c.CleanText = boolOr(normalizer.CleanText, true)
+		c.ChineseChars = boolOr(normalizer.HandleChineseChars, true)
+		c.Lowercase = boolOr(normalizer.Lowercase, true)
+		// A null strip_accents follows lowercase.
+		c.StripAccents = boolOr(normalizer.StripAccents, c.Lowercase)
+	case "Lowercase":
+		c.Lowercase = true
+	}
+	return c
+}
+
+func boolOr(b *bool, def bool) bool {
+	if b == nil {
+		return def
+	}
+	return *b
+}
+
+// normalizeBert applies the BertNormalizer to runes: control characters
+// are dropped and whitespace becomes a space, CJK ideographs are surrounded
+// by spaces so each becomes a word of its own, accents are stripped and
+// letters lowercased, as configured. origin gives, for every rune, the
+// index of the input rune it came from; if it is nil the returned origin
+// is nil too.
+func (t *SentencePieceTokenizer) normalizeBert(runes []rune, origin []int) ([]rune, []int) {
+	c := t.wordPiece
+	out := make([]rune, 0, len(runes))
+	var outOrigin []int
+	emit := func(r rune, from int) {
+		out = append(out, r)
+		if origin != nil {
+			outOrigin = append(outOrigin, from)
+		}
+	}
+
+	for i, r := range runes {
+		from := i
+		if origin != nil {
+			from = origin[i]
+		}
+
+		if c.CleanText {
+			if r == 0 || r == utf8.RuneError || isControl(r) {
+				continue
+			}
+			if unicode.IsSpace(r) {
+				r = ' '
+			}
+		}
+		if c.ChineseChars && isChineseChar(r) {
+			emit(' ', from)
+			emit(r, from)
+			emit(' ', from)
+			continue
+		}
+		if !c.StripAccents && !c.Lowercase {
+			emit(r, from)
+			continue
+		}
+
+		s := string(r)
+		if c.StripAccents {
+			s = norm.NFD.String(s)
+		}
+		if c.Lowercase {
+			s = strings.ToLower(s)
+		}
+		for _, r := range s {
+			if c.StripAccents && unicode.Is(unicode.Mn, r) {
+				continue
+			}
+			emit(r, from)
+		}
+	}
+	return out, outOrigin
+}
+
+// isControl reports whether the BertNormalizer removes r with clean_text.
+// Tabs and line breaks are whitespace, not control characters.
+func isControl(r rune) bool {
+	switch r {
+	case '\t', '\n', '\r':
+		return false
+	}
+	return unicode.In(r, unicode.Cc, unicode.Cf, unicode.Co, unicode.Cs)
+}
+
+// isChineseChar reports whether r is in one of the CJK ideograph blocks
+// BERT splits into single characters. Hiragana, katakana and hangul are
+// not among them.
+func isChineseChar(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
+
+// isBertPunctuation reports whether the BertPreTokenizer splits r off as a
+// token of its own: all non-alphanumeric ASCII, such as "$" and "^", and
+// Unicode punctuation.
+func isBertPunctuation(r rune) bool {
+	return (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) ||
+		unicode.IsPunct(r)
+}
+
+// bertPreTokenizeSpans splits text like the BertPreTokenizer: on whitespace,
+// with every punctuation character a pre-token of its own. No pre-token is
+// marked.
+func bertPreTokenizeSpans(text string) []preTokenSpan {
+	var spans []preTokenSpan
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			spans = append(spans, preTokenSpan{token: text[start:end], start: start, end: end})
+			start = -1
+		}
+	}
+
+	for i, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush(i)
+		case isBertPunctuation(r):
+			flush(i)
+			end := i + utf8.RuneLen(r)
+			spans = append(spans, preTokenSpan{token: text[i:end], start: i, end: end})
+		case start < 0:
+			start = i
+		}
+	}
+	flush(len(text))
+	return spans
+}
+
+// segmentWordPiece calls emit with the WordPiece segmentation of word:
+// longest vocabulary pieces from the left, all but the first carrying the
+// subword prefix. A word that cannot be segmented completely, or is longer
+// than MaxWordChars, is a single unknown token.
+func (t *SentencePieceTokenizer) segmentWordPiece(word string, emit func(piece)) {
+	runes := []rune(word)
+	if len(runes) > t.wordPiece.MaxWordChars {
+		emit(piece{t.unkToken, len(runes)})
+		return
+	}
+
+	var pieces []piece
+	for start := 0; start < len(runes); {
+		found := ""
+		end := len(runes)
+		for ; end > start; end-- {
+			candidate := string(runes[start:end])
+			if start > 0 {
+				candidate = t.wordPiece.SubwordPrefix + candidate
+			}
+			if _, ok := t.vocab[candidate]; ok {
+				found = candidate
+				break
+			}
+		}
+		if found == "" {
+			emit(piece{t.unkToken, len(runes)})
+			return
+		}
+		pieces = append(pieces, piece{found, end - start})
+		start = end
+	}
+
+	for _, p := range pieces {
+		emit(p)
+	}
+}
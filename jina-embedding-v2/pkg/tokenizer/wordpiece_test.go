@@ -0,0 +1,144 @@
+package tokenizer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// testWordPieceJSON is shaped like jina-embeddings-v2's BERT tokenizer.json,
+// with a tiny vocabulary.
+const testWordPieceJSON = `{
+	"version": "1.0",
+	"normalizer": {"type": "BertNormalizer", "clean_text": true, "handle_chinese_chars": true, "strip_accents": null, "lowercase": true},
+	"pre_tokenizer": {"type": "BertPreTokenizer"},
+	"post_processor": {
+		"type": "TemplateProcessing",
+		"single": [
+			{"SpecialToken": {"id": "[CLS]", "type_id": 0}},
+			{"Sequence": {"id": "A", "type_id": 0}},
+			{"SpecialToken": {"id": "[SEP]", "type_id": 0}}
+		],
+		"special_tokens": {
+			"[CLS]": {"id": "[CLS]", "ids": [2], "tokens": ["[CLS]"]},
+			"[SEP]": {"id": "[SEP]", "ids": [3], "tokens": ["[SEP]"]}
+		}
+	},
+	"model": {
+		"type": "WordPiece",
+		"unk_token": "[UNK]",
+		"continuing_subword_prefix": "##",
+		"max_input_chars_per_word": 100,
+		"vocab": {
+			"[PAD]": 0, "[UNK]": 1, "[CLS]": 2, "[SEP]": 3,
+			"this": 4, "is": 5, "an": 6, "apple": 7, "hello": 8, ",": 9, "world": 10, "!": 11,
+			"hugging": 12, "##face": 13, "中": 14, "文": 15, "$": 16, "12": 17, ".": 18, "50": 19
+		}
+	},
+	"added_tokens": [
+		{"id": 0, "content": "[PAD]", "special": true},
+		{"id": 1, "content": "[UNK]", "special": true},
+		{"id": 2, "content": "[CLS]", "special": true},
+		{"id": 3, "content": "[SEP]", "special": true}
+	]
+}`
+
+func newWordPieceTokenizer(t *testing.T) *SentencePieceTokenizer {
+	t.Helper()
+
+	tok := NewSentencePieceTokenizer()
+	if err := tok.load([]byte(testWordPieceJSON), []byte(`{}`)); err != nil {
+		t.Fatalf("failed to load test tokenizer: %v", err)
+	}
+	return tok
+}
+
+func TestEncodeWordPiece(t *testing.T) {
+	tok := newWordPieceTokenizer(t)
+
+	tests := []struct {
+		name string
+		in   string
+		want []int64
+	}{
+		{"empty", "", []int64{2, 3}},
+		{"lowercased", "This is an APPLE", []int64{2, 4, 5, 6, 7, 3}},
+		{"whitespace", "  this\tis\nan apple  ", []int64{2, 4, 5, 6, 7, 3}},
+		{"punctuation", "Hello, world!", []int64{2, 8, 9, 10, 11, 3}},
+		{"ascii symbols split", "$12.50", []int64{2, 16, 17, 18, 19, 3}},
+		{"subword pieces", "HuggingFace", []int64{2, 12, 13, 3}},
+		{"accents stripped", "Héllo wörld", []int64{2, 8, 10, 3}},
+		{"chinese characters split", "中文", []int64{2, 14, 15, 3}},
+		{"control characters removed", "hel\u200blo\x07", []int64{2, 8, 3}},
+		// A word that cannot be segmented completely is one [UNK].
+		{"unknown piece", "applez apple", []int64{2, 1, 7, 3}},
+		{"emoji", "hello 🍎", []int64{2, 8, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, mask := tok.Encode(tt.in)
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.in, ids, tt.want)
+			}
+			if len(mask) != len(ids) {
+				t.Errorf("%d mask entries for %d ids", len(mask), len(ids))
+			}
+			if got := tok.CountTokens(tt.in); got != len(ids) {
+				t.Errorf("CountTokens(%q) = %d, want %d", tt.in, got, len(ids))
+			}
+			if offsetIds, _, _ := tok.EncodeWithOffsets(tt.in); !reflect.DeepEqual(offsetIds, ids) {
+				t.Errorf("EncodeWithOffsets ids = %v, Encode ids = %v", offsetIds, ids)
+			}
+		})
+	}
+}
+
+func TestEncodeWordPieceLongWord(t *testing.T) {
+	tok := newWordPieceTokenizer(t)
+	tok.wordPiece.MaxWordChars = 4
+
+	ids, _ := tok.Encode("this apple")
+	if want := []int64{2, 4, 1, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Encode = %v, want %v", ids, want)
+	}
+}
+
+func TestEncodeWithOffsetsWordPiece(t *testing.T) {
+	tok := newWordPieceTokenizer(t)
+
+	text := "Héllo, HuggingFace 中"
+	_, _, offsets := tok.EncodeWithOffsets(text)
+	want := [][2]int{{0, 0}, {0, 5}, {5, 6}, {7, 14}, {14, 18}, {19, 20}, {0, 0}}
+	if !reflect.DeepEqual(offsets, want) {
+		t.Errorf("offsets = %v, want %v", offsets, want)
+	}
+}
+
+func TestDecodeIdsWordPiece(t *testing.T) {
+	tok := newWordPieceTokenizer(t)
+
+	ids, _ := tok.Encode("Hello, HuggingFace")
+	if got, want := tok.DecodeIds(ids), "hello , huggingface"; got != want {
+		t.Errorf("DecodeIds = %q, want %q", got, want)
+	}
+}
+
+func TestExportImportVocabWordPiece(t *testing.T) {
+	tok := newWordPieceTokenizer(t)
+	var exported bytes.Buffer
+	if err := tok.ExportVocab(&exported); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := newTestTokenizer(t)
+	if err := imported.ImportVocab(&exported); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"Héllo, HuggingFace", "applez 中文"} {
+		want, _ := tok.Encode(text)
+		if got, _ := imported.Encode(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("imported Encode(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package weaviateserver boots an embedded Weaviate inside the current
+// process, for demos and integration tests that should not need a separate
+// Weaviate install.
+package weaviateserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-openapi/loads"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"github.com/weaviate/weaviate/adapters/handlers/rest"
+	"github.com/weaviate/weaviate/adapters/handlers/rest/operations"
+)
+
+// Option configures Bootstrap and BootstrapOnFreePort.
+type Option func(*config)
+
+type config struct {
+	logger *slog.Logger
+}
+
+// WithLogger reports startup progress, readiness checks and serve errors
+// to l. Nothing is logged by default.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.logger == nil {
+		c.logger = slog.New(slog.DiscardHandler)
+	}
+	return c
+}
+
+// BootstrapOnFreePort starts Weaviate on the first free port in
+// [port, port+attempts). With attempts <= 0 the operating system picks a random
+// free port instead. The port actually used is returned so clients can be
+// configured to match.
+func BootstrapOnFreePort(ctx context.Context, port string, dataPath string, attempts int, opts ...Option) (*rest.Server, string, error) {
+	start, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Failed to convert port to int")
+	}
+
+	free, err := findFreePort(start, attempts)
+	if err != nil {
+		return nil, "", err
+	}
+	if free != start {
+		newConfig(opts).logger.Info("port in use, using another", "busy", start, "port", free)
+	}
+
+	freePort := strconv.Itoa(free)
+	server, err := Bootstrap(ctx, freePort, dataPath, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return server, freePort, nil
+}
+
+// findFreePort returns the first port in [start, start+attempts) that can be
+// bound on localhost, or a random free port if attempts <= 0.
+func findFreePort(start, attempts int) (int, error) {
+	if attempts <= 0 {
+		l, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			return 0, errors.Wrap(err, "Failed to pick a free port")
+		}
+		defer l.Close()
+		return l.Addr().(*net.TCPAddr).Port, nil
+	}
+
+	for p := start; p < start+attempts; p++ {
+		l, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", p))
+		if err != nil {
+			continue
+		}
+		l.Close()
+		return p, nil
+	}
+	return 0, fmt.Errorf("no free port in range %d-%d", start, start+attempts-1)
+}
+
+// Bootstrap starts an embedded Weaviate on port, storing its data in
+// dataPath, and waits until it reports ready. It parses os.Args for
+// Weaviate's own flags, so callers with flags of their own should trim
+// os.Args first. Cancelling ctx shuts the server down.
+func Bootstrap(ctx context.Context, port string, dataPath string, opts ...Option) (*rest.Server, error) {
+	logger := newConfig(opts).logger
+
+	// Set environment variables for Weaviate configuration
+	_ = os.Setenv("CLUSTER_HOSTNAME", "node1")
+	_ = os.Setenv("CLUSTER_GOSSIP_BIND_PORT", "7946")
+	_ = os.Setenv("CLUSTER_DATA_BIND_PORT", "7947")
+	_ = os.Unsetenv("CLUSTER_JOIN")
+	_ = os.Setenv("DISABLE_TELEMETRY", "true")
+	_ = os.Setenv("AUTHENTICATION_ANONYMOUS_ACCESS_ENABLED", "true")
+	_ = os.Setenv("AUTHORIZATION_ADMIN_LIST_ENABLED", "false")
+	_ = os.Setenv("LOG_LEVEL", "info")
+
+	startTime := time.Now()
+	logger.Info("starting Weaviate", "port", port, "dataPath", dataPath)
+
+	// Create data directory if it doesn't exist
+	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+		logger.Info("creating Weaviate data directory", "path", dataPath)
+		if err := os.MkdirAll(dataPath, 0o755); err != nil {
+			return nil, errors.Wrap(err, "Failed to create Weaviate data directory")
+		}
+	}
+
+	// Set persistence data path
+	err := os.Setenv("PERSISTENCE_DATA_PATH", dataPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to set PERSISTENCE_DATA_PATH")
+	}
+
+	// Load swagger specification
+	swaggerSpec, err := loads.Embedded(rest.SwaggerJSON, rest.FlatSwaggerJSON)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load swagger spec")
+	}
+
+	// Create API and server
+	api := operations.NewWeaviateAPI(swaggerSpec)
+	server := rest.NewServer(api)
+
+	// Configure command line parser
+	parser := flags.NewParser(server, flags.Default)
+	parser.ShortDescription = "Weaviate"
+	server.ConfigureFlags()
+
+	// Add command line option groups
+	for _, optsGroup := range api.CommandLineOptionsGroups {
+		_, err := parser.AddGroup(optsGroup.ShortDescription, optsGroup.LongDescription, optsGroup.Options)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to add flag group")
+		}
+	}
+
+	// Parse command line arguments
+	if _, err := parser.Parse(); err != nil {
+		if fe, ok := err.(*flags.Error); ok && fe.Type == flags.ErrHelp {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Configure server
+	server.EnabledListeners = []string{"http"}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert port to int")
+	}
+	server.Port = p
+
+	// Configure API
+	server.ConfigureAPI()
+
+	// Start server in goroutine
+	go func() {
+		if err := server.Serve(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Weaviate serve error", "err", err)
+		}
+	}()
+
+	// Handle context cancellation
+	go func() {
+		<-ctx.Done()
+		logger.Info("context canceled, shutting down Weaviate")
+		_ = server.Shutdown()
+	}()
+
+	// Wait for server to become ready
+	time.Sleep(100 * time.Millisecond)
+	readyURL := fmt.Sprintf("http://localhost:%d/v1/.well-known/ready", p)
+	deadline := time.Now().Add(15 * time.Second)
+	logger.Info("waiting for Weaviate to become ready", "url", readyURL)
+
+	checkCount := 0
+	for {
+		checkCount++
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("weaviate did not become ready in time on %s", readyURL)
+		}
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, readyURL, nil)
+		resp, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			if checkCount <= 5 || checkCount%5 == 0 {
+				logger.Info("Weaviate readiness check failed", "attempt", checkCount, "err", err)
+			}
+		} else {
+			defer func() {
+				if resp != nil && resp.Body != nil {
+					resp.Body.Close()
+				}
+			}()
+
+			if resp.StatusCode == http.StatusOK {
+				logger.Info("Weaviate is ready", "elapsed", time.Since(startTime), "checks", checkCount)
+				return server, nil
+			} else {
+				if checkCount <= 5 || checkCount%5 == 0 {
+					logger.Info("Weaviate not ready yet", "attempt", checkCount, "status", resp.StatusCode)
+				}
+			}
+		}
+
+		time.Sleep(200 * time.Millisecond)
+	}
+}
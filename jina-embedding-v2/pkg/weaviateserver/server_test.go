@@ -0,0 +1,71 @@
+package weaviateserver
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestFindFreePortSkipsBusyPort(t *testing.T) {
+	busy, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	busyPort := busy.Addr().(*net.TCPAddr).Port
+
+	port, err := findFreePort(busyPort, 10)
+	if err != nil {
+		t.Fatalf("findFreePort: %v", err)
+	}
+	if port == busyPort {
+		t.Fatalf("findFreePort returned the busy port %d", port)
+	}
+	if port < busyPort || port >= busyPort+10 {
+		t.Errorf("port %d outside the requested range", port)
+	}
+}
+
+func TestFindFreePortRandom(t *testing.T) {
+	port, err := findFreePort(0, 0)
+	if err != nil {
+		t.Fatalf("findFreePort: %v", err)
+	}
+	if port == 0 {
+		t.Error("expected a non-zero port")
+	}
+}
+
+func TestBootstrapFallsBackWhenPortBusy(t *testing.T) {
+	if os.Getenv("WEAVIATE_INTEGRATION") == "" {
+		t.Skip("set WEAVIATE_INTEGRATION=1 to run Weaviate integration tests")
+	}
+
+	busy, err := net.Listen("tcp", "localhost:8080")
+	if err != nil {
+		t.Skipf("cannot occupy the default port: %v", err)
+	}
+	defer busy.Close()
+
+	args := os.Args
+	os.Args = args[:1]
+	defer func() { os.Args = args }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, port, err := BootstrapOnFreePort(ctx, "8080", t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("bootstrap failed: %v", err)
+	}
+	defer func() { _ = server.Shutdown() }()
+
+	if port == "8080" {
+		t.Fatal("bootstrap reported the occupied port")
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		t.Errorf("reported port %q is not numeric", port)
+	}
+}
@@ -0,0 +1,53 @@
+// Package weaviatestore stores embeddings in Weaviate and searches them. It
+// speaks Weaviate's HTTP API directly, so large result sets can be decoded
+// as they arrive instead of being buffered whole by the Go client.
+//
+// InsertBatch talks HTTP too rather than using weaviate-go-client's
+// ObjectsBatcher: the batcher reports failures per response object, which
+// this package maps back to the caller's slice as ObjectError indices, and
+// avoiding the client keeps its large dependency tree out of programs that
+// only store vectors. cmd/weaviate shows the same flow on weaviate-go-client
+// for callers who already depend on it.
+package weaviatestore
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Client talks to one Weaviate instance.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the Weaviate instance at baseURL, e.g.
+// "http://localhost:8080". A nil httpClient means http.DefaultClient.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+var (
+	classNamePattern    = regexp.MustCompile(`^[A-Z][_0-9A-Za-z]*$`)
+	propertyNamePattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+)
+
+// checkNames rejects class and property names Weaviate would not accept.
+// Names are spliced into GraphQL queries, so this also keeps queries
+// well-formed.
+func checkNames(className string, properties []string) error {
+	if !classNamePattern.MatchString(className) {
+		return fmt.Errorf("invalid class name %q", className)
+	}
+	for _, property := range properties {
+		if !propertyNamePattern.MatchString(property) {
+			return fmt.Errorf("invalid property name %q", property)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package weaviatestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// whereClause translates a SearchOptions.Where filter into the argument of
+// a GraphQL where, one condition per property combined with And. A string,
+// bool, integer, float or time.Time value becomes an Equal condition on
+// valueText, valueBoolean, valueInt, valueNumber or valueDate. A []string
+// value becomes ContainsAny, which matches a text property equal to any of
+// the strings or a text array property containing one. It returns "" for an
+// empty filter.
+func whereClause(where map[string]interface{}) (string, error) {
+	keys := make([]string, 0, len(where))
+	for key := range where {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	operands := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !propertyNamePattern.MatchString(key) {
+			return "", fmt.Errorf("invalid filter property name %q", key)
+		}
+		operand, err := whereOperand(key, where[key])
+		if err != nil {
+			return "", err
+		}
+		operands = append(operands, operand)
+	}
+
+	switch len(operands) {
+	case 0:
+		return "", nil
+	case 1:
+		return operands[0], nil
+	default:
+		return "{operator:And,operands:[" + strings.Join(operands, ",") + "]}", nil
+	}
+}
+
+// whereOperand builds the condition that property equals value.
+func whereOperand(property string, value interface{}) (string, error) {
+	operator, field := "Equal", ""
+	var literal string
+	switch v := value.(type) {
+	case string:
+		field, literal = "valueText", quote(v)
+	case bool:
+		field, literal = "valueBoolean", strconv.FormatBool(v)
+	case int:
+		field, literal = "valueInt", strconv.Itoa(v)
+	case int64:
+		field, literal = "valueInt", strconv.FormatInt(v, 10)
+	case float32:
+		field, literal = "valueNumber", strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		field, literal = "valueNumber", strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		field, literal = "valueDate", quote(v.Format(time.RFC3339Nano))
+	case []string:
+		if len(v) == 0 {
+			return "", fmt.Errorf("filter on %q has no values", property)
+		}
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = quote(s)
+		}
+		operator, field, literal = "ContainsAny", "valueText", "["+strings.Join(quoted, ",")+"]"
+	default:
+		return "", fmt.Errorf("unsupported filter value %v (%T) for %q", value, value, property)
+	}
+	return fmt.Sprintf("{path:[%s],operator:%s,%s:%s}", quote(property), operator, field, literal), nil
+}
+
+// quote returns s as a GraphQL string literal. GraphQL strings use JSON's
+// escapes, so a JSON-encoded string is a valid one.
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
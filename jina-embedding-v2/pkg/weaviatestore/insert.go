@@ -0,0 +1,151 @@
+package weaviatestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultBatchSize is the number of objects per request when
+// BatchOptions.BatchSize is zero.
+const DefaultBatchSize = 100
+
+// Document is one object to store.
+type Document struct {
+	// ID is the object's UUID. Empty lets Weaviate assign one.
+	ID string
+	// Properties are the object's properties, e.g. {"text": "..."}.
+	Properties map[string]interface{}
+	// Vector is the precomputed embedding, e.g. from Model.EmbedBatch.
+	Vector []float32
+}
+
+// BatchOptions configures InsertBatch.
+type BatchOptions struct {
+	// BatchSize is the number of objects sent per request. Zero means
+	// DefaultBatchSize.
+	BatchSize int
+}
+
+// ObjectError is the failure of one document of an InsertBatch.
+type ObjectError struct {
+	// Index is the document's position in the slice passed to InsertBatch.
+	Index int
+	ID    string
+	Err   error
+}
+
+// BatchError is returned by InsertBatch when some documents were rejected.
+// All others were stored.
+type BatchError struct {
+	Objects []ObjectError
+}
+
+func (e *BatchError) Error() string {
+	first := e.Objects[0]
+	return fmt.Sprintf("%d objects not inserted; first, document %d: %v", len(e.Objects), first.Index, first.Err)
+}
+
+// InsertBatch stores docs in className through Weaviate's batch endpoint,
+// opts.BatchSize objects per request. Documents Weaviate rejects are
+// reported together in a *BatchError once every batch has been sent; any
+// other error, such as a failed request, stops at the batch it happened in.
+func (c *Client) InsertBatch(ctx context.Context, className string, docs []Document, opts BatchOptions) error {
+	if err := checkNames(className, nil); err != nil {
+		return err
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	var failed []ObjectError
+	for start := 0; start < len(docs); start += batchSize {
+		end := min(start+batchSize, len(docs))
+		errs, err := c.insertObjects(ctx, className, docs[start:end])
+		if err != nil {
+			return fmt.Errorf("batch of documents %d to %d: %w", start, end-1, err)
+		}
+		for i, err := range errs {
+			if err != nil {
+				failed = append(failed, ObjectError{Index: start + i, ID: docs[start+i].ID, Err: err})
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return &BatchError{Objects: failed}
+	}
+	return nil
+}
+
+type batchObject struct {
+	Class      string                 `json:"class"`
+	ID         string                 `json:"id,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Vector     []float32              `json:"vector,omitempty"`
+}
+
+type batchObjectResult struct {
+	Result struct {
+		Errors *struct {
+			Error []graphQLError `json:"error"`
+		} `json:"errors"`
+	} `json:"result"`
+}
+
+// insertObjects sends one batch request and returns the error of each
+// document, nil for those stored.
+func (c *Client) insertObjects(ctx context.Context, className string, docs []Document) ([]error, error) {
+	objects := make([]batchObject, len(docs))
+	for i, doc := range docs {
+		objects[i] = batchObject{Class: className, ID: doc.ID, Properties: doc.Properties, Vector: doc.Vector}
+	}
+	body, err := json.Marshal(map[string]interface{}{"objects": objects})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/batch/objects", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("batch insert failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("batch insert failed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var results []batchObjectResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode batch response: %w", err)
+	}
+	if len(results) != len(docs) {
+		return nil, fmt.Errorf("batch response has %d results for %d objects", len(results), len(docs))
+	}
+
+	errs := make([]error, len(docs))
+	for i, r := range results {
+		if r.Result.Errors == nil || len(r.Result.Errors.Error) == 0 {
+			continue
+		}
+		msgs := make([]string, len(r.Result.Errors.Error))
+		for j, e := range r.Result.Errors.Error {
+			msgs[j] = e.Message
+		}
+		errs[i] = errors.New(strings.Join(msgs, "; "))
+	}
+	return errs, nil
+}
@@ -0,0 +1,101 @@
+package weaviatestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeBatch serves /v1/batch/objects, rejecting objects whose "text"
+// property is "bad", and records the size of every request.
+func fakeBatch(t *testing.T, sizes *[]int) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/batch/objects" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Objects []batchObject `json:"objects"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*sizes = append(*sizes, len(req.Objects))
+
+		results := make([]map[string]interface{}, len(req.Objects))
+		for i, obj := range req.Objects {
+			result := map[string]interface{}{}
+			if obj.Class != "Doc" || len(obj.Vector) == 0 {
+				http.Error(w, "bad object", http.StatusUnprocessableEntity)
+				return
+			}
+			if obj.Properties["text"] == "bad" {
+				result["errors"] = map[string]interface{}{"error": []map[string]string{{"message": "invalid text"}}}
+			}
+			results[i] = map[string]interface{}{"id": obj.ID, "result": result}
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+}
+
+func TestInsertBatch(t *testing.T) {
+	var sizes []int
+	srv := fakeBatch(t, &sizes)
+	defer srv.Close()
+	client := NewClient(srv.URL, nil)
+
+	docs := make([]Document, 7)
+	for i := range docs {
+		docs[i] = Document{Properties: map[string]interface{}{"text": "ok"}, Vector: []float32{float32(i), 1}}
+	}
+	docs[2].Properties["text"] = "bad"
+	docs[2].ID = "00000000-0000-0000-0000-000000000002"
+	docs[5].Properties["text"] = "bad"
+
+	err := client.InsertBatch(context.Background(), "Doc", docs, BatchOptions{BatchSize: 3})
+
+	if want := []int{3, 3, 1}; len(sizes) != len(want) || sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 1 {
+		t.Errorf("batch sizes = %v, want %v", sizes, want)
+	}
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("InsertBatch error = %v, want a *BatchError", err)
+	}
+	if len(batchErr.Objects) != 2 || batchErr.Objects[0].Index != 2 || batchErr.Objects[1].Index != 5 {
+		t.Fatalf("failed objects = %+v, want documents 2 and 5", batchErr.Objects)
+	}
+	if batchErr.Objects[0].ID != docs[2].ID || !strings.Contains(batchErr.Objects[0].Err.Error(), "invalid text") {
+		t.Errorf("object error = %+v", batchErr.Objects[0])
+	}
+}
+
+func TestInsertBatchErrors(t *testing.T) {
+	var sizes []int
+	srv := fakeBatch(t, &sizes)
+	defer srv.Close()
+	client := NewClient(srv.URL, nil)
+
+	docs := []Document{{Properties: map[string]interface{}{"text": "ok"}, Vector: []float32{1}}}
+	if err := client.InsertBatch(context.Background(), "Doc", docs, BatchOptions{}); err != nil {
+		t.Errorf("InsertBatch: %v", err)
+	}
+
+	if err := client.InsertBatch(context.Background(), "doc", docs, BatchOptions{}); err == nil {
+		t.Error("expected an error for an invalid class name")
+	}
+
+	// A request the server refuses fails the whole call.
+	err := client.InsertBatch(context.Background(), "Doc", []Document{{}}, BatchOptions{})
+	var batchErr *BatchError
+	if err == nil || errors.As(err, &batchErr) || !strings.Contains(err.Error(), "status 422") {
+		t.Errorf("InsertBatch of an object without a vector = %v, want the request error", err)
+	}
+}
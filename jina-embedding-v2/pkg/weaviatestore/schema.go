@@ -0,0 +1,56 @@
+package weaviatestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrClassExists is returned by CreateClass when the class is already in
+// the schema, e.g. from an earlier run against the same data directory.
+var ErrClassExists = errors.New("class already exists")
+
+// CreateClass creates className for objects with precomputed vectors
+// (vectorizer "none"), compared with the given distance metric, e.g.
+// "cosine". Empty distance leaves Weaviate's default.
+func (c *Client) CreateClass(ctx context.Context, className, distance string) error {
+	if err := checkNames(className, nil); err != nil {
+		return err
+	}
+
+	class := map[string]interface{}{"class": className, "vectorizer": "none"}
+	if distance != "" {
+		class["vectorIndexConfig"] = map[string]string{"distance": distance}
+	}
+	body, err := json.Marshal(class)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/schema", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("create class failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		text := strings.TrimSpace(string(msg))
+		if resp.StatusCode == http.StatusUnprocessableEntity && strings.Contains(text, "already exists") {
+			return fmt.Errorf("create class %s: %w", className, ErrClassExists)
+		}
+		return fmt.Errorf("create class failed: status %d: %s", resp.StatusCode, text)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package weaviatestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCreateClass(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/schema" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch got["class"] {
+		case "Taken":
+			http.Error(w, `{"error":[{"message":"class name \"Taken\" already exists"}]}`, http.StatusUnprocessableEntity)
+			return
+		case "Broken":
+			http.Error(w, "disk full", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(got)
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, nil)
+
+	if err := client.CreateClass(context.Background(), "Doc", "cosine"); err != nil {
+		t.Fatalf("CreateClass: %v", err)
+	}
+	want := map[string]interface{}{
+		"class":             "Doc",
+		"vectorizer":        "none",
+		"vectorIndexConfig": map[string]interface{}{"distance": "cosine"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("request body = %v, want %v", got, want)
+	}
+
+	if err := client.CreateClass(context.Background(), "Taken", ""); !errors.Is(err, ErrClassExists) {
+		t.Errorf("CreateClass of an existing class: err = %v, want ErrClassExists", err)
+	}
+	if err := client.CreateClass(context.Background(), "Broken", ""); err == nil || errors.Is(err, ErrClassExists) {
+		t.Errorf("CreateClass on a failing server: err = %v, want a non-ErrClassExists error", err)
+	}
+	if err := client.CreateClass(context.Background(), "doc", ""); err == nil {
+		t.Error("expected an error for an invalid class name")
+	}
+}
@@ -0,0 +1,286 @@
+package weaviatestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultPageSize is the number of results per page when
+// SearchOptions.Limit is zero.
+const DefaultPageSize = 100
+
+// SearchOptions configures a nearVector search.
+type SearchOptions struct {
+	// Limit is the number of results per page. Zero means DefaultPageSize.
+	Limit int
+	// After is the cursor returned as Page.Next by the previous page. Empty
+	// starts at the nearest result.
+	After string
+	// Properties lists the object properties to return with each result.
+	Properties []string
+	// Where restricts the search to objects whose properties have the given
+	// values, e.g. {"category": "news", "tenant": "acme"}; all entries must
+	// match. Values may be a string, bool, int, int64, float32, float64 or
+	// time.Time, compared for equality, or a []string, matching any of them.
+	Where map[string]interface{}
+}
+
+// Result is one search hit.
+type Result struct {
+	ID string
+	// Distance is the distance Weaviate reports for the class's metric; for
+	// "cosine" it equals embedding.CosineDistance of the two vectors.
+	Distance   float32
+	Properties map[string]interface{}
+}
+
+// Page is one page of search results.
+type Page struct {
+	Results []Result
+	// Next is the cursor for the following page, or empty if this was the
+	// last one.
+	Next string
+}
+
+// SearchNearVector returns one page of the objects of className nearest to
+// vector, nearest first. Pass the returned Page.Next as opts.After to fetch
+// the next page.
+//
+// Weaviate does not support its own "after" cursor together with
+// nearVector, so the cursor is an offset into the ranked results. Pages stay
+// consistent as long as the class is not modified while paging, and
+// Weaviate's QUERY_MAXIMUM_RESULTS (10000 by default) bounds how deep paging
+// can go.
+func (c *Client) SearchNearVector(ctx context.Context, className string, vector []float32, opts SearchOptions) (Page, error) {
+	var page Page
+	next, err := c.SearchNearVectorFunc(ctx, className, vector, opts, func(r Result) error {
+		page.Results = append(page.Results, r)
+		return nil
+	})
+	page.Next = next
+	return page, err
+}
+
+// SearchNearVectorFunc is SearchNearVector calling fn for each result as it
+// is decoded from the response, so only one result is held in memory at a
+// time. If fn returns an error, decoding stops and that error is returned.
+func (c *Client) SearchNearVectorFunc(ctx context.Context, className string, vector []float32, opts SearchOptions, fn func(Result) error) (next string, err error) {
+	if err := checkNames(className, opts.Properties); err != nil {
+		return "", err
+	}
+	if len(vector) == 0 {
+		return "", errors.New("empty query vector")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	offset := 0
+	if opts.After != "" {
+		offset, err = strconv.Atoi(opts.After)
+		if err != nil || offset < 0 {
+			return "", fmt.Errorf("invalid cursor %q", opts.After)
+		}
+	}
+
+	where, err := whereClause(opts.Where)
+	if err != nil {
+		return "", err
+	}
+
+	query := nearVectorQuery(className, vector, where, limit, offset, opts.Properties)
+	body, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/graphql", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("nearVector query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("nearVector query failed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	count := 0
+	err = decodeGetResponse(resp.Body, className, func(raw json.RawMessage) error {
+		r, err := decodeResult(raw)
+		if err != nil {
+			return err
+		}
+		count++
+		return fn(r)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if count < limit {
+		return "", nil
+	}
+	return strconv.Itoa(offset + count), nil
+}
+
+// nearVectorQuery builds the GraphQL Get query for one page. where is a
+// where clause from whereClause, or empty for none.
+func nearVectorQuery(className string, vector []float32, where string, limit, offset int, properties []string) string {
+	var b strings.Builder
+	b.WriteString("{Get{")
+	b.WriteString(className)
+	b.WriteString("(nearVector:{vector:[")
+	for i, v := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	b.WriteString("]},")
+	if where != "" {
+		b.WriteString("where:")
+		b.WriteString(where)
+		b.WriteByte(',')
+	}
+	fmt.Fprintf(&b, "limit:%d,offset:%d){", limit, offset)
+	for _, property := range properties {
+		b.WriteString(property)
+		b.WriteByte(' ')
+	}
+	b.WriteString("_additional{id distance}}}}")
+	return b.String()
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// decodeGetResponse streams {"data": {"Get": {className: [...]}}, "errors":
+// [...]}, calling fn with each element of the result array. GraphQL errors
+// are returned as an error.
+func decodeGetResponse(r io.Reader, className string, fn func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+	return decodeObject(dec, func(key string) error {
+		switch key {
+		case "errors":
+			var errs []graphQLError
+			if err := dec.Decode(&errs); err != nil {
+				return err
+			}
+			if len(errs) > 0 {
+				return fmt.Errorf("nearVector query returned errors: %s", errs[0].Message)
+			}
+			return nil
+		case "data":
+			return decodeObject(dec, func(key string) error {
+				if key != "Get" {
+					return skipValue(dec)
+				}
+				return decodeObject(dec, func(key string) error {
+					if key != className {
+						return skipValue(dec)
+					}
+					return decodeArray(dec, fn)
+				})
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+}
+
+// decodeObject reads a JSON object (or null), calling fn after each key with
+// the decoder positioned at the key's value. fn must consume the value.
+func decodeObject(dec *json.Decoder, fn func(key string) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("failed to decode response: expected an object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		if err := fn(tok.(string)); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// decodeArray reads a JSON array (or null), calling fn with each element.
+func decodeArray(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("failed to decode response: expected an array, got %v", tok)
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+func skipValue(dec *json.Decoder) error {
+	var discard json.RawMessage
+	return dec.Decode(&discard)
+}
+
+// decodeResult turns one GraphQL result object into a Result.
+func decodeResult(raw json.RawMessage) (Result, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return Result{}, fmt.Errorf("failed to decode result: %w", err)
+	}
+
+	var additional struct {
+		ID       string  `json:"id"`
+		Distance float32 `json:"distance"`
+	}
+	if err := json.Unmarshal(object["_additional"], &additional); err != nil {
+		return Result{}, fmt.Errorf("failed to decode result: %w", err)
+	}
+	delete(object, "_additional")
+
+	result := Result{ID: additional.ID, Distance: additional.Distance, Properties: make(map[string]interface{}, len(object))}
+	for name, value := range object {
+		var v interface{}
+		if err := json.Unmarshal(value, &v); err != nil {
+			return Result{}, fmt.Errorf("failed to decode property %s: %w", name, err)
+		}
+		result.Properties[name] = v
+	}
+	return result, nil
+}
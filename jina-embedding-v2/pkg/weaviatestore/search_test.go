@@ -0,0 +1,199 @@
+package weaviatestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pagePattern = regexp.MustCompile(`limit:(\d+),offset:(\d+)`)
+
+// fakeGraphQL serves nearVector queries over n objects of class Doc, ranked
+// by index, honouring limit and offset the way Weaviate does.
+func fakeGraphQL(t *testing.T, n int) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/graphql" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m := pagePattern.FindStringSubmatch(req.Query)
+		if m == nil {
+			http.Error(w, "no limit/offset in "+req.Query, http.StatusBadRequest)
+			return
+		}
+		limit, _ := strconv.Atoi(m[1])
+		offset, _ := strconv.Atoi(m[2])
+
+		results := []map[string]interface{}{}
+		for i := offset; i < n && i < offset+limit; i++ {
+			results = append(results, map[string]interface{}{
+				"title":       fmt.Sprintf("doc %d", i),
+				"_additional": map[string]interface{}{"id": fmt.Sprintf("id-%d", i), "distance": float64(i) / float64(n)},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"Get": map[string]interface{}{"Doc": results}},
+		})
+	}))
+}
+
+func TestSearchNearVectorPaging(t *testing.T) {
+	const total = 250
+	srv := fakeGraphQL(t, total)
+	defer srv.Close()
+	client := NewClient(srv.URL, nil)
+
+	seen := map[string]bool{}
+	opts := SearchOptions{Limit: 40, Properties: []string{"title"}}
+	pages := 0
+	for {
+		page, err := client.SearchNearVector(context.Background(), "Doc", []float32{0.1, 0.2}, opts)
+		if err != nil {
+			t.Fatalf("page %d: %v", pages, err)
+		}
+		pages++
+		for _, r := range page.Results {
+			if seen[r.ID] {
+				t.Fatalf("duplicate result %s on page %d", r.ID, pages)
+			}
+			seen[r.ID] = true
+			if r.Properties["title"] == nil {
+				t.Errorf("result %s has no title", r.ID)
+			}
+		}
+		if page.Next == "" {
+			break
+		}
+		opts.After = page.Next
+	}
+
+	if len(seen) != total {
+		t.Errorf("saw %d results over %d pages, want %d", len(seen), pages, total)
+	}
+	if pages != 7 {
+		t.Errorf("got %d pages, want 7", pages)
+	}
+}
+
+func TestSearchNearVectorFuncStops(t *testing.T) {
+	srv := fakeGraphQL(t, 50)
+	defer srv.Close()
+	client := NewClient(srv.URL, nil)
+
+	stop := fmt.Errorf("enough")
+	calls := 0
+	_, err := client.SearchNearVectorFunc(context.Background(), "Doc", []float32{1}, SearchOptions{}, func(Result) error {
+		calls++
+		if calls == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 3 {
+		t.Errorf("got %v after %d calls, want %v after 3", err, calls, stop)
+	}
+}
+
+func TestSearchNearVectorErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"Get":{"Doc":null}},"errors":[{"message":"class Doc not found"}]}`))
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, nil)
+
+	_, err := client.SearchNearVector(context.Background(), "Doc", []float32{1}, SearchOptions{})
+	if err == nil || !strings.Contains(err.Error(), "class Doc not found") {
+		t.Errorf("expected the GraphQL error, got %v", err)
+	}
+
+	for _, tt := range []struct {
+		class string
+		opts  SearchOptions
+	}{
+		{"doc", SearchOptions{}},
+		{"Doc){x", SearchOptions{}},
+		{"Doc", SearchOptions{Properties: []string{"a b"}}},
+		{"Doc", SearchOptions{After: "-1"}},
+		{"Doc", SearchOptions{After: "abc"}},
+		{"Doc", SearchOptions{Where: map[string]interface{}{"a b": "x"}}},
+		{"Doc", SearchOptions{Where: map[string]interface{}{"tags": []int{1}}}},
+	} {
+		if _, err := client.SearchNearVector(context.Background(), tt.class, []float32{1}, tt.opts); err == nil {
+			t.Errorf("SearchNearVector(%q, %+v): expected an error", tt.class, tt.opts)
+		}
+	}
+}
+
+func TestNearVectorQuery(t *testing.T) {
+	got := nearVectorQuery("Doc", []float32{0.5, -1}, "", 10, 20, []string{"title", "body"})
+	want := "{Get{Doc(nearVector:{vector:[0.5,-1]},limit:10,offset:20){title body _additional{id distance}}}}"
+	if got != want {
+		t.Errorf("nearVectorQuery =\n%s\nwant\n%s", got, want)
+	}
+
+	got = nearVectorQuery("Doc", []float32{1}, `{path:["category"],operator:Equal,valueText:"news"}`, 5, 0, nil)
+	want = `{Get{Doc(nearVector:{vector:[1]},where:{path:["category"],operator:Equal,valueText:"news"},limit:5,offset:0){_additional{id distance}}}}`
+	if got != want {
+		t.Errorf("nearVectorQuery with where =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWhereClause(t *testing.T) {
+	tests := []struct {
+		name  string
+		where map[string]interface{}
+		want  string
+	}{
+		{"empty", nil, ""},
+		{"text", map[string]interface{}{"category": "news"}, `{path:["category"],operator:Equal,valueText:"news"}`},
+		{"escaped text", map[string]interface{}{"title": `say "hi"`}, `{path:["title"],operator:Equal,valueText:"say \"hi\""}`},
+		{"bool", map[string]interface{}{"published": true}, `{path:["published"],operator:Equal,valueBoolean:true}`},
+		{"int", map[string]interface{}{"year": 2024}, `{path:["year"],operator:Equal,valueInt:2024}`},
+		{"number", map[string]interface{}{"score": 0.5}, `{path:["score"],operator:Equal,valueNumber:0.5}`},
+		{"date", map[string]interface{}{"date": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}, `{path:["date"],operator:Equal,valueDate:"2024-05-01T00:00:00Z"}`},
+		{"any of", map[string]interface{}{"tenant": []string{"a", "b"}}, `{path:["tenant"],operator:ContainsAny,valueText:["a","b"]}`},
+		{
+			"several properties are and-ed in name order",
+			map[string]interface{}{"tenant": "acme", "category": "news"},
+			`{operator:And,operands:[{path:["category"],operator:Equal,valueText:"news"},{path:["tenant"],operator:Equal,valueText:"acme"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := whereClause(tt.where)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("whereClause =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	for _, where := range []map[string]interface{}{
+		{"a-b": "x"},
+		{"tags": []string{}},
+		{"tags": struct{}{}},
+	} {
+		if _, err := whereClause(where); err == nil {
+			t.Errorf("whereClause(%v): expected an error", where)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+import json
+
+from tokenizers import Tokenizer
+
+# Regenerate pkg/tokenizer/testdata/tokenizer_golden.json with the ids the
+# HuggingFace tokenizer produces for ../model/tokenizer.json. The Go test
+# TestEncodeGolden compares SentencePieceTokenizer.Encode against them.
+#
+# It also writes the tokenizer.json the test loads: a copy of the model's
+# with the vocabulary cut down to the tokens the golden ids use, so the
+# test runs without model/. WordPiece picks the longest piece in the
+# vocabulary, so keeping every piece it picked encodes the golden texts
+# exactly as the full vocabulary does.
+GOLDEN = '../pkg/tokenizer/testdata/tokenizer_golden.json'
+FIXTURE = '../pkg/tokenizer/testdata/jina-embeddings-v2-base-en/tokenizer.json'
+MODEL = '../model/tokenizer.json'
+
+with open(GOLDEN) as f:
+    golden = json.load(f)
+
+tokenizer = Tokenizer.from_file(MODEL)
+used = set()
+for case in golden['cases']:
+    encoding = tokenizer.encode(case['text'])
+    case['ids'] = encoding.ids
+    used.update(encoding.tokens)
+
+with open(GOLDEN, 'w') as f:
+    json.dump(golden, f, indent=2, ensure_ascii=False)
+    f.write('\n')
+
+with open(MODEL) as f:
+    fixture = json.load(f)
+used.update(token['content'] for token in fixture['added_tokens'])
+vocab = fixture['model']['vocab']
+fixture['model']['vocab'] = {token: id for token, id in vocab.items() if token in used}
+
+with open(FIXTURE, 'w') as f:
+    json.dump(fixture, f, indent=2, ensure_ascii=False)
+    f.write('\n')
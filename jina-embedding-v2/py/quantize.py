@@ -0,0 +1,9 @@
+from onnxruntime.quantization import QuantType, quantize_dynamic
+
+# Produce the int8 dynamically quantized variant loaded by
+# embedding.WithDynamicQuantization.
+quantize_dynamic(
+    model_input='../model/model.onnx',
+    model_output='../model/model.quant.onnx',
+    weight_type=QuantType.QInt8,
+)
@@ -2,4 +2,12 @@ module learn-onnx
 
 go 1.24.3
 
-require github.com/yalue/onnxruntime_go v1.20.0
+require github.com/learn-onnx/jina-embedding-v2 v0.0.0
+
+require (
+	github.com/yalue/onnxruntime_go v1.20.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
+
+// The tokenizer is shared with jina-embedding-v2, which is not published.
+replace github.com/learn-onnx/jina-embedding-v2 => ../jina-embedding-v2
@@ -1,147 +1,316 @@
 package main
 
 import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	ort "github.com/yalue/onnxruntime_go"
-	"math"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/embedding"
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
 )
 
-func meanPooling(modelOutput []float32, attentionMask []int64, batchSize, seqLen, embedDim int) []float32 {
-	result := make([]float32, batchSize*embedDim)
-
-	for b := 0; b < batchSize; b++ {
-		var sumMask float32
-		for i := 0; i < embedDim; i++ {
-			var sumEmbedding float32
-			for s := 0; s < seqLen; s++ {
-				maskVal := float32(attentionMask[b*seqLen+s])
-				embeddingVal := modelOutput[b*seqLen*embedDim+s*embedDim+i]
-				sumEmbedding += embeddingVal * maskVal
-				if i == 0 {
-					sumMask += maskVal
-				}
-			}
-			if sumMask < 1e-9 {
-				sumMask = 1e-9
-			}
-			result[b*embedDim+i] = sumEmbedding / sumMask
-		}
+// loadModel loads jina-embeddings-v3 with the LoRA adapter for taskType.
+// The model's text_embeds output holds per-token hidden states, which
+// embedding.Model mean-pools and L2-normalizes.
+func loadModel(modelPath string, tok *tokenizer.SentencePieceTokenizer, taskType string) (*embedding.Model, error) {
+	taskID, err := tok.ResolveTask(taskType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task ID: %v", err)
 	}
-	return result
+	// ResolveTask also accepts aliases such as "clustering"; WithTask wants
+	// the adapter name.
+	return embedding.NewModel(modelPath, tok,
+		embedding.WithTask(tok.Tasks()[taskID]),
+		embedding.WithOutputName("text_embeds"))
 }
 
-func l2Normalize(embeddings []float32, batchSize, embedDim int) []float32 {
-	result := make([]float32, len(embeddings))
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s [flags] <command> [args]
 
-	for b := 0; b < batchSize; b++ {
-		var norm float32
-		for i := 0; i < embedDim; i++ {
-			val := embeddings[b*embedDim+i]
-			norm += val * val
-		}
-		norm = float32(math.Sqrt(float64(norm)))
+Commands:
+  embed <text>...       print the embedding of each text
+  tokenize <text>       print the token IDs of text
+  similarity <a> <b>    print the cosine similarity of two texts
+
+A text argument of "-" is read from stdin and "@path" is read from a file.
+Text read this way is trimmed according to -trim before tokenizing; by
+default a single trailing newline is removed.
+
+-format selects how embed writes embeddings: text (human-readable), json
+(one object per line), tsv (index, then one column per value) or fvecs
+(binary: little-endian int32 dimension followed by float32 values).
 
-		for i := 0; i < embedDim; i++ {
-			result[b*embedDim+i] = embeddings[b*embedDim+i] / norm
+Flags:
+`, os.Args[0])
+	flag.PrintDefaults()
+}
+
+// Output formats for the embed command
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatTSV   = "tsv"
+	formatFvecs = "fvecs"
+)
+
+// embedOutput is the -format json form of the embed command
+type embedOutput struct {
+	Text        string    `json:"text"`
+	Dim         int       `json:"dim"`
+	Embedding   []float32 `json:"embedding"`
+	InferenceMs float64   `json:"inference_ms"`
+}
+
+// writeEmbedding writes one embed result in format; id is the position of the
+// text among the command's arguments
+func writeEmbedding(w io.Writer, format string, id int, out embedOutput) error {
+	switch format {
+	case formatText:
+		_, err := fmt.Fprintf(w, "Input: %s\nInference time: %v\nFinal embeddings shape: [1, %d]\nFirst 10 values: %v\n",
+			out.Text, time.Duration(out.InferenceMs*float64(time.Millisecond)), out.Dim, out.Embedding[:min(10, len(out.Embedding))])
+		return err
+	case formatJSON:
+		return json.NewEncoder(w).Encode(out)
+	case formatTSV:
+		var b strings.Builder
+		fmt.Fprintf(&b, "%d", id)
+		for _, v := range out.Embedding {
+			b.WriteByte('\t')
+			b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+		}
+		b.WriteByte('\n')
+		_, err := io.WriteString(w, b.String())
+		return err
+	case formatFvecs:
+		if err := binary.Write(w, binary.LittleEndian, int32(len(out.Embedding))); err != nil {
+			return err
 		}
+		return binary.Write(w, binary.LittleEndian, out.Embedding)
+	default:
+		return fmt.Errorf("unknown output format %q (want %s, %s, %s or %s)", format, formatText, formatJSON, formatTSV, formatFvecs)
 	}
-	return result
 }
 
-func main() {
-	lib := "/usr/local/lib/onnxruntime/lib/libonnxruntime.so"
-	ort.SetSharedLibraryPath(lib)
+func printJSON(v interface{}) error {
+	if err := json.NewEncoder(os.Stdout).Encode(v); err != nil {
+		return fmt.Errorf("failed to write JSON: %v", err)
+	}
+	return nil
+}
 
-	err := ort.InitializeEnvironment()
-	if err != nil {
-		panic(err)
+// errUsage makes main print the usage and exit with status 2.
+var errUsage = errors.New("usage")
+
+// Trim modes for text read from stdin or files
+const (
+	trimNone    = "none"
+	trimNewline = "newline"
+	trimSpace   = "space"
+)
+
+// trimInput removes trailing characters from text read from stdin or a file:
+// nothing, a single trailing newline (\n or \r\n), or all trailing whitespace
+func trimInput(text, mode string) (string, error) {
+	switch mode {
+	case trimNone:
+		return text, nil
+	case trimNewline:
+		if strings.HasSuffix(text, "\r\n") {
+			return text[:len(text)-2], nil
+		}
+		return strings.TrimSuffix(text, "\n"), nil
+	case trimSpace:
+		return strings.TrimRightFunc(text, unicode.IsSpace), nil
+	default:
+		return "", fmt.Errorf("unknown trim mode %q (want %s, %s or %s)", mode, trimNone, trimNewline, trimSpace)
 	}
-	defer ort.DestroyEnvironment()
+}
 
-	// Initialize real SentencePiece tokenizer
-	tokenizer := NewSentencePieceTokenizer()
-	err = tokenizer.LoadFromHuggingFace("jinaai/jina-embeddings-v3")
+// readInput resolves a text argument: "-" reads stdin, "@path" reads a file,
+// anything else is used verbatim
+func readInput(arg, trimMode string) (string, error) {
+	var data []byte
+	var err error
+	switch {
+	case arg == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(arg, "@"):
+		data, err = os.ReadFile(arg[1:])
+	default:
+		return arg, nil
+	}
 	if err != nil {
-		panic(fmt.Errorf("failed to load tokenizer: %v", err))
+		return "", err
 	}
+	return trimInput(string(data), trimMode)
+}
 
-	// Tokenize input text dynamically
-	inputText := "This is an apple"
-	inputIds, attentionMask := tokenizer.Encode(inputText)
-
-	// Get task ID dynamically
-	taskType := "text-matching"
-	taskIdValue, err := tokenizer.GetTaskID(taskType)
-	if err != nil {
-		panic(fmt.Errorf("failed to get task ID: %v", err))
+// requireArgs fails with errUsage unless the command got exactly n
+// arguments, and resolves each of them with readInput
+func requireArgs(n int, trimMode string) ([]string, error) {
+	if flag.NArg()-1 != n {
+		return nil, errUsage
 	}
-	taskId := []int64{taskIdValue}
+	return resolveArgs(flag.Args()[1:], trimMode)
+}
 
-	// Dynamic dimensions based on tokenization
-	batchSize := 1
-	seqLen := len(inputIds)
-	embedDim := 1024
+// resolveArgs resolves each command argument with readInput
+func resolveArgs(args []string, trimMode string) ([]string, error) {
+	texts := make([]string, len(args))
+	for i, arg := range args {
+		text, err := readInput(arg, trimMode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input %q: %v", arg, err)
+		}
+		texts[i] = text
+	}
+	return texts, nil
+}
 
-	fmt.Printf("\nRunning model inference:\n")
-	fmt.Printf("Input: %s\n", inputText)
-	fmt.Printf("Token IDs: %v\n", inputIds)
-	fmt.Printf("Task ID: %v\n", taskId)
+func main() {
+	modelPath := flag.String("model", "py/model/model.onnx", "path to the ONNX model")
+	taskType := flag.String("task", "text-matching", "task to embed for: "+strings.Join(tokenizer.KnownTasks, ", "))
+	format := flag.String("format", formatText, "output format: text, json, tsv or fvecs (tsv and fvecs apply to embed)")
+	jsonFlag := flag.Bool("json", false, "shorthand for -format json")
+	trimMode := flag.String("trim", trimNewline, "trimming applied to text read from stdin or files: none, newline or space")
+	flag.Usage = usage
+	flag.Parse()
 
-	// Create input tensors
-	inputIdsShape := ort.NewShape(int64(batchSize), int64(seqLen))
-	inputIdsTensor, err := ort.NewTensor(inputIdsShape, inputIds)
-	if err != nil {
-		panic(err)
+	if *jsonFlag {
+		*format = formatJSON
 	}
-	defer inputIdsTensor.Destroy()
 
-	attentionMaskShape := ort.NewShape(int64(batchSize), int64(seqLen))
-	attentionMaskTensor, err := ort.NewTensor(attentionMaskShape, attentionMask)
+	// run returns instead of exiting so that its deferred cleanup, closing
+	// the model and onnxruntime, happens before the process exits.
+	err := run(*modelPath, *taskType, *format, *trimMode)
+	if errors.Is(err, errUsage) {
+		usage()
+		os.Exit(2)
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	defer attentionMaskTensor.Destroy()
+}
 
-	taskIdShape := ort.NewShape(1)
-	taskIdTensor, err := ort.NewTensor(taskIdShape, taskId)
-	if err != nil {
-		panic(err)
+func run(modelPath, taskType, format, trimMode string) error {
+	if flag.NArg() < 1 {
+		return errUsage
+	}
+	command := flag.Arg(0)
+	switch format {
+	case formatText, formatJSON, formatTSV, formatFvecs:
+	default:
+		return fmt.Errorf("unknown output format %q (want %s, %s, %s or %s)", format, formatText, formatJSON, formatTSV, formatFvecs)
 	}
-	defer taskIdTensor.Destroy()
+	jsonOutput := format == formatJSON
 
-	// Create output tensor
-	outputShape := ort.NewShape(int64(batchSize), int64(seqLen), int64(embedDim))
-	outputTensor, err := ort.NewEmptyTensor[float32](outputShape)
+	// Initialize real SentencePiece tokenizer, logging download progress to
+	// stderr so stdout only carries results
+	tok := tokenizer.NewSentencePieceTokenizer(tokenizer.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))))
+	err := tok.LoadFromHuggingFace("jinaai/jina-embeddings-v3")
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to load tokenizer: %v", err)
 	}
-	defer outputTensor.Destroy()
 
-	model := "py/model/model.onnx"
-	session, err := ort.NewAdvancedSession(model,
-		[]string{"input_ids", "attention_mask", "task_id"},
-		[]string{"text_embeds"},
-		[]ort.Value{inputIdsTensor, attentionMaskTensor, taskIdTensor},
-		[]ort.Value{outputTensor}, nil)
-	if err != nil {
-		panic(err)
+	if command == "tokenize" {
+		args, err := requireArgs(1, trimMode)
+		if err != nil {
+			return err
+		}
+		inputIds, _ := tok.Encode(args[0])
+		if jsonOutput {
+			return printJSON(map[string]interface{}{"text": args[0], "ids": inputIds})
+		}
+		fmt.Printf("Token IDs: %v\n", inputIds)
+		return nil
 	}
-	defer session.Destroy()
 
-	err = session.Run()
-	if err != nil {
-		panic(err)
+	// CheckRuntime finds the library for this platform (or $ONNXRUNTIME_LIB)
+	// and initializes onnxruntime; closing the model destroys it again.
+	if _, err := embedding.CheckRuntime(); err != nil {
+		return fmt.Errorf("failed to initialize onnxruntime: %v", err)
 	}
 
-	// Get model output
-	rawOutput := outputTensor.GetData()
+	switch command {
+	case "embed":
+		if flag.NArg() < 2 {
+			return errUsage
+		}
+		args, err := resolveArgs(flag.Args()[1:], trimMode)
+		if err != nil {
+			return err
+		}
+
+		m, err := loadModel(modelPath, tok, taskType)
+		if err != nil {
+			return fmt.Errorf("failed to load model: %v", err)
+		}
+		defer m.Close()
+
+		out := bufio.NewWriter(os.Stdout)
+		for i, text := range args {
+			start := time.Now()
+			vector, err := m.Embed(text)
+			if err != nil {
+				return fmt.Errorf("inference failed: %v", err)
+			}
+			elapsed := time.Since(start)
 
-	// Apply mean pooling
-	pooledEmbeddings := meanPooling(rawOutput, attentionMask, batchSize, seqLen, embedDim)
+			err = writeEmbedding(out, format, i, embedOutput{
+				Text:        text,
+				Dim:         len(vector),
+				Embedding:   vector,
+				InferenceMs: float64(elapsed.Microseconds()) / 1000,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write output: %v", err)
+			}
+		}
+		if err := out.Flush(); err != nil {
+			return fmt.Errorf("failed to write output: %v", err)
+		}
+		return nil
 
-	// Apply L2 normalization
-	finalEmbeddings := l2Normalize(pooledEmbeddings, batchSize, embedDim)
+	case "similarity":
+		args, err := requireArgs(2, trimMode)
+		if err != nil {
+			return err
+		}
 
-	fmt.Printf("Final embeddings shape: [%d, %d]\n", batchSize, embedDim)
-	fmt.Printf("First 10 values: %v\n", finalEmbeddings[:10])
+		m, err := loadModel(modelPath, tok, taskType)
+		if err != nil {
+			return fmt.Errorf("failed to load model: %v", err)
+		}
+		defer m.Close()
+
+		a, err := m.Embed(args[0])
+		if err != nil {
+			return fmt.Errorf("inference failed: %v", err)
+		}
+		b, err := m.Embed(args[1])
+		if err != nil {
+			return fmt.Errorf("inference failed: %v", err)
+		}
+
+		similarity := embedding.CosineSimilarity(a, b)
+		if jsonOutput {
+			return printJSON(map[string]interface{}{"a": args[0], "b": args[1], "similarity": similarity})
+		}
+		fmt.Printf("Similarity: %.6f\n", similarity)
+		return nil
+
+	default:
+		return errUsage
+	}
 }
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/learn-onnx/jina-embedding-v2/pkg/tokenizer"
+)
+
+const testTokenizerJSON = `{
+	"model": {
+		"type": "Unigram",
+		"vocab": [["<s>", 0], ["<pad>", 0], ["</s>", 0], ["<unk>", 0], ["▁text", -1], ["▁more", -1]]
+	},
+	"pre_tokenizer": {"type": "Metaspace", "replacement": "▁", "add_prefix_space": true},
+	"added_tokens": [
+		{"id": 0, "content": "<s>", "special": true},
+		{"id": 1, "content": "<pad>", "special": true},
+		{"id": 2, "content": "</s>", "special": true},
+		{"id": 3, "content": "<unk>", "special": true}
+	]
+}`
+
+func newTestTokenizer(t *testing.T) *tokenizer.SentencePieceTokenizer {
+	t.Helper()
+
+	dir := t.TempDir()
+	tokenizerPath := filepath.Join(dir, "tokenizer.json")
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(tokenizerPath, []byte(testTokenizerJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte(`{"lora_adaptations": ["text-matching"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tok := tokenizer.NewSentencePieceTokenizer()
+	if err := tok.LoadFromLocal(tokenizerPath, configPath); err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestTrimInput(t *testing.T) {
+	tests := []struct {
+		mode, in, want string
+	}{
+		{trimNone, "text\n", "text\n"},
+		{trimNewline, "text\n", "text"},
+		{trimNewline, "text\r\n", "text"},
+		{trimNewline, "text\n\n", "text\n"},
+		{trimSpace, "text \t\n\n", "text"},
+	}
+
+	for _, tt := range tests {
+		got, err := trimInput(tt.in, tt.mode)
+		if err != nil {
+			t.Fatalf("trimInput(%q, %s): %v", tt.in, tt.mode, err)
+		}
+		if got != tt.want {
+			t.Errorf("trimInput(%q, %s) = %q, want %q", tt.in, tt.mode, got, tt.want)
+		}
+	}
+
+	if _, err := trimInput("text", "all"); err == nil {
+		t.Error("expected error for unknown trim mode")
+	}
+}
+
+func TestTrimmedFileInputTokenizesLikeArgument(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte("text\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fromFile, err := readInput("@"+path, trimNewline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok := newTestTokenizer(t)
+	wantIds, _ := tok.Encode("text")
+	gotIds, _ := tok.Encode(fromFile)
+	if !reflect.DeepEqual(gotIds, wantIds) {
+		t.Errorf("file input tokenized to %v, argument to %v", gotIds, wantIds)
+	}
+}
+
+func TestWriteEmbeddingFormats(t *testing.T) {
+	out := embedOutput{Text: "some text", Dim: 3, Embedding: []float32{0.5, -0.25, 1e-3}, InferenceMs: 1.5}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeEmbedding(&buf, formatJSON, 0, out); err != nil {
+			t.Fatal(err)
+		}
+		var got embedOutput
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+		}
+		if !reflect.DeepEqual(got, out) {
+			t.Errorf("decoded %+v, want %+v", got, out)
+		}
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeEmbedding(&buf, formatTSV, 7, out); err != nil {
+			t.Fatal(err)
+		}
+		line := buf.String()
+		if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+			t.Fatalf("expected a single line, got %q", line)
+		}
+		fields := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+		if fields[0] != "7" {
+			t.Errorf("id column = %q, want 7", fields[0])
+		}
+		if len(fields)-1 != len(out.Embedding) {
+			t.Fatalf("got %d value columns, want %d", len(fields)-1, len(out.Embedding))
+		}
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if float32(v) != out.Embedding[i] {
+				t.Errorf("value %d = %v, want %v", i, v, out.Embedding[i])
+			}
+		}
+	})
+
+	t.Run("fvecs", func(t *testing.T) {
+		var buf bytes.Buffer
+		for i := 0; i < 2; i++ {
+			if err := writeEmbedding(&buf, formatFvecs, i, out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < 2; i++ {
+			var dim int32
+			if err := binary.Read(&buf, binary.LittleEndian, &dim); err != nil {
+				t.Fatal(err)
+			}
+			if int(dim) != len(out.Embedding) {
+				t.Fatalf("record %d: dim = %d, want %d", i, dim, len(out.Embedding))
+			}
+			values := make([]float32, dim)
+			if err := binary.Read(&buf, binary.LittleEndian, values); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(values, out.Embedding) {
+				t.Errorf("record %d: values = %v, want %v", i, values, out.Embedding)
+			}
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%d trailing bytes", buf.Len())
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeEmbedding(&buf, formatText, 0, out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "Input: some text") {
+			t.Errorf("unexpected text output %q", buf.String())
+		}
+	})
+
+	if err := writeEmbedding(io.Discard, "csv", 0, out); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}